@@ -15,17 +15,27 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
+	"gruzy-ryadom/config"
 	"gruzy-ryadom/internal/api"
 	"gruzy-ryadom/internal/bots"
 	"gruzy-ryadom/internal/db"
+	"gruzy-ryadom/internal/feed"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/outbox"
 	"gruzy-ryadom/internal/service"
+	"gruzy-ryadom/internal/storage"
+	"gruzy-ryadom/internal/webhooks"
 )
 
 type Application struct {
-	server     *http.Server
-	adminBot   *bots.AdminBot
-	driverBot  *bots.DriverBot
-	service    *service.Service
+	server      *http.Server
+	adminBot    *bots.AdminBot
+	driverBot   *bots.DriverBot
+	broadcaster *bots.Broadcaster
+	dispatcher  *webhooks.Dispatcher
+	outbox      *outbox.Dispatcher
+	feed        *feed.Hub
+	service     *service.Service
 	database   *db.DB
 	ctx        context.Context
 	cancel     context.CancelFunc
@@ -35,52 +45,78 @@ type Application struct {
 func NewApplication() (*Application, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
 	// Database connection
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
+	if cfg.Database.URL == "" {
+		cancel()
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
 
-	database, err := db.New(dbURL)
+	database, err := db.New(cfg.Database.URL)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Service layer
-	svc := service.New(database)
-
-	// Create bots
-	adminBotToken := os.Getenv("ADMIN_BOT_TOKEN")
-	if adminBotToken == "" {
+	// Photo storage
+	photos, err := storage.NewLocal(cfg.Storage.PhotosDir)
+	if err != nil {
 		cancel()
 		database.Close()
-		return nil, fmt.Errorf("ADMIN_BOT_TOKEN is required")
+		return nil, fmt.Errorf("failed to open photo storage: %w", err)
 	}
 
-	driverBotToken := os.Getenv("DRIVER_BOT_TOKEN")
-	if driverBotToken == "" {
-		cancel()
-		database.Close()
-		return nil, fmt.Errorf("DRIVER_BOT_TOKEN is required")
+	// Service layer
+	svc := service.New(database, photos, cfg.Webhooks.AllowPrivateNetworks)
+
+	// Create bots
+	roster := cfg.Admins.Roster()
+	if len(roster) == 0 {
+		log.Println("Warning: no admins configured, set SUPERADMIN_IDS to access the admin bot")
 	}
 
-	adminBot, err := bots.NewAdminBot(adminBotToken, svc)
+	driverBot, err := bots.NewDriverBot(cfg.Bots.DriverBotToken, svc)
 	if err != nil {
 		cancel()
 		database.Close()
-		return nil, fmt.Errorf("failed to create admin bot: %w", err)
+		return nil, fmt.Errorf("failed to create driver bot: %w", err)
 	}
 
-	driverBot, err := bots.NewDriverBot(driverBotToken, svc)
+	// Broadcasts are delivered by the driver bot and managed from the admin bot
+	broadcaster := bots.NewBroadcaster(driverBot, svc)
+
+	adminBot, err := bots.NewAdminBot(cfg.Bots.AdminBotToken, svc, roster, broadcaster, driverBot)
 	if err != nil {
 		cancel()
 		database.Close()
-		return nil, fmt.Errorf("failed to create driver bot: %w", err)
+		return nil, fmt.Errorf("failed to create admin bot: %w", err)
 	}
 
+	// Webhook deliveries are queued by the service and sent in the background
+	dispatcher := webhooks.NewDispatcher(svc, nil)
+
+	// Side effects of changes are driven by the domain events in the outbox
+	events := outbox.NewDispatcher(svc)
+	events.Handle(models.OrderCreated, "webhooks", svc.QueueOrderWebhooks)
+	events.Handle(models.OrderUpdated, "webhooks", svc.QueueOrderWebhooks)
+
+	// Live order feed, notified of changes by every instance through Postgres
+	hub := feed.NewHub(svc, cfg.Database.URL)
+	events.Handle(models.OrderCreated, "feed", svc.NotifyOrderFeed)
+	events.Handle(models.OrderUpdated, "feed", svc.NotifyOrderFeed)
+
+	// Drivers subscribed with /subscribe get new matching orders from the bot
+	events.Handle(models.OrderCreated, "saved-searches", driverBot.NotifySavedSearches)
+	events.Handle(models.OrderUpdated, "saved-searches", driverBot.NotifySavedSearches)
+
 	// Create HTTP server
-	apiHandler := api.New(svc)
+	apiHandler := api.New(svc, cfg.Server.AdminToken, cfg.Server.PublicURL, hub)
 	r := chi.NewRouter()
 
 	// Middleware
@@ -88,7 +124,7 @@ func NewApplication() (*Application, error) {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(api.Timeout(60 * time.Second))
 
 	// CORS
 	r.Use(cors.Handler(cors.Options{
@@ -109,13 +145,8 @@ func NewApplication() (*Application, error) {
 	// API routes
 	r.Mount("/", apiHandler.Routes())
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
 	server := &http.Server{
-		Addr:         ":" + port,
+		Addr:         ":" + cfg.Server.Port,
 		Handler:      r,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
@@ -123,13 +154,17 @@ func NewApplication() (*Application, error) {
 	}
 
 	return &Application{
-		server:    server,
-		adminBot:  adminBot,
-		driverBot: driverBot,
-		service:   svc,
-		database:  database,
-		ctx:       ctx,
-		cancel:    cancel,
+		server:      server,
+		adminBot:    adminBot,
+		driverBot:   driverBot,
+		broadcaster: broadcaster,
+		dispatcher:  dispatcher,
+		outbox:      events,
+		feed:        hub,
+		service:     svc,
+		database:    database,
+		ctx:         ctx,
+		cancel:      cancel,
 	}, nil
 }
 
@@ -150,6 +185,34 @@ func (app *Application) Start() error {
 		app.driverBot.Start()
 	}()
 
+	// Start broadcast delivery worker
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.broadcaster.Run(app.ctx)
+	}()
+
+	// Start webhook delivery worker
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.dispatcher.Run(app.ctx)
+	}()
+
+	// Start outbox dispatcher
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.outbox.Run(app.ctx)
+	}()
+
+	// Start live order feed
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.feed.Run(app.ctx)
+	}()
+
 	// Start HTTP server in goroutine
 	app.wg.Add(1)
 	go func() {
@@ -186,14 +249,15 @@ func (app *Application) Stop() error {
 		}
 	}
 
+	// Wait for all goroutines to finish; the dispatchers use the database
+	// until they stop
+	app.wg.Wait()
+
 	// Close database
 	if app.database != nil {
 		app.database.Close()
 	}
 
-	// Wait for all goroutines to finish
-	app.wg.Wait()
-
 	log.Println("Application stopped")
 	return nil
 }
@@ -15,7 +15,12 @@ import (
 	"gruzy-ryadom/internal/api"
 	"gruzy-ryadom/internal/bots"
 	"gruzy-ryadom/internal/db"
+	"gruzy-ryadom/internal/feed"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/outbox"
 	"gruzy-ryadom/internal/service"
+	"gruzy-ryadom/internal/storage"
+	"gruzy-ryadom/internal/webhooks"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -24,15 +29,19 @@ import (
 )
 
 type TestApplication struct {
-	server    *http.Server
-	adminBot  *bots.AdminBot
-	driverBot *bots.DriverBot
-	service   *service.Service
-	database  *db.DB
-	ctx       context.Context
-	cancel    context.CancelFunc
-	wg        sync.WaitGroup
-	hasBots   bool
+	server      *http.Server
+	adminBot    *bots.AdminBot
+	driverBot   *bots.DriverBot
+	broadcaster *bots.Broadcaster
+	dispatcher  *webhooks.Dispatcher
+	outbox      *outbox.Dispatcher
+	feed        *feed.Hub
+	service     *service.Service
+	database    *db.DB
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	hasBots     bool
 }
 
 func NewTestApplication() (*TestApplication, error) {
@@ -57,26 +66,36 @@ func NewTestApplication() (*TestApplication, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Photo storage
+	photos, err := storage.NewLocal(cfg.Storage.PhotosDir)
+	if err != nil {
+		cancel()
+		database.Close()
+		return nil, fmt.Errorf("failed to open photo storage: %w", err)
+	}
+
 	// Service layer
-	svc := service.New(database)
+	svc := service.New(database, photos, cfg.Webhooks.AllowPrivateNetworks)
 
 	// Try to create bots, but don't fail if they can't be created
 	var adminBot *bots.AdminBot
 	var driverBot *bots.DriverBot
+	var broadcaster *bots.Broadcaster
 	hasBots := false
 
 	if cfg.Bots.AdminBotToken != "" && cfg.Bots.DriverBotToken != "" {
 		log.Println("Attempting to create Telegram bots...")
 
-		adminBot, err = bots.NewAdminBot(cfg.Bots.AdminBotToken, svc)
+		driverBot, err = bots.NewDriverBot(cfg.Bots.DriverBotToken, svc)
 		if err != nil {
-			log.Printf("Warning: Failed to create admin bot: %v", err)
-			log.Println("Application will run without admin bot")
+			log.Printf("Warning: Failed to create driver bot: %v", err)
+			log.Println("Application will run without driver bot")
 		} else {
-			driverBot, err = bots.NewDriverBot(cfg.Bots.DriverBotToken, svc)
+			broadcaster = bots.NewBroadcaster(driverBot, svc)
+			adminBot, err = bots.NewAdminBot(cfg.Bots.AdminBotToken, svc, cfg.Admins.Roster(), broadcaster, driverBot)
 			if err != nil {
-				log.Printf("Warning: Failed to create driver bot: %v", err)
-				log.Println("Application will run without driver bot")
+				log.Printf("Warning: Failed to create admin bot: %v", err)
+				log.Println("Application will run without admin bot")
 			} else {
 				hasBots = true
 				log.Println("Both bots created successfully")
@@ -86,8 +105,27 @@ func NewTestApplication() (*TestApplication, error) {
 		log.Println("Bot tokens not provided, running without bots")
 	}
 
+	// Webhooks do not depend on the bots
+	dispatcher := webhooks.NewDispatcher(svc, nil)
+
+	// Side effects of changes are driven by the domain events in the outbox
+	events := outbox.NewDispatcher(svc)
+	events.Handle(models.OrderCreated, "webhooks", svc.QueueOrderWebhooks)
+	events.Handle(models.OrderUpdated, "webhooks", svc.QueueOrderWebhooks)
+
+	// Live order feed, notified of changes by every instance through Postgres
+	hub := feed.NewHub(svc, cfg.Database.URL)
+	events.Handle(models.OrderCreated, "feed", svc.NotifyOrderFeed)
+	events.Handle(models.OrderUpdated, "feed", svc.NotifyOrderFeed)
+
+	// Drivers subscribed with /subscribe get new matching orders from the bot
+	if hasBots {
+		events.Handle(models.OrderCreated, "saved-searches", driverBot.NotifySavedSearches)
+		events.Handle(models.OrderUpdated, "saved-searches", driverBot.NotifySavedSearches)
+	}
+
 	// Create HTTP server
-	apiHandler := api.New(svc)
+	apiHandler := api.New(svc, cfg.Server.AdminToken, cfg.Server.PublicURL, hub)
 	r := chi.NewRouter()
 
 	// Middleware
@@ -95,7 +133,7 @@ func NewTestApplication() (*TestApplication, error) {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(api.Timeout(60 * time.Second))
 
 	// CORS
 	r.Use(cors.Handler(cors.Options{
@@ -136,14 +174,18 @@ func NewTestApplication() (*TestApplication, error) {
 	}
 
 	return &TestApplication{
-		server:    server,
-		adminBot:  adminBot,
-		driverBot: driverBot,
-		service:   svc,
-		database:  database,
-		ctx:       ctx,
-		cancel:    cancel,
-		hasBots:   hasBots,
+		server:      server,
+		adminBot:    adminBot,
+		driverBot:   driverBot,
+		broadcaster: broadcaster,
+		dispatcher:  dispatcher,
+		outbox:      events,
+		feed:        hub,
+		service:     svc,
+		database:    database,
+		ctx:         ctx,
+		cancel:      cancel,
+		hasBots:     hasBots,
 	}, nil
 }
 
@@ -164,10 +206,36 @@ func (app *TestApplication) Start() error {
 			log.Println("Starting driver bot...")
 			app.driverBot.Start()
 		}()
+
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+			app.broadcaster.Run(app.ctx)
+		}()
 	} else {
 		log.Println("Running without Telegram bots")
 	}
 
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.dispatcher.Run(app.ctx)
+	}()
+
+	// Start outbox dispatcher
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.outbox.Run(app.ctx)
+	}()
+
+	// Start live order feed
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.feed.Run(app.ctx)
+	}()
+
 	// Start HTTP server in goroutine
 	app.wg.Add(1)
 	go func() {
@@ -206,14 +274,15 @@ func (app *TestApplication) Stop() error {
 		}
 	}
 
+	// Wait for all goroutines to finish; the dispatchers use the database
+	// until they stop
+	app.wg.Wait()
+
 	// Close database
 	if app.database != nil {
 		app.database.Close()
 	}
 
-	// Wait for all goroutines to finish
-	app.wg.Wait()
-
 	log.Println("Test application stopped")
 	return nil
 }
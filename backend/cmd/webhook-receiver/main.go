@@ -0,0 +1,69 @@
+// Command webhook-receiver is a local stand-in for a partner endpoint. It
+// checks the signatures of webhook requests and prints the events; with -fail
+// it answers 500 to the first attempts of every delivery to exercise retries.
+// The application only sends to it with webhooks.allow_private_networks set.
+//
+//	go run ./cmd/webhook-receiver -secret <secret> -fail 2
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"gruzy-ryadom/internal/webhooks"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	secret := flag.String("secret", "", "webhook secret; signatures are not checked if empty")
+	fail := flag.Int("fail", 0, "number of attempts of each delivery to answer with 500")
+	flag.Parse()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		event := r.Header.Get(webhooks.HeaderEvent)
+		delivery := r.Header.Get(webhooks.HeaderDelivery)
+		if *secret != "" {
+			err := webhooks.Verify(*secret, r.Header.Get(webhooks.HeaderTimestamp), r.Header.Get(webhooks.HeaderSignature), body, 5*time.Minute)
+			if err != nil {
+				log.Printf("%s %s: %v", event, delivery, err)
+				http.Error(w, "Invalid signature", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		mu.Lock()
+		attempts[delivery]++
+		attempt := attempts[delivery]
+		mu.Unlock()
+		if attempt <= *fail {
+			log.Printf("%s %s: attempt %d, failing on purpose", event, delivery, attempt)
+			http.Error(w, "Failing on purpose", http.StatusInternalServerError)
+			return
+		}
+
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") != nil {
+			pretty.Write(body)
+		}
+		log.Printf("%s %s: attempt %d\n%s", event, delivery, attempt, pretty.String())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -4,15 +4,20 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
+	"gruzy-ryadom/internal/models"
 )
 
 type Config struct {
 	Database DatabaseConfig `yaml:"database"`
 	Server   ServerConfig   `yaml:"server"`
 	Bots     BotsConfig
-	Env      string `yaml:"env"`
+	Admins   AdminsConfig   `yaml:"admins"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+	Env      string         `yaml:"env"`
 }
 
 type DatabaseConfig struct {
@@ -21,6 +26,23 @@ type DatabaseConfig struct {
 
 type ServerConfig struct {
 	Port string `yaml:"port"`
+	// AdminToken protects the /v1/admin endpoints; they are disabled if empty
+	AdminToken string `yaml:"admin_token"`
+	// PublicURL is where clients reach the API, e.g. behind a proxy; links in
+	// RSS/Atom feeds are built from the request host if empty
+	PublicURL string `yaml:"public_url"`
+}
+
+// StorageConfig sets where uploaded files such as order photos are kept
+type StorageConfig struct {
+	PhotosDir string `yaml:"photos_dir"`
+}
+
+// WebhooksConfig sets where partner webhooks may be sent
+type WebhooksConfig struct {
+	// AllowPrivateNetworks lets webhooks reach loopback and private addresses,
+	// e.g. the local webhook-receiver; never enable it in production
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
 }
 
 type BotsConfig struct {
@@ -28,6 +50,25 @@ type BotsConfig struct {
 	AdminBotToken  string
 }
 
+// AdminsConfig is the static admin roster. These Telegram IDs always have
+// access to the admin bot; superadmins can grant more roles from the bot.
+type AdminsConfig struct {
+	Superadmins []int64 `yaml:"superadmins"`
+	Moderators  []int64 `yaml:"moderators"`
+}
+
+// Roster maps Telegram IDs to roles; superadmin wins if an ID is listed twice
+func (a AdminsConfig) Roster() map[int64]models.AdminRole {
+	roster := make(map[int64]models.AdminRole, len(a.Superadmins)+len(a.Moderators))
+	for _, id := range a.Moderators {
+		roster[id] = models.RoleModerator
+	}
+	for _, id := range a.Superadmins {
+		roster[id] = models.RoleSuperadmin
+	}
+	return roster
+}
+
 func Load() (*Config, error) {
 	config := &Config{}
 	
@@ -51,6 +92,13 @@ func Load() (*Config, error) {
 	if port := getEnv("PORT", ""); port != "" {
 		config.Server.Port = port
 	}
+	if token := getEnv("ADMIN_API_TOKEN", ""); token != "" {
+		config.Server.AdminToken = token
+	}
+	if url := getEnv("PUBLIC_URL", ""); url != "" {
+		config.Server.PublicURL = url
+	}
+	config.Server.PublicURL = strings.TrimRight(config.Server.PublicURL, "/")
 
 	// Load bot tokens from environment variables (required)
 	config.Bots = BotsConfig{
@@ -58,6 +106,20 @@ func Load() (*Config, error) {
 		AdminBotToken:  getEnv("ADMIN_BOT_TOKEN", ""),
 	}
 
+	if dir := getEnv("PHOTOS_DIR", ""); dir != "" {
+		config.Storage.PhotosDir = dir
+	}
+	if config.Storage.PhotosDir == "" {
+		config.Storage.PhotosDir = "data/photos"
+	}
+
+	if ids := getEnvInt64List("SUPERADMIN_IDS"); ids != nil {
+		config.Admins.Superadmins = ids
+	}
+	if ids := getEnvInt64List("MODERATOR_IDS"); ids != nil {
+		config.Admins.Moderators = ids
+	}
+
 	// Validate required fields
 	if config.Bots.DriverBotToken == "" {
 		return nil, fmt.Errorf("DRIVER_BOT_TOKEN is required")
@@ -84,3 +146,20 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvInt64List parses a comma-separated list of IDs, returning nil if the
+// variable is unset. Invalid entries are skipped.
+func getEnvInt64List(key string) []int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	ids := []int64{}
+	for _, part := range strings.Split(value, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -12,3 +12,8 @@ require (
 )
 
 require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/image v0.14.0
+	golang.org/x/text v0.14.0 // indirect
+)
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+// withActor attributes changes made by a request to the API channel
+func (api *API) withActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := service.WithActor(r.Context(), models.Actor{
+			Channel:   models.ChannelAPI,
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireAdmin checks the "Authorization: Bearer <token>" header against the admin token
+func (api *API) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(api.adminToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetAudit returns the audit log, filtered by entity_type, entity_uuid, actor_id and channel
+func (api *API) GetAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		EntityType: query.Get("entity_type"),
+		Channel:    models.Channel(query.Get("channel")),
+	}
+
+	if entityUUID := query.Get("entity_uuid"); entityUUID != "" {
+		id, err := uuid.Parse(entityUUID)
+		if err != nil {
+			http.Error(w, "Invalid entity_uuid", http.StatusBadRequest)
+			return
+		}
+		filter.EntityUUID = &id
+	}
+	if actorID := query.Get("actor_id"); actorID != "" {
+		id, err := strconv.ParseInt(actorID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid actor_id", http.StatusBadRequest)
+			return
+		}
+		filter.ActorID = &id
+	}
+	if page := query.Get("page"); page != "" {
+		if val, err := strconv.Atoi(page); err == nil && val > 0 {
+			filter.Page = val
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if val, err := strconv.Atoi(limit); err == nil && val > 0 && val <= 100 {
+			filter.Limit = val
+		}
+	}
+
+	// Set defaults
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	entries, total, err := api.service.ListAudit(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.AuditResponse{
+		Page:    filter.Page,
+		Limit:   filter.Limit,
+		Total:   total,
+		Entries: entries,
+	}
+	if response.Entries == nil {
+		response.Entries = []models.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -7,28 +7,109 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"gruzy-ryadom/internal/feed"
 	"gruzy-ryadom/internal/models"
 	"gruzy-ryadom/internal/service"
 )
 
 type API struct {
-	service *service.Service
+	service    *service.Service
+	adminToken string
+	publicURL  string
+	feed       *feed.Hub
 }
 
-func New(service *service.Service) *API {
-	return &API{service: service}
+// New creates the HTTP API. Admin endpoints are enabled only when adminToken
+// is set; the live order stream needs a feed hub. publicURL prefixes links in
+// RSS/Atom feeds.
+func New(service *service.Service, adminToken, publicURL string, feed *feed.Hub) *API {
+	return &API{service: service, adminToken: adminToken, publicURL: publicURL, feed: feed}
 }
 
 func (api *API) Routes() chi.Router {
 	r := chi.NewRouter()
+	r.Use(api.withActor)
 	
 	// Public API
 	r.Get("/v1/orders", api.GetOrders)
+	r.Get("/v1/orders/export", api.GetOrdersExport)
+	r.Get(streamPath, api.GetOrdersStream)
+	r.Get("/v1/orders.rss", api.GetOrdersRSS)
+	r.Get("/v1/orders.atom", api.GetOrdersAtom)
+	r.With(api.identifyDriver).Get("/v1/orders/{uuid}", api.GetOrder)
+	r.Get("/v1/orders/{uuid}/photos", api.GetOrderPhotos)
+	r.Get("/v1/photos/{uuid}", api.GetPhoto)
+	r.Get("/v1/photos/{uuid}/thumbnail", api.GetPhotoThumbnail)
+	r.Get("/v1/price-estimate", api.GetPriceEstimate)
+
+	// Driver API
+	r.With(api.requireDriver).Post("/v1/orders/{uuid}/contacts", api.PostOrderContacts)
+	r.With(api.requireDriver).Get("/v1/orders/{uuid}/stats", api.GetOrderStats)
+	r.With(api.requireDriver).Get("/v1/orders/{uuid}/waybill.pdf", api.GetWaybill)
+	r.With(api.requireDriver).Post("/v1/orders/import", api.PostOrdersImport)
+
+	// Admin API
+	if api.adminToken != "" {
+		r.Route("/v1/admin", func(r chi.Router) {
+			r.Use(api.requireAdmin)
+			r.Get("/audit", api.GetAudit)
+			r.Get("/orders/export", api.GetAdminOrdersExport)
+			r.Get("/customers/export", api.GetAdminCustomersExport)
+			r.Get("/webhooks", api.GetWebhooks)
+			r.Post("/webhooks", api.PostWebhook)
+			r.Get("/webhooks/{uuid}", api.GetWebhook)
+			r.Put("/webhooks/{uuid}", api.PutWebhook)
+			r.Delete("/webhooks/{uuid}", api.DeleteWebhook)
+			r.Post("/webhooks/{uuid}/ping", api.PostWebhookPing)
+			r.Get("/webhooks/{uuid}/deliveries", api.GetWebhookDeliveries)
+			r.Post("/webhook-deliveries/{uuid}/retry", api.PostWebhookDeliveryRetry)
+		})
+	}
 	
 	return r
 }
 
 func (api *API) GetOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		http.Error(w, "Unsupported currency", http.StatusBadRequest)
+		return
+	}
+
+	// Set defaults
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+
+	orders, total, err := api.service.ListOrders(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Contacts are only revealed one by one to authenticated drivers
+	public := make([]models.PublicOrder, 0, len(orders))
+	for _, order := range orders {
+		public = append(public, order.Public())
+	}
+
+	response := models.OrdersResponse{
+		Page:   filter.Page,
+		Limit:  filter.Limit,
+		Total:  total,
+		Orders: public,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseOrderFilter reads the order filter from query parameters; it fails
+// only on an unsupported currency
+func parseOrderFilter(r *http.Request) (models.OrderFilter, error) {
 	filter := models.OrderFilter{}
 
 	// Parse query parameters
@@ -72,14 +153,22 @@ func (api *API) GetOrders(w http.ResponseWriter, r *http.Request) {
 			filter.MaxHeight = val
 		}
 	}
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		val, err := models.ParseCurrency(currency)
+		if err != nil {
+			return filter, err
+		}
+		filter.Currency = val
+	}
+	// Price bounds are in units of the currency, RUB by default
 	if minPrice := r.URL.Query().Get("min_price"); minPrice != "" {
-		if val, err := strconv.ParseFloat(minPrice, 64); err == nil {
-			filter.MinPrice = val
+		if val, err := models.ParseMoney(minPrice, filter.Currency); err == nil {
+			filter.MinPrice = val.Amount
 		}
 	}
 	if maxPrice := r.URL.Query().Get("max_price"); maxPrice != "" {
-		if val, err := strconv.ParseFloat(maxPrice, 64); err == nil {
-			filter.MaxPrice = val
+		if val, err := models.ParseMoney(maxPrice, filter.Currency); err == nil {
+			filter.MaxPrice = val.Amount
 		}
 	}
 	if tags := r.URL.Query().Get("tags"); tags != "" {
@@ -91,6 +180,9 @@ func (api *API) GetOrders(w http.ResponseWriter, r *http.Request) {
 	if to := r.URL.Query().Get("to"); to != "" {
 		filter.To = to
 	}
+	if location := r.URL.Query().Get("location"); location != "" {
+		filter.Location = location
+	}
 	if page := r.URL.Query().Get("page"); page != "" {
 		if val, err := strconv.Atoi(page); err == nil && val > 0 {
 			filter.Page = val
@@ -108,27 +200,5 @@ func (api *API) GetOrders(w http.ResponseWriter, r *http.Request) {
 		filter.SortOrder = sortOrder
 	}
 
-	// Set defaults
-	if filter.Page <= 0 {
-		filter.Page = 1
-	}
-	if filter.Limit <= 0 {
-		filter.Limit = 20
-	}
-
-	orders, total, err := api.service.ListOrders(r.Context(), filter)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-
-	response := models.OrdersResponse{
-		Page:   filter.Page,
-		Limit:  filter.Limit,
-		Total:  total,
-		Orders: orders,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return filter, nil
 }
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+type driverKey struct{}
+
+// identifyDriver authenticates a driver by the "Authorization: Bearer <token>"
+// header, where the token is issued by the /api_token command of the driver
+// bot. Requests without the header pass through anonymously.
+func (api *API) identifyDriver(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		driver, err := api.service.AuthenticateAPIToken(r.Context(), token)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if driver == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// A banned user's token stays valid but opens nothing until the ban ends
+		ban, err := api.service.GetActiveBanByCustomer(r.Context(), driver.UUID)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if ban != nil {
+			http.Error(w, "Forbidden: "+ban.Reason, http.StatusForbidden)
+			return
+		}
+
+		actor := service.ActorFrom(r.Context())
+		actor.TelegramID = driver.TelegramID
+		ctx := service.WithActor(r.Context(), actor)
+		ctx = context.WithValue(ctx, driverKey{}, driver)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireDriver rejects requests that identifyDriver left anonymous
+func (api *API) requireDriver(next http.Handler) http.Handler {
+	return api.identifyDriver(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if driverFrom(r.Context()) == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func driverFrom(ctx context.Context) *models.Customer {
+	driver, _ := ctx.Value(driverKey{}).(*models.Customer)
+	return driver
+}
+
+// PostOrderContacts reveals the contacts of an order's customer to the authenticated driver
+func (api *API) PostOrderContacts(w http.ResponseWriter, r *http.Request) {
+	driver := driverFrom(r.Context())
+
+	contacts, err := api.service.RevealContacts(r.Context(), chi.URLParam(r, "uuid"), driver.UUID)
+	switch {
+	case errors.Is(err, service.ErrBanned):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrRevealLimit):
+		http.Error(w, "Daily contact reveal limit reached", http.StatusTooManyRequests)
+		return
+	case errors.Is(err, service.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(contacts)
+}
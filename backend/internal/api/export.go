@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gruzy-ryadom/internal/export"
+	"gruzy-ryadom/internal/models"
+)
+
+// GetOrdersExport streams the published orders matching the same filters as
+// GetOrders, without pagination, as ?format=csv (default) or ?format=xlsx
+func (api *API) GetOrdersExport(w http.ResponseWriter, r *http.Request) {
+	api.exportOrders(w, r, false)
+}
+
+// GetAdminOrdersExport also exports unpublished orders and customers' contacts
+func (api *API) GetAdminOrdersExport(w http.ResponseWriter, r *http.Request) {
+	api.exportOrders(w, r, true)
+}
+
+func (api *API) exportOrders(w http.ResponseWriter, r *http.Request, admin bool) {
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		http.Error(w, "Unsupported currency", http.StatusBadRequest)
+		return
+	}
+
+	writer := startExport(w, r, format, "orders")
+	if writer == nil {
+		return
+	}
+	// The status is already sent, a failure can only cut the file short
+	if err := api.service.ExportOrders(r.Context(), filter, writer, admin); err != nil {
+		log.Printf("Failed to export orders: %v", err)
+	}
+}
+
+// GetAdminCustomersExport streams customers filtered by name, phone and telegram_tag
+func (api *API) GetAdminCustomersExport(w http.ResponseWriter, r *http.Request) {
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "Unsupported format", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	filter := models.CustomerFilter{
+		Name:        query.Get("name"),
+		Phone:       query.Get("phone"),
+		TelegramTag: strings.TrimPrefix(query.Get("telegram_tag"), "@"),
+		SortBy:      query.Get("sort_by"),
+		SortOrder:   query.Get("sort_order"),
+	}
+
+	writer := startExport(w, r, format, "customers")
+	if writer == nil {
+		return
+	}
+	if err := api.service.ExportCustomers(r.Context(), filter, writer); err != nil {
+		log.Printf("Failed to export customers: %v", err)
+	}
+}
+
+// startExport sends the headers of a file download and returns its writer,
+// or nil after replying with an error
+func startExport(w http.ResponseWriter, r *http.Request, format export.Format, name string) export.Writer {
+	// A large file outlives the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear the write deadline of %s export %s: %v", name, r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format("2006-01-02"), format))
+	w.Header().Set("Cache-Control", "no-store")
+
+	writer, err := export.NewWriter(w, format, name)
+	if err != nil {
+		log.Printf("Failed to start %s export: %v", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil
+	}
+	return writer
+}
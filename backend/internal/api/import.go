@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gruzy-ryadom/internal/export"
+	"gruzy-ryadom/internal/service"
+)
+
+// PostOrdersImport creates orders of the authenticated customer from a CSV or
+// XLSX file, sent as the "file" field of a multipart form or as the raw body.
+// Query parameters: format (csv|xlsx, guessed from the file name if omitted),
+// dry_run=true to only validate, columns=field:Header,... to map columns.
+func (api *API) PostOrdersImport(w http.ResponseWriter, r *http.Request) {
+	customer := driverFrom(r.Context())
+	query := r.URL.Query()
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxImportBytes+1<<20)
+
+	body, filename := io.Reader(r.Body), ""
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body, filename = file, header.Filename
+	}
+
+	format, ok := export.FormatOf(filename)
+	if name := query.Get("format"); name != "" || !ok {
+		var err error
+		if format, err = export.ParseFormat(name); err != nil {
+			http.Error(w, "Unsupported format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, service.MaxImportBytes+1))
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+	if len(data) > service.MaxImportBytes {
+		http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	rows, err := export.ReadRows(data, format, service.MaxImportRows+1)
+	if errors.Is(err, service.ErrImportTooLarge) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to parse file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mapping := map[string]string{}
+	if columns := query.Get("columns"); columns != "" {
+		for _, pair := range strings.Split(columns, ",") {
+			field, header, ok := strings.Cut(pair, ":")
+			if !ok {
+				http.Error(w, "Invalid columns, expected field:Header,...", http.StatusBadRequest)
+				return
+			}
+			mapping[strings.TrimSpace(field)] = strings.TrimSpace(header)
+		}
+	}
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+
+	result, err := api.service.ImportOrders(r.Context(), customer.UUID, rows, mapping, dryRun)
+	switch {
+	case errors.Is(err, service.ErrBanned):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	case errors.Is(err, service.ErrImportColumns), errors.Is(err, service.ErrImportTooLarge):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Failed to import orders of %s: %v", customer.UUID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Created) > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
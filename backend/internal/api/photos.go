@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/storage"
+)
+
+// photoResponse is an order photo with the URLs it is served at
+type photoResponse struct {
+	models.OrderPhoto
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// GetOrderPhotos lists the photos of a published order
+func (api *API) GetOrderPhotos(w http.ResponseWriter, r *http.Request) {
+	order, err := api.service.GetPublicOrder(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if order == nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	photos, err := api.service.ListOrderPhotos(r.Context(), order.UUID)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]photoResponse, 0, len(photos))
+	for _, photo := range photos {
+		response = append(response, photoResponse{
+			OrderPhoto:   photo,
+			URL:          "/v1/photos/" + photo.UUID.String(),
+			ThumbnailURL: "/v1/photos/" + photo.UUID.String() + "/thumbnail",
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"photos": response})
+}
+
+// GetPhoto serves a photo file of a published order
+func (api *API) GetPhoto(w http.ResponseWriter, r *http.Request) {
+	api.servePhoto(w, r, false)
+}
+
+// GetPhotoThumbnail serves the JPEG thumbnail of a photo of a published order
+func (api *API) GetPhotoThumbnail(w http.ResponseWriter, r *http.Request) {
+	api.servePhoto(w, r, true)
+}
+
+func (api *API) servePhoto(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	photo, err := api.service.GetOrderPhoto(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil || photo == nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+	order, err := api.service.GetPublicOrder(r.Context(), photo.OrderUUID.String())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if order == nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+
+	file, err := api.service.OpenOrderPhoto(r.Context(), *photo, thumbnail)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open photo %s: %v", photo.UUID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	contentType, etag := photo.ContentType, `"`+photo.UUID.String()+`"`
+	if thumbnail {
+		contentType, etag = "image/jpeg", `"`+photo.UUID.String()+`-thumb"`
+	}
+	// A photo never changes once uploaded, but its order may be unpublished,
+	// so it is cached for a limited time only
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("ETag", etag)
+	if strings.Contains(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	io.Copy(w, file)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// GetPriceEstimate suggests a price range for a cargo from similar past orders
+func (api *API) GetPriceEstimate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	weight, err := strconv.ParseFloat(query.Get("weight"), 64)
+	if err != nil || weight <= 0 {
+		http.Error(w, "Invalid weight", http.StatusBadRequest)
+		return
+	}
+	input := models.PriceEstimateInput{WeightKg: weight}
+
+	if from := query.Get("from"); from != "" {
+		input.From = &from
+	}
+	if to := query.Get("to"); to != "" {
+		input.To = &to
+	}
+	if currency := query.Get("currency"); currency != "" {
+		val, err := models.ParseCurrency(currency)
+		if err != nil {
+			http.Error(w, "Unsupported currency", http.StatusBadRequest)
+			return
+		}
+		input.Currency = val
+	}
+	if tags := query.Get("tags"); tags != "" {
+		input.Tags = strings.Split(tags, ",")
+	}
+	for name, dest := range map[string]**float64{"length": &input.LengthCm, "width": &input.WidthCm, "height": &input.HeightCm} {
+		if value := query.Get(name); value != "" {
+			val, err := strconv.ParseFloat(value, 64)
+			if err != nil || val <= 0 {
+				http.Error(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dest = &val
+		}
+	}
+	if date := query.Get("date"); date != "" {
+		val, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+		input.Date = &val
+	}
+
+	estimate, err := api.service.EstimatePrice(r.Context(), input)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if estimate == nil {
+		http.Error(w, "Not enough similar orders", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estimate)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	streamPath      = "/v1/orders/stream"
+	streamKeepalive = 25 * time.Second
+	streamRetry     = 5 * time.Second
+)
+
+// GetOrdersStream pushes published orders as they are created or updated,
+// as Server-Sent Events. It takes the same filters as GET /v1/orders; each
+// event is "created", "updated" or "removed" with a JSON body.
+func (api *API) GetOrdersStream(w http.ResponseWriter, r *http.Request) {
+	if api.feed == nil {
+		http.Error(w, "Live feed is disabled", http.StatusServiceUnavailable)
+		return
+	}
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		http.Error(w, "Unsupported currency", http.StatusBadRequest)
+		return
+	}
+
+	// The stream outlives the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear the write deadline of the order stream: %v", err)
+	}
+
+	sub := api.feed.Subscribe(filter)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering in nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case event, ok := <-sub.Events():
+			if !ok {
+				return // too slow or shutting down, the client reconnects and catches up with GET /v1/orders
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Long-running responses that the Timeout middleware leaves alone
+var untimedPaths = map[string]bool{
+	streamPath:                   true,
+	"/v1/orders/export":          true,
+	"/v1/admin/orders/export":    true,
+	"/v1/admin/customers/export": true,
+}
+
+// Timeout cancels requests running longer than timeout, except the live
+// feed stream that stays open and the exports that stream whole tables
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	limit := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		limited := limit(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if untimedPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
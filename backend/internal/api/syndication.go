@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/syndication"
+)
+
+// Feed readers poll often; a feed is fresh for a few minutes
+const feedCacheControl = "public, max-age=300"
+
+// GetOrdersRSS serves the published orders matching the same filters and
+// pagination as GetOrders as an RSS 2.0 feed
+func (api *API) GetOrdersRSS(w http.ResponseWriter, r *http.Request) {
+	api.serveOrderFeed(w, r, syndication.RSS)
+}
+
+// GetOrdersAtom serves the same feed as GetOrdersRSS in Atom
+func (api *API) GetOrdersAtom(w http.ResponseWriter, r *http.Request) {
+	api.serveOrderFeed(w, r, syndication.Atom)
+}
+
+func (api *API) serveOrderFeed(w http.ResponseWriter, r *http.Request, format syndication.Format) {
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		http.Error(w, "Unsupported currency", http.StatusBadRequest)
+		return
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+
+	orders, _, err := api.service.ListOrders(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	base := api.baseURL(r)
+	query := ""
+	if r.URL.RawQuery != "" {
+		query = "?" + r.URL.RawQuery
+	}
+	feed := syndication.Feed{
+		Title:       "Грузы рядом: " + describeOrderFilter(filter),
+		Description: "Новые опубликованные заказы на перевозку грузов",
+		SelfURL:     base + r.URL.Path + query,
+		Link:        base + "/v1/orders" + query,
+		Orders:      orders,
+		OrderURL: func(order models.Order) string {
+			return base + "/v1/orders/" + order.UUID.String()
+		},
+	}
+	body, err := syndication.Render(feed, format)
+	if err != nil {
+		log.Printf("Failed to render orders feed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// ServeContent answers a matching If-None-Match with 304. Orders have no
+	// modification time, so there is no Last-Modified: only the content ETag
+	// notices edited and removed orders.
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", feedCacheControl)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// baseURL is the configured public URL or the scheme and host the request came to
+func (api *API) baseURL(r *http.Request) string {
+	if api.publicURL != "" {
+		return api.publicURL
+	}
+	scheme := "http"
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// describeOrderFilter names a search for feed titles, e.g. "Москва → Казань, #тент"
+func describeOrderFilter(filter models.OrderFilter) string {
+	var parts []string
+	switch {
+	case filter.From != "" || filter.To != "":
+		from, to := filter.From, filter.To
+		if from == "" {
+			from = "…"
+		}
+		if to == "" {
+			to = "…"
+		}
+		parts = append(parts, from+" → "+to)
+	case filter.Location != "":
+		parts = append(parts, filter.Location)
+	}
+	for _, tag := range filter.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	if len(parts) == 0 {
+		return "все заказы"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+// GetOrder returns a published order and counts the caller as its viewer
+func (api *API) GetOrder(w http.ResponseWriter, r *http.Request) {
+	order, err := api.service.GetPublicOrder(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if order == nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	// RealIP leaves the port in RemoteAddr when there is no proxy header
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	viewer := service.AnonymousViewer(ip, r.UserAgent())
+	if driver := driverFrom(r.Context()); driver != nil {
+		viewer = service.CustomerViewer(driver.UUID)
+	}
+	if err := api.service.RecordOrderView(r.Context(), *order, viewer); err != nil {
+		log.Printf("Failed to record view of order %s: %v", order.UUID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order.Public())
+}
+
+// GetOrderStats returns the view and contact reveal counts of an order to its owner
+func (api *API) GetOrderStats(w http.ResponseWriter, r *http.Request) {
+	owner := driverFrom(r.Context())
+
+	order, err := api.service.GetOrder(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil || order == nil || order.CustomerUUID != owner.UUID {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	stats, err := api.service.GetOrderStats(r.Context(), []uuid.UUID{order.UUID})
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	result, ok := stats[order.UUID]
+	if !ok {
+		result = models.OrderStats{OrderUUID: order.UUID}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(result)
+}
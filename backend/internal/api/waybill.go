@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"gruzy-ryadom/internal/service"
+	"gruzy-ryadom/internal/waybill"
+)
+
+// GetWaybill returns the PDF waybill of a taken order to its customer or
+// carrier. The form is chosen with ?form=ttn (default) or ?form=cmr.
+func (api *API) GetWaybill(w http.ResponseWriter, r *http.Request) {
+	driver := driverFrom(r.Context())
+
+	form, err := waybill.ParseForm(r.URL.Query().Get("form"))
+	if err != nil {
+		http.Error(w, "Unsupported form", http.StatusBadRequest)
+		return
+	}
+
+	pdf, err := api.service.Waybill(r.Context(), chi.URLParam(r, "uuid"), &driver.UUID, form)
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrNoCarrier):
+		http.Error(w, "Order has not been taken by a driver", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="waybill-%s.pdf"`, chi.URLParam(r, "uuid")))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(pdf)
+}
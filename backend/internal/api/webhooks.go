@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+// GetWebhooks lists the webhook subscriptions
+func (api *API) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := api.service.ListWebhooks(r.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// PostWebhook subscribes an endpoint to order events. The response is the
+// only one that contains the signing secret.
+func (api *API) PostWebhook(w http.ResponseWriter, r *http.Request) {
+	var input models.CreateWebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := api.service.CreateWebhook(r.Context(), input)
+	if errors.Is(err, service.ErrInvalidWebhook) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (api *API) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, err := api.service.GetWebhook(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if webhook == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// PutWebhook changes the URL or events of a webhook, or pauses it with "active": false
+func (api *API) PutWebhook(w http.ResponseWriter, r *http.Request) {
+	var input models.UpdateWebhookInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := api.service.UpdateWebhook(r.Context(), chi.URLParam(r, "uuid"), input)
+	if errors.Is(err, service.ErrInvalidWebhook) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if webhook == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (api *API) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	deleted, err := api.service.DeleteWebhook(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostWebhookPing sends a signed ping event to check the endpoint
+func (api *API) PostWebhookPing(w http.ResponseWriter, r *http.Request) {
+	queued, err := api.service.PingWebhook(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !queued {
+		http.Error(w, "Webhook not found or paused", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first,
+// filtered by status and limited with limit (50 by default, up to 200)
+func (api *API) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, err := api.service.GetWebhook(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if webhook == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.WebhookPending, models.WebhookDelivered, models.WebhookFailed:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 && val <= 200 {
+		limit = val
+	}
+
+	deliveries, err := api.service.ListWebhookDeliveries(r.Context(), webhook.UUID, status, limit)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// PostWebhookDeliveryRetry starts over a delivery that was given up on
+func (api *API) PostWebhookDeliveryRetry(w http.ResponseWriter, r *http.Request) {
+	delivery, err := api.service.RetryWebhookDelivery(r.Context(), chi.URLParam(r, "uuid"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if delivery == nil {
+		http.Error(w, "Failed delivery not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
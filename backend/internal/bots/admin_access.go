@@ -0,0 +1,142 @@
+package bots
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+// roleKey is the telebot context key holding the sender's admin role
+const roleKey = "admin_role"
+
+// roleOf resolves the admin role of a Telegram user. The config roster takes
+// precedence over the database; an empty role means no access.
+func (b *AdminBot) roleOf(telegramID int64) (models.AdminRole, error) {
+	if role, ok := b.roster[telegramID]; ok {
+		return role, nil
+	}
+
+	admin, err := b.service.GetAdmin(b.ctx, telegramID)
+	if err != nil {
+		return "", err
+	}
+	if admin == nil {
+		return "", nil
+	}
+	return admin.Role, nil
+}
+
+// role returns the role stored by requireRole for the current update
+func (b *AdminBot) role(c telebot.Context) models.AdminRole {
+	role, _ := c.Get(roleKey).(models.AdminRole)
+	return role
+}
+
+// requireRole is a middleware that lets the update through only if the
+// sender's role grants the required access level. Denied attempts are logged.
+func (b *AdminBot) requireRole(required models.AdminRole) telebot.MiddlewareFunc {
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			user := c.Sender()
+			if user == nil {
+				return nil
+			}
+
+			role, err := b.roleOf(user.ID)
+			if err != nil {
+				log.Printf("Admin bot: failed to resolve role for %d: %v", user.ID, err)
+				return c.Send("❌ Ошибка проверки доступа.")
+			}
+
+			if !role.Allows(required) {
+				log.Printf("Admin bot: denied %q for user %d (@%s), role %q, requires %q",
+					c.Text(), user.ID, user.Username, role, required)
+				if c.Callback() != nil {
+					return c.Respond(&telebot.CallbackResponse{Text: "⛔ Доступ запрещен."})
+				}
+				return c.Send("⛔ Доступ запрещен.")
+			}
+
+			c.Set(roleKey, role)
+			return next(c)
+		}
+	}
+}
+
+func (b *AdminBot) handleAdmins(c telebot.Context) error {
+	admins, err := b.service.ListAdmins(b.ctx)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении списка администраторов.")
+	}
+
+	var msg strings.Builder
+	msg.WriteString("👑 Администраторы из конфигурации:\n")
+	for id, role := range b.roster {
+		msg.WriteString(fmt.Sprintf("• %d — %s\n", id, role))
+	}
+
+	msg.WriteString("\n🔧 Назначены через бота:\n")
+	if len(admins) == 0 {
+		msg.WriteString("пока никого\n")
+	}
+	for _, admin := range admins {
+		msg.WriteString(fmt.Sprintf("• %d — %s (с %s)\n", admin.TelegramID, admin.Role, admin.CreatedAt.Format("02.01.2006")))
+	}
+
+	msg.WriteString("\n/add_admin <telegram_id> <moderator|superadmin>\n/remove_admin <telegram_id>")
+
+	return c.Send(msg.String())
+}
+
+func (b *AdminBot) handleAddAdmin(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 2 {
+		return c.Send("Использование: /add_admin <telegram_id> <moderator|superadmin>")
+	}
+
+	telegramID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Send("❌ Некорректный Telegram ID.")
+	}
+	if _, ok := b.roster[telegramID]; ok {
+		return c.Send("❌ Роль этого администратора задана в конфигурации.")
+	}
+
+	admin, err := b.service.SaveAdmin(b.ctx, telegramID, models.AdminRole(args[1]), c.Sender().ID)
+	if err != nil {
+		return c.Send(fmt.Sprintf("❌ Не удалось назначить роль: %v", err))
+	}
+
+	log.Printf("Admin bot: %d granted role %q to %d", c.Sender().ID, admin.Role, admin.TelegramID)
+	return c.Send(fmt.Sprintf("✅ %d теперь %s.", admin.TelegramID, admin.Role))
+}
+
+func (b *AdminBot) handleRemoveAdmin(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /remove_admin <telegram_id>")
+	}
+
+	telegramID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Send("❌ Некорректный Telegram ID.")
+	}
+	if _, ok := b.roster[telegramID]; ok {
+		return c.Send("❌ Администратора из конфигурации нельзя удалить через бота.")
+	}
+
+	removed, err := b.service.DeleteAdmin(b.ctx, telegramID)
+	if err != nil {
+		return c.Send("❌ Не удалось отозвать доступ.")
+	}
+	if !removed {
+		return c.Send("Этот пользователь не является администратором.")
+	}
+
+	log.Printf("Admin bot: %d revoked access of %d", c.Sender().ID, telegramID)
+	return c.Send(fmt.Sprintf("✅ Доступ %d отозван.", telegramID))
+}
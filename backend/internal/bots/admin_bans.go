@@ -0,0 +1,236 @@
+package bots
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+const bansListLimit = 30
+
+var banDurationRe = regexp.MustCompile(`^(\d+)\s*(h|ч|d|д|w|н)$`)
+
+const banUsage = `Использование: /ban <цель> <срок> <причина>
+
+Цель: Telegram ID, +телефон, @тег или UUID заказчика
+Срок: 12h, 7d, 4w или "навсегда"
+
+Пример: /ban +79001234567 30d спам`
+
+// parseBanExpiry turns a duration like "7d" into an expiry time; nil means permanent
+func parseBanExpiry(text string) (*time.Time, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "навсегда" || text == "forever" {
+		return nil, true
+	}
+
+	m := banDurationRe.FindStringSubmatch(text)
+	if m == nil {
+		return nil, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n <= 0 {
+		return nil, false
+	}
+
+	unit := map[string]time.Duration{
+		"h": time.Hour, "ч": time.Hour,
+		"d": 24 * time.Hour, "д": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour, "н": 7 * 24 * time.Hour,
+	}[m[2]]
+	expiresAt := time.Now().UTC().Add(time.Duration(n) * unit)
+	return &expiresAt, true
+}
+
+// resolveBanTarget fills the identifier of a ban from a command argument
+func (b *AdminBot) resolveBanTarget(target string, input *models.CreateBanInput) error {
+	switch {
+	case strings.HasPrefix(target, "+"):
+		input.Phone = &target
+	case strings.HasPrefix(target, "@"):
+		tag := strings.TrimPrefix(target, "@")
+		customers, _, err := b.service.ListCustomers(b.ctx, models.CustomerFilter{TelegramTag: tag, Limit: 20})
+		if err != nil {
+			return err
+		}
+		for _, customer := range customers {
+			if customer.TelegramTag != nil && strings.EqualFold(*customer.TelegramTag, tag) {
+				input.CustomerUUID = &customer.UUID
+				return nil
+			}
+		}
+		return fmt.Errorf("заказчик %s не найден", target)
+	default:
+		if id, err := uuid.Parse(target); err == nil {
+			customer, err := b.service.GetCustomer(b.ctx, target)
+			if err != nil {
+				return err
+			}
+			if customer == nil {
+				return fmt.Errorf("заказчик %s не найден", target)
+			}
+			input.CustomerUUID = &id
+			return nil
+		}
+		telegramID, err := strconv.ParseInt(target, 10, 64)
+		if err != nil {
+			return fmt.Errorf("не удалось распознать цель %q", target)
+		}
+		input.TelegramID = &telegramID
+	}
+	return nil
+}
+
+func (b *AdminBot) handleBan(c telebot.Context) error {
+	args := c.Args()
+	if len(args) < 3 {
+		return c.Send(banUsage)
+	}
+
+	input := models.CreateBanInput{
+		Reason:    strings.Join(args[2:], " "),
+		CreatedBy: c.Sender().ID,
+	}
+	if err := b.resolveBanTarget(args[0], &input); err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	expiresAt, ok := parseBanExpiry(args[1])
+	if !ok {
+		return c.Send(banUsage)
+	}
+	input.ExpiresAt = expiresAt
+
+	return b.createBan(c, input)
+}
+
+func (b *AdminBot) createBan(c telebot.Context, input models.CreateBanInput) error {
+	ban, err := b.service.CreateBan(b.ctx, input)
+	if err != nil {
+		log.Printf("Admin bot: failed to create ban: %v", err)
+		return c.Send("❌ Не удалось заблокировать пользователя.")
+	}
+	log.Printf("Admin bot: %d banned %s: %s", c.Sender().ID, banTarget(ban), ban.Reason)
+
+	return c.Send("🚫 Заблокирован: " + formatBanLine(ban))
+}
+
+// handleUnban lifts a ban by its UUID or every ban of a Telegram user or customer
+func (b *AdminBot) handleUnban(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /unban <uuid бана|telegram_id|uuid заказчика>")
+	}
+	target := args[0]
+
+	if _, err := uuid.Parse(target); err == nil {
+		lifted, err := b.service.LiftBan(b.ctx, target, c.Sender().ID)
+		if err != nil {
+			return c.Send("❌ Ошибка при снятии блокировки.")
+		}
+		if lifted {
+			log.Printf("Admin bot: %d lifted ban %s", c.Sender().ID, target)
+			return c.Send("✅ Блокировка снята.")
+		}
+	}
+
+	var find func() (*models.Ban, error)
+	if id, err := uuid.Parse(target); err == nil {
+		find = func() (*models.Ban, error) { return b.service.GetActiveBanByCustomer(b.ctx, id) }
+	} else if telegramID, err := strconv.ParseInt(target, 10, 64); err == nil {
+		find = func() (*models.Ban, error) { return b.service.GetActiveBanByTelegramID(b.ctx, telegramID) }
+	} else {
+		return c.Send("❌ Не удалось распознать цель.")
+	}
+
+	// A user can be covered by several bans, e.g. by phone and by Telegram ID
+	lifted := 0
+	for {
+		ban, err := find()
+		if err != nil {
+			return c.Send("❌ Ошибка при снятии блокировки.")
+		}
+		if ban == nil {
+			break
+		}
+		ok, err := b.service.LiftBan(b.ctx, ban.UUID.String(), c.Sender().ID)
+		if err != nil || !ok {
+			return c.Send("❌ Ошибка при снятии блокировки.")
+		}
+		log.Printf("Admin bot: %d lifted ban %s", c.Sender().ID, ban.UUID)
+		lifted++
+	}
+
+	if lifted == 0 {
+		return c.Send("Активных блокировок не найдено.")
+	}
+	return c.Send(fmt.Sprintf("✅ Снято блокировок: %d", lifted))
+}
+
+func (b *AdminBot) handleBans(c telebot.Context) error {
+	bans, err := b.service.ListActiveBans(b.ctx, bansListLimit)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении блокировок.")
+	}
+	if len(bans) == 0 {
+		return c.Send("✅ Активных блокировок нет.")
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("🚫 Активные блокировки (%d):\n\n", len(bans)))
+	for i, ban := range bans {
+		msg.WriteString(fmt.Sprintf("%d. %s\n   🆔 %s\n\n", i+1, formatBanLine(ban), ban.UUID))
+	}
+	msg.WriteString("Снять: /unban <uuid>")
+
+	return c.Send(msg.String())
+}
+
+// banCustomer handles the "срок причина" reply to the ban button of a customer card
+func (b *AdminBot) banCustomer(c telebot.Context, input *pendingInput) error {
+	duration, reason, _ := strings.Cut(strings.TrimSpace(c.Text()), " ")
+	expiresAt, ok := parseBanExpiry(duration)
+	if !ok || strings.TrimSpace(reason) == "" {
+		return c.Send("❌ Формат: <срок> <причина>, например: 7d спам\n\nИсправьте и отправьте снова или /cancel.")
+	}
+	b.setInput(c.Sender().ID, nil)
+
+	id, err := uuid.Parse(input.id)
+	if err != nil {
+		return c.Send("❌ Заказчик не найден.")
+	}
+
+	return b.createBan(c, models.CreateBanInput{
+		CustomerUUID: &id,
+		Reason:       reason,
+		ExpiresAt:    expiresAt,
+		CreatedBy:    c.Sender().ID,
+	})
+}
+
+func banTarget(ban models.Ban) string {
+	switch {
+	case ban.TelegramID != nil:
+		return fmt.Sprintf("Telegram ID %d", *ban.TelegramID)
+	case ban.Phone != nil:
+		return "телефон +" + *ban.Phone
+	case ban.CustomerUUID != nil:
+		return "заказчик " + ban.CustomerUUID.String()
+	default:
+		return "?"
+	}
+}
+
+func formatBanLine(ban models.Ban) string {
+	until := "навсегда"
+	if ban.ExpiresAt != nil {
+		until = "до " + ban.ExpiresAt.Format("02.01.2006 15:04")
+	}
+	return fmt.Sprintf("%s, %s — %s", banTarget(ban), until, ban.Reason)
+}
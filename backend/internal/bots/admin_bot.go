@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/telebot.v3"
@@ -13,13 +14,24 @@ import (
 )
 
 type AdminBot struct {
-	bot     *telebot.Bot
-	service *service.Service
-	ctx     context.Context
-	cancel  context.CancelFunc
+	bot         *telebot.Bot
+	service     *service.Service
+	roster      map[int64]models.AdminRole
+	broadcaster *Broadcaster
+	driverBot   *DriverBot
+	ctx         context.Context
+	cancel      context.CancelFunc
+
+	mu     sync.Mutex
+	drafts map[int64]*broadcastDraft
+	inputs map[int64]*pendingInput
+	lists  map[int64]*listState
 }
 
-func NewAdminBot(token string, service *service.Service) (*AdminBot, error) {
+// NewAdminBot creates the admin bot. The roster holds admins from the config;
+// they cannot be demoted from the bot, additional admins live in the database.
+// Users are notified about moderation decisions through the driver bot.
+func NewAdminBot(token string, service *service.Service, roster map[int64]models.AdminRole, broadcaster *Broadcaster, driverBot *DriverBot) (*AdminBot, error) {
 	pref := telebot.Settings{
 		Token:  token,
 		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
@@ -33,21 +45,67 @@ func NewAdminBot(token string, service *service.Service) (*AdminBot, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &AdminBot{
-		bot:     bot,
-		service: service,
-		ctx:     ctx,
-		cancel:  cancel,
+		bot:         bot,
+		service:     service,
+		roster:      roster,
+		broadcaster: broadcaster,
+		driverBot:   driverBot,
+		ctx:         ctx,
+		cancel:      cancel,
+		drafts:      make(map[int64]*broadcastDraft),
+		inputs:      make(map[int64]*pendingInput),
+		lists:       make(map[int64]*listState),
 	}, nil
 }
 
 func (b *AdminBot) Start() {
-	// Admin commands
-	b.bot.Handle("/start", b.handleStart)
-	b.bot.Handle("/help", b.handleHelp)
-	b.bot.Handle("/customers", b.handleCustomers)
-	b.bot.Handle("/orders", b.handleOrders)
-	b.bot.Handle("/stats", b.handleStats)
-	b.bot.Handle("/broadcast", b.handleBroadcast)
+	// Commands available to every admin
+	moderators := b.bot.Group()
+	moderators.Use(b.requireRole(models.RoleModerator))
+	moderators.Handle("/start", b.handleStart)
+	moderators.Handle("/help", b.handleHelp)
+	moderators.Handle("/customers", b.handleCustomers)
+	moderators.Handle("/orders", b.handleOrders)
+	moderators.Handle("/customer", b.handleCustomer)
+	moderators.Handle("/order", b.handleOrder)
+	moderators.Handle(&btnListPage, b.handleListPage)
+	moderators.Handle(&btnOrderAction, b.handleOrderAction)
+	moderators.Handle(&btnCustomerAction, b.handleCustomerAction)
+	moderators.Handle("/stats", b.handleStats)
+	moderators.Handle(&btnStatsPeriod, b.handleStatsPeriod)
+	moderators.Handle("/chart", b.handleChart)
+	moderators.Handle("/export", b.handleExport)
+	moderators.Handle("/referrals", b.handleReferrals)
+	moderators.Handle("/moderation", b.handleModeration)
+	moderators.Handle(&btnModApprove, b.handleModApprove)
+	moderators.Handle(&btnModReject, b.handleModReject)
+	moderators.Handle(&btnModEdit, b.handleModEdit)
+	moderators.Handle("/trust", b.handleTrust)
+	moderators.Handle("/untrust", b.handleUntrust)
+	moderators.Handle("/audit", b.handleAudit)
+	moderators.Handle("/reviews", b.handleReviews)
+	moderators.Handle("/chats", b.handleChats)
+	moderators.Handle(&btnReviewRemove, b.handleReviewRemove)
+	moderators.Handle("/ban", b.handleBan)
+	moderators.Handle("/unban", b.handleUnban)
+	moderators.Handle("/bans", b.handleBans)
+	moderators.Handle("/cancel", b.handleCancel)
+	moderators.Handle(telebot.OnText, b.handleText)
+	moderators.Handle(telebot.OnPhoto, b.handlePhoto)
+
+	// Commands restricted to superadmins
+	superadmins := b.bot.Group()
+	superadmins.Use(b.requireRole(models.RoleSuperadmin))
+	superadmins.Handle("/broadcast", b.handleBroadcast)
+	superadmins.Handle(&btnBroadcastAudience, b.handleBroadcastAudience)
+	superadmins.Handle(&btnBroadcastConfirm, b.handleBroadcastConfirm)
+	superadmins.Handle(&btnBroadcastDiscard, b.handleBroadcastDiscard)
+	superadmins.Handle("/admins", b.handleAdmins)
+	superadmins.Handle("/add_admin", b.handleAddAdmin)
+	superadmins.Handle("/remove_admin", b.handleRemoveAdmin)
+
+	b.resumeBroadcastWatchers()
+	go b.watchModerationQueue()
 
 	log.Println("Admin Bot started...")
 	b.bot.Start()
@@ -59,32 +117,25 @@ func (b *AdminBot) Stop() {
 }
 
 func (b *AdminBot) handleStart(c telebot.Context) error {
-	user := c.Sender()
-	
-	// Admin check
-	adminIDs := []int64{123456789} // Replace with actual admin IDs
-	
-	isAdmin := false
-	for _, id := range adminIDs {
-		if user.ID == id {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		return c.Send("⛔ У вас нет доступа к административной панели.")
-	}
-
 	msg := `🔧 Административная панель "Грузы рядом"
 
 Доступные команды:
 /customers - Список заказчиков
 /orders - Список заказов
+/customer - Найти заказчика
+/order - Найти заказ
 /stats - Статистика
-/broadcast - Отправить сообщение всем пользователям
+/chart - Графики
+/export - Выгрузка в CSV/XLSX
+/referrals - Рейтинг приглашений
+/moderation - Заказы на модерации
+/bans - Блокировки
 /help - Помощь`
 
+	if b.role(c).Allows(models.RoleSuperadmin) {
+		msg += "\n\n👑 Суперадмин: /broadcast - рассылка, /admins - администраторы"
+	}
+
 	return c.Send(msg)
 }
 
@@ -92,164 +143,71 @@ func (b *AdminBot) handleHelp(c telebot.Context) error {
 	msg := `📋 Административные команды:
 
 /start - Главное меню
-/customers - Просмотр списка заказчиков
-/orders - Просмотр списка заказов
-/stats - Статистика системы
-/broadcast - Массовая рассылка
+/customers [имя|телефон|@тег] - Список заказчиков с фильтром
+/orders [Москва - Казань 500кг #тент] - Список заказов с фильтром
+/customer <телефон|@тег|uuid> - Карточка заказчика
+/order <uuid> - Карточка заказа
+/stats [day|week|month|all] - Статистика за период
+/chart <orders|customers|prices|routes> [период] [RUB|KZT|BYN] - График
+/export <orders|customers> [csv|xlsx] [запрос] - Выгрузка со всеми заказами или заказчиками по запросу
+/referrals - Кто сколько пригласил
+/moderation - Очередь заказов на модерации
+/trust <telegram_id|uuid> - Публиковать заказы клиента без модерации
+/untrust <telegram_id|uuid> - Вернуть заказы клиента на модерацию
+/ban <telegram_id|+телефон|@тег|uuid> <срок> <причина> - Заблокировать
+/unban <uuid бана|telegram_id|uuid заказчика> - Снять блокировку
+/bans - Активные блокировки
+/audit [uuid|telegram_id] - Журнал изменений заказов и заказчиков
+/reviews [uuid заказчика] - Последние отзывы, удаление оскорбительных
+/chats <uuid заказа> - Переписка водителей с заказчиком по заказу
+/cancel - Отменить ввод
 /help - Показать эту справку`
 
-	return c.Send(msg)
-}
-
-func (b *AdminBot) handleCustomers(c telebot.Context) error {
-	// Admin check
-	adminIDs := []int64{123456789}
-	isAdmin := false
-	for _, id := range adminIDs {
-		if c.Sender().ID == id {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		return c.Send("⛔ Доступ запрещен.")
-	}
+	if b.role(c).Allows(models.RoleSuperadmin) {
+		msg += `
 
-	filter := models.CustomerFilter{
-		Page:  1,
-		Limit: 20,
-	}
-
-	customers, total, err := b.service.ListCustomers(b.ctx, filter)
-	if err != nil {
-		return c.Send("❌ Ошибка при получении списка заказчиков.")
-	}
-
-	if len(customers) == 0 {
-		return c.Send("📭 Заказчиков пока нет.")
-	}
-
-	var msg strings.Builder
-	msg.WriteString(fmt.Sprintf("👥 Заказчиков: %d\n\n", total))
-
-	for i, customer := range customers {
-		msg.WriteString(fmt.Sprintf("%d. %s\n", i+1, customer.Name))
-		msg.WriteString(fmt.Sprintf("   📞 %s\n", customer.Phone))
-		if customer.TelegramTag != nil {
-			msg.WriteString(fmt.Sprintf("   📱 @%s\n", *customer.TelegramTag))
-		}
-		msg.WriteString(fmt.Sprintf("   📅 %s\n", customer.CreatedAt.Format("02.01.2006")))
-		msg.WriteString("\n")
+👑 Только для суперадминов:
+/broadcast - Массовая рассылка
+/cancel - Отменить черновик или остановить рассылку
+/admins - Список администраторов
+/add_admin <telegram_id> <moderator|superadmin> - Выдать роль
+/remove_admin <telegram_id> - Отозвать доступ`
 	}
 
-	return c.Send(msg.String())
+	return c.Send(msg)
 }
 
-func (b *AdminBot) handleOrders(c telebot.Context) error {
-	// Admin check
-	adminIDs := []int64{123456789}
-	isAdmin := false
-	for _, id := range adminIDs {
-		if c.Sender().ID == id {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		return c.Send("⛔ Доступ запрещен.")
-	}
-
-	filter := models.OrderFilter{
-		Page:  1,
-		Limit: 20,
-	}
-
-	orders, total, err := b.service.ListOrders(b.ctx, filter)
+func (b *AdminBot) handleReferrals(c telebot.Context) error {
+	referrers, total, err := b.service.ListTopReferrers(b.ctx, 20)
 	if err != nil {
-		return c.Send("❌ Ошибка при получении списка заказов.")
+		return c.Send("❌ Ошибка при получении статистики приглашений.")
 	}
 
-	if len(orders) == 0 {
-		return c.Send("📦 Заказов пока нет.")
+	if len(referrers) == 0 {
+		return c.Send("🤝 Приглашений пока нет.")
 	}
 
 	var msg strings.Builder
-	msg.WriteString(fmt.Sprintf("📦 Заказов: %d\n\n", total))
-
-	for i, order := range orders {
-		msg.WriteString(fmt.Sprintf("%d. %s\n", i+1, order.Title))
-		msg.WriteString(fmt.Sprintf("   ⚖️ %.1f кг\n", order.WeightKg))
-		msg.WriteString(fmt.Sprintf("   💰 %.0f ₽\n", order.Price))
-		if order.FromLocation != nil {
-			msg.WriteString(fmt.Sprintf("   📍 Откуда: %s\n", *order.FromLocation))
-		}
-		if order.ToLocation != nil {
-			msg.WriteString(fmt.Sprintf("   🎯 Куда: %s\n", *order.ToLocation))
+	msg.WriteString(fmt.Sprintf("🤝 Всего приглашено: %d\n\n", total))
+
+	for i, referrer := range referrers {
+		msg.WriteString(fmt.Sprintf("%d. %s — %d\n", i+1, referrer.Customer.Name, referrer.Referrals))
+		if referrer.Customer.TelegramTag != nil {
+			msg.WriteString(fmt.Sprintf("   📱 @%s\n", *referrer.Customer.TelegramTag))
 		}
-		msg.WriteString(fmt.Sprintf("   📅 %s\n", order.CreatedAt.Format("02.01.2006")))
-		msg.WriteString("\n")
 	}
 
 	return c.Send(msg.String())
 }
 
-func (b *AdminBot) handleStats(c telebot.Context) error {
-	// Admin check
-	adminIDs := []int64{123456789}
-	isAdmin := false
-	for _, id := range adminIDs {
-		if c.Sender().ID == id {
-			isAdmin = true
-			break
-		}
+// handleText routes free text to the dialog waiting for input, if any
+func (b *AdminBot) handleText(c telebot.Context) error {
+	if handled, err := b.captureInput(c); handled {
+		return err
 	}
-
-	if !isAdmin {
-		return c.Send("⛔ Доступ запрещен.")
+	if handled, err := b.captureBroadcastText(c); handled {
+		return err
 	}
-
-	// Get basic stats
-	customers, _, err := b.service.ListCustomers(b.ctx, models.CustomerFilter{})
-	if err != nil {
-		return c.Send("❌ Ошибка при получении статистики.")
-	}
-
-	orders, _, err := b.service.ListOrders(b.ctx, models.OrderFilter{})
-	if err != nil {
-		return c.Send("❌ Ошибка при получении статистики.")
-	}
-
-	msg := fmt.Sprintf(`📊 Статистика системы
-
-👥 Заказчиков: %d
-📦 Заказов: %d
-📅 Дата: %s`, len(customers), len(orders), time.Now().Format("02.01.2006 15:04"))
-
-	return c.Send(msg)
+	return nil
 }
 
-func (b *AdminBot) handleBroadcast(c telebot.Context) error {
-	// Admin check
-	adminIDs := []int64{123456789}
-	isAdmin := false
-	for _, id := range adminIDs {
-		if c.Sender().ID == id {
-			isAdmin = true
-			break
-		}
-	}
-
-	if !isAdmin {
-		return c.Send("⛔ Доступ запрещен.")
-	}
-
-	msg := `📢 Массовая рассылка
-
-Отправьте сообщение, которое будет разослано всем заказчикам.
-
-Для отмены отправьте /cancel`
-
-	return c.Send(msg)
-} 
\ No newline at end of file
@@ -0,0 +1,368 @@
+package bots
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+const (
+	maxBroadcastPhotoSize  = 10 << 20
+	broadcastProgressEvery = 3 * time.Second
+)
+
+var (
+	btnBroadcastAudience = telebot.Btn{Unique: "bc_audience"}
+	btnBroadcastConfirm  = telebot.Btn{Unique: "bc_confirm"}
+	btnBroadcastDiscard  = telebot.Btn{Unique: "bc_discard"}
+)
+
+var audienceTitles = map[models.BroadcastAudience]string{
+	models.AudienceAll:       "Всем пользователям",
+	models.AudienceCustomers: "Заказчикам",
+	models.AudienceDrivers:   "Водителям",
+	models.AudienceRegion:    "По региону подписки",
+}
+
+type broadcastStep int
+
+const (
+	stepAwaitMessage broadcastStep = iota
+	stepAwaitAudience
+	stepAwaitRegion
+	stepAwaitConfirm
+)
+
+// broadcastDraft is a broadcast being composed by a superadmin
+type broadcastDraft struct {
+	step  broadcastStep
+	input models.CreateBroadcastInput
+}
+
+func (b *AdminBot) draft(telegramID int64) *broadcastDraft {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.drafts[telegramID]
+}
+
+func (b *AdminBot) setDraft(telegramID int64, draft *broadcastDraft) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if draft == nil {
+		delete(b.drafts, telegramID)
+		return
+	}
+	b.drafts[telegramID] = draft
+}
+
+func (b *AdminBot) handleBroadcast(c telebot.Context) error {
+	b.setDraft(c.Sender().ID, &broadcastDraft{
+		step:  stepAwaitMessage,
+		input: models.CreateBroadcastInput{CreatedBy: c.Sender().ID},
+	})
+
+	msg := `📢 Массовая рассылка
+
+Отправьте сообщение для рассылки: текст, фото с подписью или перешлите пост из канала.
+
+Для отмены отправьте /cancel`
+
+	return c.Send(msg)
+}
+
+// captureBroadcastText handles text input while a broadcast draft is open.
+// It reports false if the message was not meant for the draft.
+func (b *AdminBot) captureBroadcastText(c telebot.Context) (bool, error) {
+	draft := b.draft(c.Sender().ID)
+	if draft == nil {
+		return false, nil
+	}
+
+	switch draft.step {
+	case stepAwaitMessage:
+		msg := c.Message()
+		draft.input.Text = msg.Text
+		draft.input.Entities = marshalEntities(msg.Entities)
+		return true, b.previewBroadcast(c, draft)
+	case stepAwaitRegion:
+		region := strings.TrimSpace(c.Text())
+		if region == "" {
+			return true, c.Send("Введите название региона или города.")
+		}
+		draft.input.Region = &region
+		return true, b.confirmBroadcast(c, draft)
+	default:
+		return false, nil
+	}
+}
+
+func (b *AdminBot) handlePhoto(c telebot.Context) error {
+	draft := b.draft(c.Sender().ID)
+	if draft == nil || draft.step != stepAwaitMessage {
+		return nil
+	}
+
+	msg := c.Message()
+	if msg.Photo.FileSize > maxBroadcastPhotoSize {
+		return c.Send("❌ Фото слишком большое, максимум 10 МБ.")
+	}
+
+	// file_id is bot-specific, so keep the bytes for the driver bot to re-upload
+	reader, err := b.bot.File(&msg.Photo.File)
+	if err != nil {
+		log.Printf("Admin bot: failed to download broadcast photo: %v", err)
+		return c.Send("❌ Не удалось загрузить фото.")
+	}
+	defer reader.Close()
+
+	photo, err := io.ReadAll(io.LimitReader(reader, maxBroadcastPhotoSize))
+	if err != nil {
+		log.Printf("Admin bot: failed to read broadcast photo: %v", err)
+		return c.Send("❌ Не удалось загрузить фото.")
+	}
+
+	draft.input.Photo = photo
+	draft.input.Text = msg.Caption
+	draft.input.Entities = marshalEntities(msg.CaptionEntities)
+
+	return b.previewBroadcast(c, draft)
+}
+
+func (b *AdminBot) previewBroadcast(c telebot.Context, draft *broadcastDraft) error {
+	draft.step = stepAwaitAudience
+
+	if err := c.Send("👀 Предпросмотр рассылки:"); err != nil {
+		return err
+	}
+	if _, err := b.bot.Copy(c.Chat(), c.Message()); err != nil {
+		log.Printf("Admin bot: failed to copy broadcast preview: %v", err)
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for _, audience := range []models.BroadcastAudience{
+		models.AudienceAll, models.AudienceCustomers, models.AudienceDrivers, models.AudienceRegion,
+	} {
+		rows = append(rows, markup.Row(markup.Data(audienceTitles[audience], btnBroadcastAudience.Unique, string(audience))))
+	}
+	rows = append(rows, markup.Row(markup.Data("❌ Отмена", btnBroadcastDiscard.Unique)))
+	markup.Inline(rows...)
+
+	return c.Send("Кому отправить?", markup)
+}
+
+func (b *AdminBot) handleBroadcastAudience(c telebot.Context) error {
+	draft := b.draft(c.Sender().ID)
+	if draft == nil || draft.step != stepAwaitAudience {
+		return c.Respond(&telebot.CallbackResponse{Text: "Черновик рассылки не найден."})
+	}
+
+	audience := models.BroadcastAudience(c.Data())
+	if _, ok := audienceTitles[audience]; !ok {
+		return c.Respond(&telebot.CallbackResponse{Text: "Неизвестная аудитория."})
+	}
+	draft.input.Audience = audience
+	c.Respond()
+
+	if audience == models.AudienceRegion {
+		draft.step = stepAwaitRegion
+		return c.Edit("Введите регион или город — получат пользователи с подпиской на него.")
+	}
+
+	return b.confirmBroadcast(c, draft)
+}
+
+func (b *AdminBot) confirmBroadcast(c telebot.Context, draft *broadcastDraft) error {
+	count, err := b.service.CountBroadcastAudience(b.ctx, draft.input.Audience, draft.input.Region)
+	if err != nil {
+		return c.Send("❌ Ошибка при подсчете получателей.")
+	}
+	draft.step = stepAwaitConfirm
+
+	audience := audienceTitles[draft.input.Audience]
+	if draft.input.Region != nil {
+		audience += ": " + *draft.input.Region
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data("✅ Отправить", btnBroadcastConfirm.Unique),
+		markup.Data("❌ Отмена", btnBroadcastDiscard.Unique),
+	))
+
+	return c.Send(fmt.Sprintf("📢 %s\n👥 Получателей: %d\n\nОтправить рассылку?", audience, count), markup)
+}
+
+func (b *AdminBot) handleBroadcastConfirm(c telebot.Context) error {
+	draft := b.draft(c.Sender().ID)
+	if draft == nil || draft.step != stepAwaitConfirm {
+		return c.Respond(&telebot.CallbackResponse{Text: "Черновик рассылки не найден."})
+	}
+	b.setDraft(c.Sender().ID, nil)
+	c.Respond()
+
+	broadcast, err := b.service.CreateBroadcast(b.ctx, draft.input)
+	if err != nil {
+		log.Printf("Admin bot: failed to create broadcast: %v", err)
+		return c.Edit("❌ Не удалось создать рассылку.")
+	}
+
+	progress, err := b.bot.Edit(c.Message(), formatBroadcastProgress(broadcast))
+	if err != nil {
+		return err
+	}
+	if err := b.service.SetBroadcastProgressMessage(b.ctx, broadcast.UUID, progress.Chat.ID, progress.ID); err != nil {
+		log.Printf("Admin bot: %v", err)
+	}
+
+	log.Printf("Admin bot: %d started broadcast %s to %d recipients", c.Sender().ID, broadcast.UUID, broadcast.Total)
+	b.broadcaster.Enqueue()
+	go b.watchBroadcast(broadcast.UUID, progress)
+
+	return nil
+}
+
+func (b *AdminBot) handleBroadcastDiscard(c telebot.Context) error {
+	b.setDraft(c.Sender().ID, nil)
+	c.Respond()
+	return c.Edit("Рассылка отменена.")
+}
+
+// handleCancel drops the awaited input or broadcast draft or, if there is
+// none, lets a superadmin stop running broadcasts
+func (b *AdminBot) handleCancel(c telebot.Context) error {
+	if b.input(c.Sender().ID) != nil {
+		b.setInput(c.Sender().ID, nil)
+		return c.Send("Действие отменено.")
+	}
+	if b.draft(c.Sender().ID) != nil {
+		b.setDraft(c.Sender().ID, nil)
+		return c.Send("Рассылка отменена.")
+	}
+	if !b.role(c).Allows(models.RoleSuperadmin) {
+		return c.Send("Нечего отменять.")
+	}
+
+	broadcasts, err := b.service.ListActiveBroadcasts(b.ctx)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении рассылок.")
+	}
+	if len(broadcasts) == 0 {
+		return c.Send("Нет активных рассылок.")
+	}
+
+	stopped := 0
+	for _, broadcast := range broadcasts {
+		cancelled, err := b.broadcaster.Cancel(b.ctx, broadcast.UUID)
+		if err != nil {
+			log.Printf("Admin bot: failed to cancel broadcast %s: %v", broadcast.UUID, err)
+			continue
+		}
+		if cancelled {
+			stopped++
+			log.Printf("Admin bot: %d cancelled broadcast %s", c.Sender().ID, broadcast.UUID)
+		}
+	}
+
+	return c.Send(fmt.Sprintf("⏹ Остановлено рассылок: %d", stopped))
+}
+
+// resumeBroadcastWatchers restores progress updates for broadcasts that were
+// running before a restart
+func (b *AdminBot) resumeBroadcastWatchers() {
+	broadcasts, err := b.service.ListActiveBroadcasts(b.ctx)
+	if err != nil {
+		log.Printf("Admin bot: failed to list broadcasts: %v", err)
+		return
+	}
+	for _, broadcast := range broadcasts {
+		if broadcast.ProgressChatID == nil || broadcast.ProgressMessageID == nil {
+			continue
+		}
+		msg := telebot.StoredMessage{
+			MessageID: strconv.Itoa(*broadcast.ProgressMessageID),
+			ChatID:    *broadcast.ProgressChatID,
+		}
+		go b.watchBroadcast(broadcast.UUID, msg)
+	}
+}
+
+// watchBroadcast keeps the progress message up to date until the broadcast finishes
+func (b *AdminBot) watchBroadcast(id uuid.UUID, msg telebot.Editable) {
+	ticker := time.NewTicker(broadcastProgressEvery)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		broadcast, err := b.service.GetBroadcast(b.ctx, id)
+		if err != nil || broadcast == nil {
+			log.Printf("Admin bot: failed to get broadcast %s: %v", id, err)
+			return
+		}
+
+		text := formatBroadcastProgress(*broadcast)
+		if text != last {
+			if _, err := b.bot.Edit(msg, text); err != nil {
+				log.Printf("Admin bot: failed to update broadcast progress: %v", err)
+			}
+			last = text
+		}
+
+		if broadcast.Status == models.BroadcastDone || broadcast.Status == models.BroadcastCancelled {
+			return
+		}
+	}
+}
+
+func formatBroadcastProgress(broadcast models.Broadcast) string {
+	status := map[models.BroadcastStatus]string{
+		models.BroadcastPending:   "⏳ В очереди",
+		models.BroadcastRunning:   "📤 Отправляется",
+		models.BroadcastDone:      "✅ Завершена",
+		models.BroadcastCancelled: "⏹ Остановлена",
+	}[broadcast.Status]
+
+	processed := broadcast.Sent + broadcast.Failed + broadcast.Blocked
+	percent := 100
+	if broadcast.Total > 0 {
+		percent = processed * 100 / broadcast.Total
+	}
+
+	msg := fmt.Sprintf(`📢 Рассылка: %s
+
+Обработано: %d из %d (%d%%)
+✅ Доставлено: %d
+🚫 Заблокировали бота: %d
+❌ Ошибки: %d`, status, processed, broadcast.Total, percent, broadcast.Sent, broadcast.Blocked, broadcast.Failed)
+
+	if broadcast.Status == models.BroadcastPending || broadcast.Status == models.BroadcastRunning {
+		msg += "\n\nОстановить: /cancel"
+	}
+	return msg
+}
+
+// marshalEntities encodes message formatting so it can be replayed by the driver bot
+func marshalEntities(entities telebot.Entities) []byte {
+	if len(entities) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(entities)
+	if err != nil {
+		return nil
+	}
+	return data
+}
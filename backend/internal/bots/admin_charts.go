@@ -0,0 +1,158 @@
+package bots
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/charts"
+	"gruzy-ryadom/internal/models"
+)
+
+const priceBuckets = 10
+
+var chartTitles = map[string]string{
+	"orders":    "Новые заказы",
+	"customers": "Новые заказчики",
+	"prices":    "Распределение цен",
+	"routes":    "Популярные маршруты",
+}
+
+const chartUsage = `Использование: /chart <тип> [day|week|month|all] [RUB|KZT|BYN]
+
+Типы графиков:
+orders - новые заказы
+customers - новые заказчики
+prices - распределение цен в выбранной валюте (по умолчанию RUB)
+routes - популярные маршруты`
+
+func (b *AdminBot) handleChart(c telebot.Context) error {
+	args := c.Args()
+	if len(args) == 0 || len(args) > 3 {
+		return c.Send(chartUsage)
+	}
+
+	kind := strings.ToLower(args[0])
+	if _, ok := chartTitles[kind]; !ok {
+		return c.Send(chartUsage)
+	}
+
+	periodArg := ""
+	currency := models.DefaultCurrency
+	for _, arg := range args[1:] {
+		if parsed, err := models.ParseCurrency(arg); err == nil {
+			currency = parsed
+		} else {
+			periodArg = arg
+		}
+	}
+	period, ok := parsePeriod(periodArg)
+	if !ok {
+		return c.Send(chartUsage)
+	}
+
+	png, err := b.renderChart(kind, period, currency)
+	if err != nil {
+		log.Printf("Admin bot: failed to render %s chart: %v", kind, err)
+		return c.Send("❌ Не удалось построить график.")
+	}
+	if png == nil {
+		return c.Send("📭 Нет данных за выбранный период.")
+	}
+
+	photo := &telebot.Photo{
+		File:    telebot.FromReader(bytes.NewReader(png)),
+		Caption: fmt.Sprintf("📈 %s — %s", chartTitles[kind], periodTitles[period]),
+	}
+	return c.Send(photo)
+}
+
+// renderChart returns nil without an error if there is nothing to draw
+func (b *AdminBot) renderChart(kind string, period models.StatsPeriod, currency models.Currency) ([]byte, error) {
+	title := fmt.Sprintf("%s — %s", chartTitles[kind], periodTitles[period])
+
+	if kind == "prices" {
+		distribution, err := b.service.GetPriceDistribution(b.ctx, period, currency, priceBuckets)
+		if err != nil || len(distribution) == 0 {
+			return nil, err
+		}
+		points := make([]charts.Point, len(distribution))
+		for i, bucket := range distribution {
+			label := fmt.Sprintf("%.0f–%.0f", bucket.From.Float(), bucket.To.Float())
+			if i == len(distribution)-1 {
+				label = fmt.Sprintf("≥%.0f", bucket.From.Float())
+			}
+			points[i] = charts.Point{Label: label, Value: float64(bucket.Orders)}
+		}
+		return charts.Bars(title+", "+currency.Symbol(), points)
+	}
+
+	stats, err := b.service.GetStats(b.ctx, period)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "orders":
+		return seriesChart(title, stats, stats.OrdersSeries)
+	case "customers":
+		return seriesChart(title, stats, stats.CustomerSeries)
+	default:
+		if len(stats.TopRoutes) == 0 {
+			return nil, nil
+		}
+		points := make([]charts.Point, len(stats.TopRoutes))
+		for i, route := range stats.TopRoutes {
+			points[i] = charts.Point{Label: route.From + " → " + route.To, Value: float64(route.Orders)}
+		}
+		return charts.HorizontalBars(title, points)
+	}
+}
+
+func seriesChart(title string, stats models.Stats, series []models.DailyCount) ([]byte, error) {
+	if len(series) == 0 {
+		return nil, nil
+	}
+	points := fillSeries(series, stats.Since, stats.Bucket)
+	if stats.Bucket == "week" {
+		title += " (по неделям)"
+	}
+	return charts.Bars(title, points)
+}
+
+// fillSeries adds zero points for days or weeks without rows so gaps are visible
+func fillSeries(series []models.DailyCount, since *time.Time, bucket string) []charts.Point {
+	step := func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	truncate := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if bucket == "week" {
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+		day := truncate
+		truncate = func(t time.Time) time.Time {
+			// date_trunc('week') starts weeks on Monday
+			t = day(t)
+			return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		}
+	}
+
+	counts := make(map[time.Time]int, len(series))
+	for _, point := range series {
+		counts[truncate(point.Day)] = point.Count
+	}
+
+	start := truncate(series[0].Day)
+	if since != nil {
+		start = truncate(*since)
+	}
+	end := truncate(time.Now().UTC())
+
+	var points []charts.Point
+	for t := start; !t.After(end); t = step(t) {
+		points = append(points, charts.Point{Label: t.Format("02.01"), Value: float64(counts[t])})
+	}
+	return points
+}
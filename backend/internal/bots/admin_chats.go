@@ -0,0 +1,80 @@
+package bots
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+// Only the tail of long conversations is shown to admins
+const chatMessagesLimit = 20
+
+// handleChats shows the driver conversations about an order for dispute resolution
+func (b *AdminBot) handleChats(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /chats <uuid заказа>")
+	}
+	orderID, err := uuid.Parse(args[0])
+	if err != nil {
+		return c.Send("❌ Некорректный UUID заказа.")
+	}
+	return b.sendChats(c, orderID)
+}
+
+func (b *AdminBot) sendChats(c telebot.Context, orderID uuid.UUID) error {
+	threads, err := b.service.ListChatThreads(b.ctx, orderID)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении переписки.")
+	}
+	if len(threads) == 0 {
+		return c.Send("💬 По этому заказу переписки нет.")
+	}
+
+	for _, thread := range threads {
+		messages, err := b.service.ListChatMessages(b.ctx, thread.UUID, chatMessagesLimit)
+		if err != nil {
+			log.Printf("Admin bot: failed to get messages of chat %s: %v", thread.UUID, err)
+			return c.Send("❌ Ошибка при получении переписки.")
+		}
+
+		text := formatChatThread(thread, messages)
+		if runes := []rune(text); len(runes) > maxMessageLength {
+			text = "…" + string(runes[len(runes)-maxMessageLength:])
+		}
+		if err := c.Send(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatChatThread(thread models.ChatThread, messages []models.ChatMessage) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("💬 «%s»\n🚚 %s (%s)", thread.OrderTitle, thread.DriverName, chatAlias(thread)))
+	if thread.DriverRevealed {
+		msg.WriteString(" · контакты раскрыты")
+	}
+	msg.WriteString(fmt.Sprintf("\n👤 %s", thread.CustomerName))
+	if thread.CustomerRevealed {
+		msg.WriteString(" · контакты раскрыты")
+	}
+	msg.WriteString(fmt.Sprintf("\n🆔 %s\n", thread.UUID))
+
+	if len(messages) == 0 {
+		msg.WriteString("\nСообщений нет.")
+	}
+	for _, message := range messages {
+		sender := thread.CustomerName
+		if message.SenderUUID == thread.DriverUUID {
+			sender = thread.DriverName
+		}
+		msg.WriteString(fmt.Sprintf("\n[%s] %s: %s", message.CreatedAt.Format("02.01 15:04"), sender, message.Text))
+	}
+
+	return msg.String()
+}
@@ -0,0 +1,74 @@
+package bots
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/export"
+)
+
+const exportUsage = `Использование:
+/export orders [csv|xlsx] [Москва - Казань 500кг #тент]
+/export customers [csv|xlsx] [имя|телефон|@тег]`
+
+// handleExport sends orders or customers matching a search as a spreadsheet.
+// The file is streamed to Telegram while it is being written.
+func (b *AdminBot) handleExport(c telebot.Context) error {
+	args := strings.Fields(c.Message().Payload)
+	if len(args) == 0 || (args[0] != "orders" && args[0] != "customers") {
+		return c.Send(exportUsage)
+	}
+	kind, args := args[0], args[1:]
+
+	format := export.CSV
+	if len(args) > 0 {
+		if parsed, err := export.ParseFormat(args[0]); err == nil {
+			format, args = parsed, args[1:]
+		}
+	}
+	query := strings.Join(args, " ")
+
+	reader, writer := io.Pipe()
+	go func() {
+		out, err := export.NewWriter(writer, format, kind)
+		if err == nil {
+			if kind == "orders" {
+				err = b.service.ExportOrders(b.ctx, parseSearchQuery(query), out, true)
+			} else {
+				err = b.service.ExportCustomers(b.ctx, parseCustomerQuery(query), out)
+			}
+		}
+		writer.CloseWithError(err)
+	}()
+
+	log.Printf("Admin bot: %d exported %s %q as %s", c.Sender().ID, kind, query, format)
+	err := c.Send(&telebot.Document{
+		File:     telebot.FromReader(reader),
+		FileName: fmt.Sprintf("%s-%s.%s", kind, time.Now().Format("2006-01-02"), format),
+		MIME:     format.ContentType(),
+		Caption:  "📤 Выгрузка: " + exportTitles[kind] + queryCaption(query),
+	})
+	// Unblock the writer if the upload stopped early
+	reader.Close()
+	if err != nil {
+		log.Printf("Admin bot: failed to export %s: %v", kind, err)
+		return c.Send("❌ Не удалось сформировать выгрузку.")
+	}
+	return nil
+}
+
+var exportTitles = map[string]string{
+	"orders":    "заказы",
+	"customers": "заказчики",
+}
+
+func queryCaption(query string) string {
+	if query == "" {
+		return ""
+	}
+	return fmt.Sprintf(" по запросу «%s»", query)
+}
@@ -0,0 +1,622 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/waybill"
+)
+
+const adminPageSize = 10
+
+var (
+	btnListPage       = telebot.Btn{Unique: "list_page"}
+	btnOrderAction    = telebot.Btn{Unique: "order_act"}
+	btnCustomerAction = telebot.Btn{Unique: "cust_act"}
+)
+
+var phoneRe = regexp.MustCompile(`^\+?[\d\s()-]{5,}$`)
+
+var orderStatusTitles = map[models.OrderStatus]string{
+	models.OrderOpen:       "открыт",
+	models.OrderInProgress: "в работе",
+	models.OrderCompleted:  "выполнен",
+	models.OrderCancelled:  "отменен",
+}
+
+var moderationTitles = map[models.ModerationStatus]string{
+	models.ModerationPending:  "⏳ на проверке",
+	models.ModerationApproved: "✅ одобрен",
+	models.ModerationRejected: "❌ отклонен",
+}
+
+// listState remembers the filters of the last lists an admin opened, so that
+// the page buttons only need to carry the page number
+type listState struct {
+	orders      models.OrderFilter
+	ordersTitle string
+	customers   models.CustomerFilter
+	reviews     models.ReviewFilter
+}
+
+func (b *AdminBot) listState(telegramID int64) *listState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.lists[telegramID]
+	if !ok {
+		state = &listState{}
+		b.lists[telegramID] = state
+	}
+	return state
+}
+
+// parseCustomerQuery picks the customer field a search term refers to
+func parseCustomerQuery(text string) models.CustomerFilter {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		return models.CustomerFilter{}
+	case strings.HasPrefix(text, "@"):
+		return models.CustomerFilter{TelegramTag: strings.TrimPrefix(text, "@")}
+	case phoneRe.MatchString(text):
+		return models.CustomerFilter{Phone: text}
+	default:
+		return models.CustomerFilter{Name: text}
+	}
+}
+
+func (b *AdminBot) handleCustomers(c telebot.Context) error {
+	filter := parseCustomerQuery(c.Message().Payload)
+	b.setCustomerList(c.Sender().ID, filter)
+
+	text, markup, err := b.renderCustomerList(filter, 1)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении списка заказчиков.")
+	}
+	return c.Send(text, markup)
+}
+
+func (b *AdminBot) handleOrders(c telebot.Context) error {
+	filter := parseSearchQuery(c.Message().Payload)
+	b.setOrderList(c.Sender().ID, filter, "")
+
+	return b.sendOrderList(c, filter, "")
+}
+
+func (b *AdminBot) setCustomerList(telegramID int64, filter models.CustomerFilter) {
+	state := b.listState(telegramID)
+	b.mu.Lock()
+	state.customers = filter
+	b.mu.Unlock()
+}
+
+func (b *AdminBot) setOrderList(telegramID int64, filter models.OrderFilter, title string) {
+	state := b.listState(telegramID)
+	b.mu.Lock()
+	state.orders = filter
+	state.ordersTitle = title
+	b.mu.Unlock()
+}
+
+func (b *AdminBot) sendOrderList(c telebot.Context, filter models.OrderFilter, title string) error {
+	filter.Page = 1
+	text, markup, err := b.renderOrderList(filter, title)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении списка заказов.")
+	}
+	return c.Send(text, markup)
+}
+
+// handleListPage switches a list message to another page, data is "<list>|<page>"
+func (b *AdminBot) handleListPage(c telebot.Context) error {
+	list, pageText, _ := strings.Cut(c.Data(), "|")
+	page, err := strconv.Atoi(pageText)
+	if err != nil || page < 1 {
+		return c.Respond()
+	}
+	c.Respond()
+
+	state := b.listState(c.Sender().ID)
+	b.mu.Lock()
+	orders, title, customers := state.orders, state.ordersTitle, state.customers
+	b.mu.Unlock()
+
+	var text string
+	var markup *telebot.ReplyMarkup
+	switch list {
+	case "orders":
+		orders.Page = page
+		text, markup, err = b.renderOrderList(orders, title)
+	case "customers":
+		text, markup, err = b.renderCustomerList(customers, page)
+	default:
+		return nil
+	}
+	if err != nil {
+		log.Printf("Admin bot: failed to render %s page %d: %v", list, page, err)
+		return nil
+	}
+	// The page counter button refreshes the current page, which may be unchanged
+	if err := c.Edit(text, markup); err != nil && !errors.Is(err, telebot.ErrSameMessageContent) {
+		return err
+	}
+	return nil
+}
+
+func (b *AdminBot) renderOrderList(filter models.OrderFilter, title string) (string, *telebot.ReplyMarkup, error) {
+	filter.Limit = adminPageSize
+	filter.IncludeUnmoderated = true
+	filter.IncludeHidden = true
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	orders, total, err := b.service.ListOrders(b.ctx, filter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var msg strings.Builder
+	if title != "" {
+		msg.WriteString(title + "\n")
+	}
+	if total == 0 {
+		msg.WriteString("📦 Заказов не найдено.")
+		return msg.String(), nil, nil
+	}
+	msg.WriteString(fmt.Sprintf("📦 Заказов: %d\n\n", total))
+
+	markup := &telebot.ReplyMarkup{}
+	var buttons []telebot.Btn
+	offset := (filter.Page - 1) * adminPageSize
+	for i, order := range orders {
+		n := offset + i + 1
+		msg.WriteString(fmt.Sprintf("%d. %s\n", n, order.Title))
+		msg.WriteString(fmt.Sprintf("   🛣 %s\n", formatRoute(order)))
+		msg.WriteString(fmt.Sprintf("   ⚖️ %.1f кг · 💰 %s\n", order.WeightKg, order.Price))
+		msg.WriteString(fmt.Sprintf("   📅 %s · %s", order.CreatedAt.Format("02.01.2006"), orderStatusTitles[order.Status]))
+		if order.ModerationStatus != models.ModerationApproved {
+			msg.WriteString(" · " + moderationTitles[order.ModerationStatus])
+		}
+		if order.Hidden {
+			msg.WriteString(" · 🙈 скрыт")
+		}
+		msg.WriteString("\n\n")
+		buttons = append(buttons, markup.Data(strconv.Itoa(n), btnOrderAction.Unique, "open|"+order.UUID.String()))
+	}
+
+	markup.Inline(listRows(markup, buttons, "orders", filter.Page, total)...)
+	return strings.TrimRight(msg.String(), "\n"), markup, nil
+}
+
+func (b *AdminBot) renderCustomerList(filter models.CustomerFilter, page int) (string, *telebot.ReplyMarkup, error) {
+	filter.Page = page
+	filter.Limit = adminPageSize
+
+	customers, total, err := b.service.ListCustomers(b.ctx, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	if total == 0 {
+		return "📭 Заказчиков не найдено.", nil, nil
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("👥 Заказчиков: %d\n\n", total))
+
+	markup := &telebot.ReplyMarkup{}
+	var buttons []telebot.Btn
+	offset := (page - 1) * adminPageSize
+	for i, customer := range customers {
+		n := offset + i + 1
+		msg.WriteString(fmt.Sprintf("%d. %s\n", n, customer.Name))
+		if customer.Phone != "" {
+			msg.WriteString(fmt.Sprintf("   📞 %s\n", customer.Phone))
+		}
+		if customer.TelegramTag != nil {
+			msg.WriteString(fmt.Sprintf("   📱 @%s\n", *customer.TelegramTag))
+		}
+		msg.WriteString(fmt.Sprintf("   📅 %s\n\n", customer.CreatedAt.Format("02.01.2006")))
+		buttons = append(buttons, markup.Data(strconv.Itoa(n), btnCustomerAction.Unique, "open|"+customer.UUID.String()))
+	}
+
+	markup.Inline(listRows(markup, buttons, "customers", page, total)...)
+	return strings.TrimRight(msg.String(), "\n"), markup, nil
+}
+
+// listRows lays out item buttons five per row followed by page navigation
+func listRows(markup *telebot.ReplyMarkup, buttons []telebot.Btn, list string, page, total int) []telebot.Row {
+	var rows []telebot.Row
+	for len(buttons) > 0 {
+		n := min(5, len(buttons))
+		rows = append(rows, markup.Row(buttons[:n]...))
+		buttons = buttons[n:]
+	}
+
+	pages := (total + adminPageSize - 1) / adminPageSize
+	var nav []telebot.Btn
+	if page > 1 {
+		nav = append(nav, markup.Data("◀️", btnListPage.Unique, fmt.Sprintf("%s|%d", list, page-1)))
+	}
+	if pages > 1 {
+		nav = append(nav, markup.Data(fmt.Sprintf("%d / %d", page, pages), btnListPage.Unique, fmt.Sprintf("%s|%d", list, page)))
+	}
+	if page < pages {
+		nav = append(nav, markup.Data("▶️", btnListPage.Unique, fmt.Sprintf("%s|%d", list, page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, markup.Row(nav...))
+	}
+	return rows
+}
+
+func (b *AdminBot) handleOrder(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /order <uuid>")
+	}
+
+	order, err := b.service.GetOrder(b.ctx, args[0])
+	if err != nil || order == nil {
+		return c.Send("❌ Заказ не найден.")
+	}
+	return c.Send(formatAdminOrderCard(*order), orderActionsMarkup(*order))
+}
+
+// handleCustomer looks a customer up by UUID, phone or Telegram tag. Several
+// matches are shown as a list.
+func (b *AdminBot) handleCustomer(c telebot.Context) error {
+	query := strings.TrimSpace(c.Message().Payload)
+	if query == "" {
+		return c.Send("Использование: /customer <телефон|@тег|uuid>")
+	}
+
+	if _, err := uuid.Parse(query); err == nil {
+		return b.sendCustomerCard(c, query, false)
+	}
+
+	filter := parseCustomerQuery(query)
+	filter.Limit = 2
+	customers, total, err := b.service.ListCustomers(b.ctx, filter)
+	if err != nil {
+		return c.Send("❌ Ошибка при поиске заказчика.")
+	}
+	switch total {
+	case 0:
+		return c.Send("❌ Заказчик не найден.")
+	case 1:
+		return b.sendCustomerCard(c, customers[0].UUID.String(), false)
+	}
+
+	b.setCustomerList(c.Sender().ID, filter)
+	text, markup, err := b.renderCustomerList(filter, 1)
+	if err != nil {
+		return c.Send("❌ Ошибка при поиске заказчика.")
+	}
+	return c.Send(text, markup)
+}
+
+// handleOrderAction dispatches order card buttons, data is "<action>|<uuid>"
+func (b *AdminBot) handleOrderAction(c telebot.Context) error {
+	action, id, _ := strings.Cut(c.Data(), "|")
+
+	order, err := b.service.GetOrder(b.ctx, id)
+	if err != nil || order == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ не найден."})
+	}
+
+	switch action {
+	case "open":
+		c.Respond()
+		return c.Send(formatAdminOrderCard(*order), orderActionsMarkup(*order))
+	case "back":
+		c.Respond()
+		return c.Edit(formatAdminOrderCard(*order), orderActionsMarkup(*order))
+	case "edit":
+		b.setInput(c.Sender().ID, &pendingInput{kind: inputEditOrderCard, id: id, message: c.Message()})
+		c.Respond()
+		return c.Send(orderEditPrompt)
+	case "hide", "show":
+		hidden := action == "hide"
+		if _, err := b.service.UpdateOrder(b.actorContext(c), id, models.UpdateOrderInput{Hidden: &hidden}); err != nil {
+			log.Printf("Admin bot: failed to change visibility of order %s: %v", id, err)
+			return c.Respond(&telebot.CallbackResponse{Text: "❌ Не удалось изменить заказ."})
+		}
+		log.Printf("Admin bot: %d set hidden=%t for order %s", c.Sender().ID, hidden, id)
+		order.Hidden = hidden
+		c.Respond()
+		return c.Edit(formatAdminOrderCard(*order), orderActionsMarkup(*order))
+	case "customer":
+		c.Respond()
+		return b.sendCustomerCard(c, order.CustomerUUID.String(), false)
+	case "history":
+		c.Respond()
+		return b.sendAudit(c, models.AuditFilter{EntityUUID: &order.UUID, Limit: auditPageSize})
+	case "chats":
+		c.Respond()
+		return b.sendChats(c, order.UUID)
+	case "ttn", "cmr":
+		form := waybill.Form(action)
+		pdf, err := b.service.Waybill(b.ctx, id, nil, form)
+		if err != nil {
+			log.Printf("Admin bot: failed to render waybill of %s: %v", id, err)
+			return c.Respond(&telebot.CallbackResponse{Text: "❌ Не удалось сформировать накладную."})
+		}
+		c.Respond()
+		return c.Send(waybillDocument(*order, form, pdf))
+	case "delete":
+		c.Respond()
+		markup := &telebot.ReplyMarkup{}
+		markup.Inline(markup.Row(
+			markup.Data("🗑 Да, удалить", btnOrderAction.Unique, "delete_ok|"+id),
+			markup.Data("↩️ Назад", btnOrderAction.Unique, "back|"+id),
+		))
+		return c.Edit(formatAdminOrderCard(*order)+"\n\n⚠️ Удалить заказ безвозвратно?", markup)
+	case "delete_ok":
+		if _, err := b.service.DeleteOrder(b.ctx, id); err != nil {
+			log.Printf("Admin bot: failed to delete order %s: %v", id, err)
+			return c.Respond(&telebot.CallbackResponse{Text: "❌ Не удалось удалить заказ."})
+		}
+		log.Printf("Admin bot: %d deleted order %s", c.Sender().ID, id)
+		c.Respond()
+		return c.Edit(fmt.Sprintf("🗑 Заказ «%s» удален.", order.Title))
+	default:
+		return c.Respond()
+	}
+}
+
+// handleCustomerAction dispatches customer card buttons, data is "<action>|<uuid>"
+func (b *AdminBot) handleCustomerAction(c telebot.Context) error {
+	action, id, _ := strings.Cut(c.Data(), "|")
+
+	customer, err := b.service.GetCustomer(b.ctx, id)
+	if err != nil || customer == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказчик не найден."})
+	}
+
+	switch action {
+	case "open":
+		c.Respond()
+		return b.sendCustomerCard(c, id, false)
+	case "back":
+		c.Respond()
+		return b.sendCustomerCard(c, id, true)
+	case "edit":
+		b.setInput(c.Sender().ID, &pendingInput{kind: inputEditCustomer, id: id, message: c.Message()})
+		c.Respond()
+		return c.Send(customerEditPrompt)
+	case "ban":
+		b.setInput(c.Sender().ID, &pendingInput{kind: inputBanCustomer, id: id, message: c.Message()})
+		c.Respond()
+		return c.Send(fmt.Sprintf("🚫 Блокировка %s\n\nОтправьте срок и причину, например: 7d спам\nСрок: 12h, 7d, 4w или \"навсегда\"\n\nДля отмены отправьте /cancel", customer.Name))
+	case "history":
+		c.Respond()
+		return b.sendAudit(c, models.AuditFilter{EntityUUID: &customer.UUID, Limit: auditPageSize})
+	case "orders":
+		c.Respond()
+		filter := models.OrderFilter{CustomerUUID: &customer.UUID}
+		title := fmt.Sprintf("👤 %s", customer.Name)
+		b.setOrderList(c.Sender().ID, filter, title)
+		return b.sendOrderList(c, filter, title)
+	case "reviews":
+		c.Respond()
+		return b.sendReviews(c, models.ReviewFilter{TargetUUID: &customer.UUID})
+	case "delete":
+		c.Respond()
+		markup := &telebot.ReplyMarkup{}
+		markup.Inline(markup.Row(
+			markup.Data("🗑 Да, удалить", btnCustomerAction.Unique, "delete_ok|"+id),
+			markup.Data("↩️ Назад", btnCustomerAction.Unique, "back|"+id),
+		))
+		return c.Edit(fmt.Sprintf("⚠️ Удалить заказчика %s вместе со всеми его заказами?", customer.Name), markup)
+	case "delete_ok":
+		if _, err := b.service.DeleteCustomer(b.ctx, id); err != nil {
+			log.Printf("Admin bot: failed to delete customer %s: %v", id, err)
+			return c.Respond(&telebot.CallbackResponse{Text: "❌ Не удалось удалить заказчика."})
+		}
+		log.Printf("Admin bot: %d deleted customer %s", c.Sender().ID, id)
+		c.Respond()
+		return c.Edit(fmt.Sprintf("🗑 Заказчик %s удален.", customer.Name))
+	default:
+		return c.Respond()
+	}
+}
+
+// sendCustomerCard shows a customer with the number of their orders
+func (b *AdminBot) sendCustomerCard(c telebot.Context, id string, edit bool) error {
+	customer, err := b.service.GetCustomer(b.ctx, id)
+	if err != nil || customer == nil {
+		return c.Send("❌ Заказчик не найден.")
+	}
+
+	_, orders, err := b.service.ListOrders(b.ctx, models.OrderFilter{
+		CustomerUUID: &customer.UUID, Limit: 1, IncludeUnmoderated: true, IncludeHidden: true,
+	})
+	if err != nil {
+		log.Printf("Admin bot: failed to count orders of %s: %v", customer.UUID, err)
+	}
+
+	ban, err := b.service.GetActiveBanByCustomer(b.ctx, customer.UUID)
+	if err != nil {
+		log.Printf("Admin bot: failed to check ban of %s: %v", customer.UUID, err)
+	}
+
+	text := formatAdminCustomerCard(*customer, orders, ban)
+	markup := customerActionsMarkup(*customer)
+	if edit {
+		return c.Edit(text, markup)
+	}
+	return c.Send(text, markup)
+}
+
+func (b *AdminBot) editCustomer(c telebot.Context, input *pendingInput) error {
+	update, err := parseCustomerEdits(c.Text())
+	if err != nil {
+		return c.Send(fmt.Sprintf("❌ %v\n\nИсправьте и отправьте снова или /cancel.", err))
+	}
+	b.setInput(c.Sender().ID, nil)
+
+	if _, err := b.service.UpdateCustomer(b.actorContext(c), input.id, update); err != nil {
+		log.Printf("Admin bot: failed to edit customer %s: %v", input.id, err)
+		return c.Send("❌ Не удалось изменить заказчика.")
+	}
+	log.Printf("Admin bot: %d edited customer %s", c.Sender().ID, input.id)
+
+	customer, err := b.service.GetCustomer(b.ctx, input.id)
+	if err == nil && customer != nil {
+		_, orders, _ := b.service.ListOrders(b.ctx, models.OrderFilter{
+			CustomerUUID: &customer.UUID, Limit: 1, IncludeUnmoderated: true, IncludeHidden: true,
+		})
+		ban, _ := b.service.GetActiveBanByCustomer(b.ctx, customer.UUID)
+		if _, err := b.bot.Edit(input.message, formatAdminCustomerCard(*customer, orders, ban), customerActionsMarkup(*customer)); err != nil {
+			log.Printf("Admin bot: failed to update customer card: %v", err)
+		}
+	}
+	return c.Send("✅ Заказчик изменен.")
+}
+
+const customerEditPrompt = `✏️ Отправьте новые значения, по одному полю в строке:
+
+имя: Иван Петров
+телефон: +7 900 123-45-67
+тег: ivan_petrov
+
+Для отмены отправьте /cancel`
+
+// parseCustomerEdits reads "поле: значение" lines into a customer update
+func parseCustomerEdits(text string) (models.UpdateCustomerInput, error) {
+	var update models.UpdateCustomerInput
+	fields := 0
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return update, fmt.Errorf("строка без двоеточия: %q", line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "имя":
+			if value == "" {
+				return update, fmt.Errorf("имя не может быть пустым")
+			}
+			update.Name = &value
+		case "телефон":
+			update.Phone = &value
+		case "тег":
+			value = strings.TrimPrefix(value, "@")
+			update.TelegramTag = &value
+		default:
+			return update, fmt.Errorf("неизвестное поле: %q", key)
+		}
+		fields++
+	}
+
+	if fields == 0 {
+		return update, fmt.Errorf("нет полей для изменения")
+	}
+	return update, nil
+}
+
+func orderActionsMarkup(order models.Order) *telebot.ReplyMarkup {
+	id := order.UUID.String()
+	visibility, visibilityAction := "🙈 Скрыть", "hide"
+	if order.Hidden {
+		visibility, visibilityAction = "👁 Показать", "show"
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	rows := []telebot.Row{
+		markup.Row(
+			markup.Data("✏️ Изменить", btnOrderAction.Unique, "edit|"+id),
+			markup.Data(visibility, btnOrderAction.Unique, visibilityAction+"|"+id),
+		),
+		markup.Row(
+			markup.Data("👤 Заказчик", btnOrderAction.Unique, "customer|"+id),
+			markup.Data("📜 История", btnOrderAction.Unique, "history|"+id),
+			markup.Data("💬 Переписка", btnOrderAction.Unique, "chats|"+id),
+		),
+	}
+	if order.CarrierUUID != nil && order.Status != models.OrderCancelled {
+		rows = append(rows, markup.Row(
+			markup.Data("📄 ТТН", btnOrderAction.Unique, "ttn|"+id),
+			markup.Data("🌍 CMR", btnOrderAction.Unique, "cmr|"+id),
+		))
+	}
+	markup.Inline(append(rows, markup.Row(markup.Data("🗑 Удалить", btnOrderAction.Unique, "delete|"+id)))...)
+	return markup
+}
+
+func customerActionsMarkup(customer models.Customer) *telebot.ReplyMarkup {
+	id := customer.UUID.String()
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(
+			markup.Data("📦 Заказы", btnCustomerAction.Unique, "orders|"+id),
+			markup.Data("✏️ Изменить", btnCustomerAction.Unique, "edit|"+id),
+			markup.Data("📜 История", btnCustomerAction.Unique, "history|"+id),
+		),
+		markup.Row(
+			markup.Data("⭐ Отзывы", btnCustomerAction.Unique, "reviews|"+id),
+			markup.Data("🚫 Заблокировать", btnCustomerAction.Unique, "ban|"+id),
+			markup.Data("🗑 Удалить", btnCustomerAction.Unique, "delete|"+id),
+		),
+	)
+	return markup
+}
+
+func formatAdminOrderCard(order models.Order) string {
+	msg := formatOrderCard(order)
+	msg += fmt.Sprintf("\n\n📌 Статус: %s\n🛡 Модерация: %s", orderStatusTitles[order.Status], moderationTitles[order.ModerationStatus])
+	if order.ModerationReason != nil {
+		msg += fmt.Sprintf(" (%s)", *order.ModerationReason)
+	}
+	if order.Hidden {
+		msg += "\n🙈 Скрыт из выдачи"
+	}
+	if order.PhotosCount > 0 {
+		msg += fmt.Sprintf("\n📷 Фото: %d", order.PhotosCount)
+	}
+	if warning := formatPriceCheck(order); warning != "" {
+		msg += "\n" + warning
+	}
+	if order.Customer != nil {
+		msg += fmt.Sprintf("\n👤 %s", order.Customer.Name)
+		if order.Customer.TelegramTag != nil {
+			msg += fmt.Sprintf(" (@%s)", *order.Customer.TelegramTag)
+		}
+	}
+	msg += fmt.Sprintf("\n🕐 %s\n🆔 %s", order.CreatedAt.Format("02.01.2006 15:04"), order.UUID)
+	return msg
+}
+
+func formatAdminCustomerCard(customer models.Customer, orders int, ban *models.Ban) string {
+	msg := fmt.Sprintf("👤 %s\n", customer.Name)
+	if customer.Phone != "" {
+		msg += fmt.Sprintf("📞 %s\n", customer.Phone)
+	}
+	if customer.TelegramTag != nil {
+		msg += fmt.Sprintf("📱 @%s\n", *customer.TelegramTag)
+	}
+	if customer.TelegramID != nil {
+		msg += fmt.Sprintf("💬 Telegram ID: %d\n", *customer.TelegramID)
+	}
+	msg += fmt.Sprintf("📦 Заказов: %d\n%s\n📅 %s\n🆔 %s", orders, formatReputation(customer),
+		customer.CreatedAt.Format("02.01.2006"), customer.UUID)
+	if ban != nil {
+		msg += "\n\n🚫 Заблокирован: " + formatBanLine(*ban)
+	}
+	return msg
+}
@@ -0,0 +1,432 @@
+package bots
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+const (
+	moderationPollEvery = 15 * time.Second
+	moderationBatchSize = 20
+)
+
+var (
+	btnModApprove = telebot.Btn{Unique: "mod_approve"}
+	btnModReject  = telebot.Btn{Unique: "mod_reject"}
+	btnModEdit    = telebot.Btn{Unique: "mod_edit"}
+)
+
+type inputKind int
+
+const (
+	inputRejectReason inputKind = iota
+	inputEditOrder
+	inputEditOrderCard
+	inputEditCustomer
+	inputBanCustomer
+)
+
+// pendingInput is a free-text answer an admin is expected to send next
+type pendingInput struct {
+	kind inputKind
+	id   string // UUID of the order or customer being handled
+	// message is the card the input refers to, updated once it is received
+	message telebot.Editable
+}
+
+func (b *AdminBot) input(telegramID int64) *pendingInput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inputs[telegramID]
+}
+
+func (b *AdminBot) setInput(telegramID int64, input *pendingInput) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if input == nil {
+		delete(b.inputs, telegramID)
+		return
+	}
+	b.inputs[telegramID] = input
+}
+
+// captureInput handles text sent in reply to a moderation prompt.
+// It reports false if no input was expected.
+func (b *AdminBot) captureInput(c telebot.Context) (bool, error) {
+	input := b.input(c.Sender().ID)
+	if input == nil {
+		return false, nil
+	}
+
+	switch input.kind {
+	case inputRejectReason:
+		return true, b.rejectOrder(c, input)
+	case inputEditOrder, inputEditOrderCard:
+		return true, b.editOrder(c, input)
+	case inputEditCustomer:
+		return true, b.editCustomer(c, input)
+	case inputBanCustomer:
+		return true, b.banCustomer(c, input)
+	default:
+		return false, nil
+	}
+}
+
+// watchModerationQueue sends newly created pending orders to every moderator
+func (b *AdminBot) watchModerationQueue() {
+	ticker := time.NewTicker(moderationPollEvery)
+	defer ticker.Stop()
+
+	for {
+		b.notifyModerators()
+
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *AdminBot) notifyModerators() {
+	orders, err := b.service.ListUnnotifiedModeration(b.ctx, moderationBatchSize)
+	if err != nil {
+		log.Printf("Admin bot: failed to list orders for moderation: %v", err)
+		return
+	}
+	if len(orders) == 0 {
+		return
+	}
+
+	recipients, err := b.moderatorIDs()
+	if err != nil {
+		log.Printf("Admin bot: failed to list moderators: %v", err)
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(orders))
+	for _, order := range orders {
+		for _, telegramID := range recipients {
+			if err := b.sendModerationCard(telebot.ChatID(telegramID), order); err != nil {
+				log.Printf("Admin bot: failed to send order %s to moderator %d: %v", order.UUID, telegramID, err)
+			}
+		}
+		ids = append(ids, order.UUID)
+	}
+
+	if err := b.service.MarkModerationNotified(b.ctx, ids); err != nil {
+		log.Printf("Admin bot: %v", err)
+	}
+}
+
+// moderatorIDs returns everyone with access to the admin bot
+func (b *AdminBot) moderatorIDs() ([]int64, error) {
+	admins, err := b.service.ListAdmins(b.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	var ids []int64
+	for id := range b.roster {
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	for _, admin := range admins {
+		if !seen[admin.TelegramID] {
+			seen[admin.TelegramID] = true
+			ids = append(ids, admin.TelegramID)
+		}
+	}
+	return ids, nil
+}
+
+func (b *AdminBot) handleModeration(c telebot.Context) error {
+	orders, total, err := b.service.ListPendingModeration(b.ctx, moderationBatchSize)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении очереди модерации.")
+	}
+	if total == 0 {
+		return c.Send("✅ Очередь модерации пуста.")
+	}
+
+	if err := c.Send(fmt.Sprintf("🛡 На модерации: %d", total)); err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if err := b.sendModerationCard(c.Chat(), order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *AdminBot) sendModerationCard(to telebot.Recipient, order models.Order) error {
+	_, err := b.bot.Send(to, formatModerationCard(order), moderationMarkup(order.UUID.String()))
+	return err
+}
+
+func moderationMarkup(orderID string) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(
+			markup.Data("✅ Одобрить", btnModApprove.Unique, orderID),
+			markup.Data("❌ Отклонить", btnModReject.Unique, orderID),
+		),
+		markup.Row(markup.Data("✏️ Изменить", btnModEdit.Unique, orderID)),
+	)
+	return markup
+}
+
+func (b *AdminBot) handleModApprove(c telebot.Context) error {
+	order, err := b.service.ModerateOrder(b.ctx, c.Data(), models.ModerationApproved, "", c.Sender().ID)
+	if err != nil {
+		log.Printf("Admin bot: failed to approve order %s: %v", c.Data(), err)
+		return c.Respond(&telebot.CallbackResponse{Text: "❌ Не удалось одобрить заказ."})
+	}
+	if order == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ уже проверен."})
+	}
+	c.Respond()
+
+	log.Printf("Admin bot: %d approved order %s", c.Sender().ID, order.UUID)
+	b.notifyAuthor(*order, fmt.Sprintf("✅ Ваш заказ «%s» прошел модерацию и опубликован.", order.Title))
+
+	return c.Edit(formatModerationCard(*order) + "\n\n✅ Одобрен: " + senderName(c.Sender()))
+}
+
+func (b *AdminBot) handleModReject(c telebot.Context) error {
+	b.setInput(c.Sender().ID, &pendingInput{
+		kind:    inputRejectReason,
+		id:      c.Data(),
+		message: c.Message(),
+	})
+	c.Respond()
+
+	return c.Send("Напишите причину отклонения — она будет отправлена автору.\n\nДля отмены отправьте /cancel")
+}
+
+func (b *AdminBot) rejectOrder(c telebot.Context, input *pendingInput) error {
+	reason := strings.TrimSpace(c.Text())
+	if reason == "" {
+		return c.Send("Причина не может быть пустой.")
+	}
+	b.setInput(c.Sender().ID, nil)
+
+	order, err := b.service.ModerateOrder(b.ctx, input.id, models.ModerationRejected, reason, c.Sender().ID)
+	if err != nil {
+		log.Printf("Admin bot: failed to reject order %s: %v", input.id, err)
+		return c.Send("❌ Не удалось отклонить заказ.")
+	}
+	if order == nil {
+		return c.Send("Заказ уже проверен другим модератором.")
+	}
+
+	log.Printf("Admin bot: %d rejected order %s", c.Sender().ID, order.UUID)
+	b.notifyAuthor(*order, fmt.Sprintf("❌ Ваш заказ «%s» отклонен модератором.\nПричина: %s", order.Title, reason))
+
+	text := formatModerationCard(*order) + fmt.Sprintf("\n\n❌ Отклонен: %s\nПричина: %s", senderName(c.Sender()), reason)
+	if _, err := b.bot.Edit(input.message, text); err != nil {
+		log.Printf("Admin bot: failed to update moderation card: %v", err)
+	}
+	return c.Send("Заказ отклонен, автор уведомлен.")
+}
+
+func (b *AdminBot) handleModEdit(c telebot.Context) error {
+	b.setInput(c.Sender().ID, &pendingInput{
+		kind:    inputEditOrder,
+		id:      c.Data(),
+		message: c.Message(),
+	})
+	c.Respond()
+	return c.Send(orderEditPrompt)
+}
+
+const orderEditPrompt = `✏️ Отправьте новые значения, по одному полю в строке:
+
+название: Перевезти диван
+описание: Третий этаж без лифта
+вес: 80
+цена: 4500 (или 120000 тг, 300 Br; без валюты — в валюте заказа)
+откуда: Москва
+куда: Тверь
+теги: мебель, грузчики
+
+Для отмены отправьте /cancel`
+
+func (b *AdminBot) editOrder(c telebot.Context, input *pendingInput) error {
+	update, err := parseOrderEdits(c.Text())
+	if err != nil {
+		return c.Send(fmt.Sprintf("❌ %v\n\nИсправьте и отправьте снова или /cancel.", err))
+	}
+	b.setInput(c.Sender().ID, nil)
+
+	if _, err := b.service.UpdateOrder(b.actorContext(c), input.id, update); err != nil {
+		log.Printf("Admin bot: failed to edit order %s: %v", input.id, err)
+		return c.Send("❌ Не удалось изменить заказ.")
+	}
+	log.Printf("Admin bot: %d edited order %s", c.Sender().ID, input.id)
+
+	order, err := b.service.GetOrder(b.ctx, input.id)
+	if err != nil || order == nil {
+		return c.Send("❌ Заказ не найден.")
+	}
+
+	if input.kind == inputEditOrderCard {
+		if _, err := b.bot.Edit(input.message, formatAdminOrderCard(*order), orderActionsMarkup(*order)); err != nil {
+			log.Printf("Admin bot: failed to update order card: %v", err)
+		}
+	} else if order.ModerationStatus == models.ModerationPending {
+		// Keep the decision buttons while the order is still pending
+		if _, err := b.bot.Edit(input.message, formatModerationCard(*order), moderationMarkup(input.id)); err != nil {
+			log.Printf("Admin bot: failed to update moderation card: %v", err)
+		}
+	}
+	return c.Send("✅ Заказ изменен.")
+}
+
+// parseOrderEdits reads "поле: значение" lines into an order update
+func parseOrderEdits(text string) (models.UpdateOrderInput, error) {
+	var update models.UpdateOrderInput
+	fields := 0
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return update, fmt.Errorf("строка без двоеточия: %q", line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "название":
+			if value == "" {
+				return update, fmt.Errorf("название не может быть пустым")
+			}
+			update.Title = &value
+		case "описание":
+			update.Description = &value
+		case "вес":
+			weight, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+			if err != nil || weight <= 0 {
+				return update, fmt.Errorf("неверный вес: %q", value)
+			}
+			update.WeightKg = &weight
+		case "цена":
+			price, err := parsePrice(value)
+			if err != nil {
+				return update, fmt.Errorf("неверная цена: %q", value)
+			}
+			update.Price = &price
+		case "откуда":
+			update.FromLocation = &value
+		case "куда":
+			update.ToLocation = &value
+		case "теги":
+			tags := []string{}
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			update.Tags = &tags
+		default:
+			return update, fmt.Errorf("неизвестное поле: %q", key)
+		}
+		fields++
+	}
+
+	if fields == 0 {
+		return update, fmt.Errorf("нет полей для изменения")
+	}
+	return update, nil
+}
+
+// notifyAuthor tells the order's author about a moderation decision through the driver bot
+func (b *AdminBot) notifyAuthor(order models.Order, text string) {
+	if order.Customer == nil || order.Customer.TelegramID == nil {
+		return
+	}
+	if err := b.driverBot.Notify(*order.Customer.TelegramID, text); err != nil {
+		log.Printf("Admin bot: failed to notify author of order %s: %v", order.UUID, err)
+	}
+}
+
+func (b *AdminBot) handleTrust(c telebot.Context) error {
+	return b.setTrusted(c, true)
+}
+
+func (b *AdminBot) handleUntrust(c telebot.Context) error {
+	return b.setTrusted(c, false)
+}
+
+// setTrusted toggles auto-approval for a customer given by Telegram ID or UUID
+func (b *AdminBot) setTrusted(c telebot.Context, trusted bool) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Send("Использование: /trust <telegram_id|uuid> или /untrust <telegram_id|uuid>")
+	}
+
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		telegramID, parseErr := strconv.ParseInt(args[0], 10, 64)
+		if parseErr != nil {
+			return c.Send("❌ Укажите Telegram ID или UUID заказчика.")
+		}
+		customer, err := b.service.GetCustomerByTelegramID(b.ctx, telegramID)
+		if err != nil {
+			return c.Send("❌ Ошибка при поиске заказчика.")
+		}
+		if customer == nil {
+			return c.Send("❌ Заказчик не найден.")
+		}
+		id = customer.UUID
+	}
+
+	if err := b.service.SetCustomerTrusted(b.ctx, id, trusted); err != nil {
+		log.Printf("Admin bot: %v", err)
+		return c.Send("❌ Заказчик не найден.")
+	}
+	log.Printf("Admin bot: %d set trusted=%t for customer %s", c.Sender().ID, trusted, id)
+
+	if trusted {
+		return c.Send("✅ Заказы клиента будут публиковаться без модерации.")
+	}
+	return c.Send("✅ Заказы клиента снова проходят модерацию.")
+}
+
+func formatModerationCard(order models.Order) string {
+	msg := "🛡 Заказ на модерации\n\n" + formatOrderCard(order)
+	if warning := formatPriceCheck(order); warning != "" {
+		msg += "\n\n" + warning
+	}
+	if order.Customer != nil {
+		msg += fmt.Sprintf("\n\n👤 %s", order.Customer.Name)
+		if order.Customer.TelegramTag != nil {
+			msg += fmt.Sprintf(" (@%s)", *order.Customer.TelegramTag)
+		}
+		if order.Customer.TelegramID != nil {
+			msg += fmt.Sprintf("\n🆔 %d", *order.Customer.TelegramID)
+		}
+	}
+	msg += fmt.Sprintf("\n🕐 %s", order.CreatedAt.Format("02.01.2006 15:04"))
+	return msg
+}
+
+func senderName(user *telebot.User) string {
+	if user.Username != "" {
+		return "@" + user.Username
+	}
+	return strconv.FormatInt(user.ID, 10)
+}
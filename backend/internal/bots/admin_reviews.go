@@ -0,0 +1,104 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+var btnReviewRemove = telebot.Btn{Unique: "review_rm"}
+
+// handleReviews lists the latest reviews, optionally only those about one customer
+func (b *AdminBot) handleReviews(c telebot.Context) error {
+	filter := models.ReviewFilter{}
+	if args := c.Args(); len(args) == 1 {
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			return c.Send("Использование: /reviews [uuid заказчика]")
+		}
+		filter.TargetUUID = &id
+	}
+	return b.sendReviews(c, filter)
+}
+
+func (b *AdminBot) sendReviews(c telebot.Context, filter models.ReviewFilter) error {
+	filter.Limit = adminPageSize
+	state := b.listState(c.Sender().ID)
+	b.mu.Lock()
+	state.reviews = filter
+	b.mu.Unlock()
+
+	text, markup, err := b.renderReviews(filter)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении отзывов.")
+	}
+	return c.Send(text, markup)
+}
+
+// handleReviewRemove hides an abusive review and refreshes the list it was removed from
+func (b *AdminBot) handleReviewRemove(c telebot.Context) error {
+	removed, err := b.service.RemoveReview(b.ctx, c.Data(), c.Sender().ID)
+	if err != nil {
+		log.Printf("Admin bot: failed to remove review %s: %v", c.Data(), err)
+		return c.Respond(&telebot.CallbackResponse{Text: "❌ Не удалось удалить отзыв."})
+	}
+	if !removed {
+		return c.Respond(&telebot.CallbackResponse{Text: "Отзыв уже удален."})
+	}
+	log.Printf("Admin bot: %d removed review %s", c.Sender().ID, c.Data())
+	c.Respond(&telebot.CallbackResponse{Text: "🗑 Отзыв удален."})
+
+	state := b.listState(c.Sender().ID)
+	b.mu.Lock()
+	filter := state.reviews
+	b.mu.Unlock()
+
+	text, markup, err := b.renderReviews(filter)
+	if err != nil {
+		return err
+	}
+	if err := c.Edit(text, markup); err != nil && !errors.Is(err, telebot.ErrSameMessageContent) {
+		return err
+	}
+	return nil
+}
+
+func (b *AdminBot) renderReviews(filter models.ReviewFilter) (string, *telebot.ReplyMarkup, error) {
+	reviews, err := b.service.ListReviews(b.ctx, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(reviews) == 0 {
+		return "⭐ Отзывов нет.", nil, nil
+	}
+
+	var msg strings.Builder
+	if filter.TargetUUID != nil {
+		msg.WriteString(fmt.Sprintf("⭐ Отзывы о %s:\n\n", reviews[0].TargetName))
+	} else {
+		msg.WriteString("⭐ Последние отзывы:\n\n")
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	buttons := make([]telebot.Btn, 0, len(reviews))
+	for i, review := range reviews {
+		msg.WriteString(fmt.Sprintf("%d. %s %s → %s\n", i+1, strings.Repeat("⭐", review.Rating), review.AuthorName, review.TargetName))
+		if review.Comment != nil {
+			msg.WriteString(fmt.Sprintf("   «%s»\n", *review.Comment))
+		}
+		msg.WriteString(fmt.Sprintf("   📅 %s\n\n", review.CreatedAt.Format("02.01.2006")))
+		buttons = append(buttons, markup.Data(fmt.Sprintf("🗑 %d", i+1), btnReviewRemove.Unique, review.UUID.String()))
+	}
+	markup.Inline(listRows(markup, buttons, "reviews", 1, len(reviews))...)
+
+	text := strings.TrimRight(msg.String(), "\n")
+	if runes := []rune(text); len(runes) > maxMessageLength {
+		text = string(runes[:maxMessageLength]) + "…"
+	}
+	return text, markup, nil
+}
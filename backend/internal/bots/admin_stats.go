@@ -0,0 +1,188 @@
+package bots
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+var btnStatsPeriod = telebot.Btn{Unique: "stats_period"}
+
+// maxSeriesLines keeps the message short for the all-time period
+const maxSeriesLines = 12
+
+var periodTitles = map[models.StatsPeriod]string{
+	models.PeriodDay:   "Сегодня",
+	models.PeriodWeek:  "7 дней",
+	models.PeriodMonth: "30 дней",
+	models.PeriodAll:   "Всё время",
+}
+
+var statusTitles = map[models.OrderStatus]string{
+	models.OrderOpen:       "Открыт",
+	models.OrderInProgress: "В работе",
+	models.OrderCompleted:  "Выполнен",
+	models.OrderCancelled:  "Отменён",
+}
+
+// parsePeriod maps a command argument to a period, defaulting to a week
+func parsePeriod(arg string) (models.StatsPeriod, bool) {
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "", "week", "неделя":
+		return models.PeriodWeek, true
+	case "day", "today", "сегодня":
+		return models.PeriodDay, true
+	case "month", "месяц":
+		return models.PeriodMonth, true
+	case "all", "всё", "все":
+		return models.PeriodAll, true
+	default:
+		return "", false
+	}
+}
+
+func periodMarkup(current models.StatsPeriod, btn telebot.Btn) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	var buttons []telebot.Btn
+	for _, period := range []models.StatsPeriod{models.PeriodDay, models.PeriodWeek, models.PeriodMonth, models.PeriodAll} {
+		title := periodTitles[period]
+		if period == current {
+			title = "• " + title + " •"
+		}
+		buttons = append(buttons, markup.Data(title, btn.Unique, string(period)))
+	}
+	markup.Inline(markup.Row(buttons...))
+	return markup
+}
+
+func (b *AdminBot) handleStats(c telebot.Context) error {
+	period, ok := parsePeriod(c.Message().Payload)
+	if !ok {
+		return c.Send("Использование: /stats [day|week|month|all]")
+	}
+
+	stats, err := b.service.GetStats(b.ctx, period)
+	if err != nil {
+		log.Printf("Admin bot: failed to get stats: %v", err)
+		return c.Send("❌ Ошибка при получении статистики.")
+	}
+
+	return c.Send(formatStats(stats), periodMarkup(period, btnStatsPeriod))
+}
+
+func (b *AdminBot) handleStatsPeriod(c telebot.Context) error {
+	period, ok := parsePeriod(c.Data())
+	if !ok {
+		return c.Respond(&telebot.CallbackResponse{Text: "Неизвестный период."})
+	}
+
+	stats, err := b.service.GetStats(b.ctx, period)
+	if err != nil {
+		log.Printf("Admin bot: failed to get stats: %v", err)
+		return c.Respond(&telebot.CallbackResponse{Text: "❌ Ошибка при получении статистики."})
+	}
+
+	c.Respond()
+	return c.Edit(formatStats(stats), periodMarkup(period, btnStatsPeriod))
+}
+
+func formatStats(stats models.Stats) string {
+	var msg strings.Builder
+
+	msg.WriteString(fmt.Sprintf("📊 Статистика: %s\n", periodTitles[stats.Period]))
+	if stats.Since != nil {
+		msg.WriteString(fmt.Sprintf("с %s UTC\n", stats.Since.Format("02.01.2006 15:04")))
+	}
+
+	msg.WriteString(fmt.Sprintf(`
+👥 Заказчиков всего: %d
+🆕 Новых за период: %d
+🟢 Активных за период: %d
+
+📦 Заказов всего: %d
+🆕 Новых за период: %d
+
+👁 Просмотров заказов: %d
+📞 Открытий контактов: %d
+`, stats.TotalCustomers, stats.NewCustomers, stats.ActiveUsers, stats.TotalOrders, stats.NewOrders,
+		stats.OrderViews, stats.ContactReveals))
+
+	if len(stats.OrdersByStatus) > 0 {
+		msg.WriteString("\n📋 По статусам:\n")
+		for _, status := range []models.OrderStatus{models.OrderOpen, models.OrderInProgress, models.OrderCompleted, models.OrderCancelled} {
+			if count := stats.OrdersByStatus[status]; count > 0 {
+				msg.WriteString(fmt.Sprintf("   %s: %d\n", statusTitles[status], count))
+			}
+		}
+	}
+
+	gmv := []string{}
+	for _, amount := range stats.GMV {
+		gmv = append(gmv, amount.String())
+	}
+	if len(gmv) == 0 {
+		gmv = append(gmv, models.NewMoney(0, models.DefaultCurrency).String())
+	}
+	msg.WriteString(fmt.Sprintf("\n💰 Оборот (GMV): %s\n", strings.Join(gmv, " · ")))
+	if len(stats.MedianPricePerKg) > 0 {
+		perKg := []string{}
+		for _, amount := range stats.MedianPricePerKg {
+			perKg = append(perKg, amount.String()+"/кг")
+		}
+		msg.WriteString(fmt.Sprintf("⚖️ Медианная цена: %s\n", strings.Join(perKg, " · ")))
+	}
+
+	if len(stats.TopRoutes) > 0 {
+		msg.WriteString("\n🛣 Популярные маршруты:\n")
+		for i, route := range stats.TopRoutes {
+			msg.WriteString(fmt.Sprintf("%d. %s → %s — %d (ср. %s)\n", i+1, route.From, route.To, route.Orders, route.AvgPrice))
+		}
+	}
+
+	if len(stats.OrdersSeries) > 0 || len(stats.CustomerSeries) > 0 {
+		unit := "по дням"
+		if stats.Bucket == "week" {
+			unit = "по неделям"
+		}
+		msg.WriteString(fmt.Sprintf("\n📈 Новые %s (заказы / заказчики):\n", unit))
+		lines := mergeSeries(stats.OrdersSeries, stats.CustomerSeries)
+		if len(lines) > maxSeriesLines {
+			lines = lines[len(lines)-maxSeriesLines:]
+		}
+		for _, line := range lines {
+			msg.WriteString(line + "\n")
+		}
+	}
+
+	msg.WriteString(fmt.Sprintf("\n📅 %s", time.Now().Format("02.01.2006 15:04")))
+	return msg.String()
+}
+
+// mergeSeries lines up two series by bucket start as "02.01: orders / customers"
+func mergeSeries(orders, customers []models.DailyCount) []string {
+	counts := map[time.Time][2]int{}
+	var days []time.Time
+	for i, series := range [][]models.DailyCount{orders, customers} {
+		for _, point := range series {
+			pair, seen := counts[point.Day]
+			if !seen {
+				days = append(days, point.Day)
+			}
+			pair[i] = point.Count
+			counts[point.Day] = pair
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	lines := make([]string, 0, len(days))
+	for _, day := range days {
+		pair := counts[day]
+		lines = append(lines, fmt.Sprintf("   %s: %d / %d", day.Format("02.01"), pair[0], pair[1]))
+	}
+	return lines
+}
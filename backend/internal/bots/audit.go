@@ -0,0 +1,129 @@
+package bots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+const (
+	auditPageSize   = 10
+	auditValueWidth = 60
+	// Telegram rejects messages longer than 4096 characters
+	maxMessageLength = 4000
+)
+
+// actorContext attributes changes made while handling an update to its sender
+func actorContext(ctx context.Context, channel models.Channel, c telebot.Context) context.Context {
+	actor := models.Actor{
+		Channel:   channel,
+		RequestID: fmt.Sprintf("tg-%d", c.Update().ID),
+	}
+	if user := c.Sender(); user != nil {
+		actor.TelegramID = &user.ID
+	}
+	return service.WithActor(ctx, actor)
+}
+
+func (b *DriverBot) actorContext(c telebot.Context) context.Context {
+	return actorContext(b.ctx, models.ChannelDriverBot, c)
+}
+
+func (b *AdminBot) actorContext(c telebot.Context) context.Context {
+	return actorContext(b.ctx, models.ChannelAdminBot, c)
+}
+
+// handleAudit shows the latest changes, of one order or customer given by
+// UUID, or made by one user given by Telegram ID
+func (b *AdminBot) handleAudit(c telebot.Context) error {
+	filter := models.AuditFilter{Limit: auditPageSize}
+
+	if args := c.Args(); len(args) > 0 {
+		if id, err := uuid.Parse(args[0]); err == nil {
+			filter.EntityUUID = &id
+		} else if telegramID, err := strconv.ParseInt(args[0], 10, 64); err == nil {
+			filter.ActorID = &telegramID
+		} else {
+			return c.Send("Использование: /audit [uuid заказа или заказчика | telegram_id автора]")
+		}
+	}
+
+	return b.sendAudit(c, filter)
+}
+
+func (b *AdminBot) sendAudit(c telebot.Context, filter models.AuditFilter) error {
+	entries, total, err := b.service.ListAudit(b.ctx, filter)
+	if err != nil {
+		return c.Send("❌ Ошибка при получении журнала изменений.")
+	}
+	if total == 0 {
+		return c.Send("📜 Изменений не найдено.")
+	}
+	text := formatAudit(entries, total)
+	if runes := []rune(text); len(runes) > maxMessageLength {
+		text = string(runes[:maxMessageLength]) + "…"
+	}
+	return c.Send(text)
+}
+
+func formatAudit(entries []models.AuditEntry, total int) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("📜 Журнал изменений: %d", total))
+	if total > len(entries) {
+		msg.WriteString(fmt.Sprintf(", последние %d", len(entries)))
+	}
+	msg.WriteString("\n")
+
+	entity := map[string]string{models.AuditOrder: "заказ", models.AuditCustomer: "заказчик"}
+	action := map[string]string{models.AuditCreate: "🆕 создан", models.AuditUpdate: "✏️ изменен"}
+
+	for _, entry := range entries {
+		msg.WriteString(fmt.Sprintf("\n%s · %s", entry.CreatedAt.Format("02.01.2006 15:04"), entry.Channel))
+		if entry.ActorID != nil {
+			msg.WriteString(fmt.Sprintf(" · %d", *entry.ActorID))
+		}
+		msg.WriteString(fmt.Sprintf("\n%s %s %s\n", entity[entry.EntityType], entry.EntityUUID, action[entry.Action]))
+
+		fields := make([]string, 0, len(entry.Changes))
+		for field := range entry.Changes {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			change := entry.Changes[field]
+			if entry.Action == models.AuditCreate {
+				msg.WriteString(fmt.Sprintf("   %s: %s\n", field, formatAuditValue(change.After)))
+				continue
+			}
+			msg.WriteString(fmt.Sprintf("   %s: %s → %s\n", field, formatAuditValue(change.Before), formatAuditValue(change.After)))
+		}
+	}
+
+	return strings.TrimRight(msg.String(), "\n")
+}
+
+func formatAuditValue(value interface{}) string {
+	if value == nil {
+		return "—"
+	}
+	text, ok := value.(string)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		text = string(data)
+	}
+	if runes := []rune(text); len(runes) > auditValueWidth {
+		text = string(runes[:auditValueWidth]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,212 @@
+package bots
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+const (
+	// Telegram allows about 30 messages per second to different users
+	broadcastRate      = 25
+	broadcastBatchSize = 100
+	broadcastIdlePoll  = 30 * time.Second
+)
+
+// Broadcaster delivers queued broadcasts through the driver bot, the bot
+// every user has started. The queue lives in the database, so deliveries
+// resume after a restart.
+type Broadcaster struct {
+	bot     *telebot.Bot
+	service *service.Service
+	wake    chan struct{}
+
+	mu      sync.Mutex
+	cancels map[uuid.UUID]context.CancelFunc
+}
+
+func NewBroadcaster(driverBot *DriverBot, service *service.Service) *Broadcaster {
+	return &Broadcaster{
+		bot:     driverBot.bot,
+		service: service,
+		wake:    make(chan struct{}, 1),
+		cancels: make(map[uuid.UUID]context.CancelFunc),
+	}
+}
+
+// Run processes active broadcasts one by one until ctx is cancelled
+func (br *Broadcaster) Run(ctx context.Context) {
+	log.Println("Broadcaster started...")
+	for {
+		broadcasts, err := br.service.ListActiveBroadcasts(ctx)
+		if err != nil {
+			log.Printf("Broadcaster: failed to list broadcasts: %v", err)
+		}
+		for _, broadcast := range broadcasts {
+			if ctx.Err() != nil {
+				return
+			}
+			br.deliver(ctx, broadcast)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Broadcaster stopped")
+			return
+		case <-br.wake:
+		case <-time.After(broadcastIdlePoll):
+		}
+	}
+}
+
+// Enqueue wakes the worker up after a new broadcast was created
+func (br *Broadcaster) Enqueue() {
+	select {
+	case br.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel stops a broadcast; deliveries already sent are kept
+func (br *Broadcaster) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	cancelled, err := br.service.SetBroadcastStatus(ctx, id, models.BroadcastCancelled)
+	if err != nil {
+		return false, err
+	}
+
+	br.mu.Lock()
+	if cancel, ok := br.cancels[id]; ok {
+		cancel()
+	}
+	br.mu.Unlock()
+
+	return cancelled, nil
+}
+
+func (br *Broadcaster) deliver(parent context.Context, broadcast models.Broadcast) {
+	ctx, cancel := context.WithCancel(parent)
+	br.mu.Lock()
+	br.cancels[broadcast.UUID] = cancel
+	br.mu.Unlock()
+	defer func() {
+		cancel()
+		br.mu.Lock()
+		delete(br.cancels, broadcast.UUID)
+		br.mu.Unlock()
+	}()
+
+	active, err := br.service.SetBroadcastStatus(ctx, broadcast.UUID, models.BroadcastRunning)
+	if err != nil {
+		log.Printf("Broadcaster: failed to start %s: %v", broadcast.UUID, err)
+		return
+	}
+	if !active {
+		return
+	}
+
+	limiter := time.NewTicker(time.Second / broadcastRate)
+	defer limiter.Stop()
+
+	for {
+		recipients, err := br.service.NextBroadcastRecipients(ctx, broadcast.UUID, broadcastBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Broadcaster: failed to fetch recipients of %s: %v", broadcast.UUID, err)
+			}
+			return
+		}
+		if len(recipients) == 0 {
+			break
+		}
+
+		for _, telegramID := range recipients {
+			select {
+			case <-ctx.Done():
+				return
+			case <-limiter.C:
+			}
+
+			status, deliveryErr := br.send(ctx, &broadcast, telegramID)
+			if status == models.DeliveryPending {
+				return // stopped while waiting out a flood limit, retry later
+			}
+			var errText *string
+			if deliveryErr != nil {
+				text := deliveryErr.Error()
+				errText = &text
+			}
+			// Record the outcome even if we were cancelled meanwhile, so the message is not sent twice
+			if err := br.service.MarkBroadcastDelivery(context.WithoutCancel(ctx), broadcast.UUID, telegramID, status, errText); err != nil {
+				log.Printf("Broadcaster: failed to record delivery to %d: %v", telegramID, err)
+				return
+			}
+		}
+	}
+
+	if _, err := br.service.SetBroadcastStatus(ctx, broadcast.UUID, models.BroadcastDone); err != nil {
+		log.Printf("Broadcaster: failed to finish %s: %v", broadcast.UUID, err)
+		return
+	}
+	log.Printf("Broadcaster: %s finished", broadcast.UUID)
+}
+
+// send delivers the broadcast to one user, waiting out flood limits, and
+// returns the delivery status. DeliveryPending means nothing was sent.
+func (br *Broadcaster) send(ctx context.Context, broadcast *models.Broadcast, telegramID int64) (string, error) {
+	opts := &telebot.SendOptions{}
+	if len(broadcast.Entities) > 0 {
+		if err := json.Unmarshal(broadcast.Entities, &opts.Entities); err != nil {
+			log.Printf("Broadcaster: invalid entities in %s: %v", broadcast.UUID, err)
+		}
+	}
+
+	for {
+		var what interface{} = broadcast.Text
+		if len(broadcast.Photo) > 0 {
+			photo := &telebot.Photo{Caption: broadcast.Text}
+			if broadcast.PhotoFileID != nil {
+				photo.File = telebot.File{FileID: *broadcast.PhotoFileID}
+			} else {
+				photo.File = telebot.FromReader(bytes.NewReader(broadcast.Photo))
+			}
+			what = photo
+		}
+
+		msg, err := br.bot.Send(telebot.ChatID(telegramID), what, opts)
+
+		var flood telebot.FloodError
+		switch {
+		case err == nil:
+			// Reuse the uploaded photo for the remaining recipients
+			if broadcast.PhotoFileID == nil && msg != nil && msg.Photo != nil {
+				fileID := msg.Photo.FileID
+				broadcast.PhotoFileID = &fileID
+				if err := br.service.SetBroadcastPhotoFileID(ctx, broadcast.UUID, fileID); err != nil {
+					log.Printf("Broadcaster: %v", err)
+				}
+			}
+			return models.DeliverySent, nil
+		case errors.As(err, &flood):
+			select {
+			case <-ctx.Done():
+				return models.DeliveryPending, ctx.Err()
+			case <-time.After(time.Duration(flood.RetryAfter) * time.Second):
+			}
+		case errors.Is(err, telebot.ErrBlockedByUser),
+			errors.Is(err, telebot.ErrUserIsDeactivated),
+			errors.Is(err, telebot.ErrChatNotFound):
+			return models.DeliveryBlocked, err
+		default:
+			return models.DeliveryFailed, err
+		}
+	}
+}
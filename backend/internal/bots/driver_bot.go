@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"gopkg.in/telebot.v3"
 	"gruzy-ryadom/internal/models"
 	"gruzy-ryadom/internal/service"
@@ -17,6 +19,13 @@ type DriverBot struct {
 	service *service.Service
 	ctx     context.Context
 	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	comments map[int64]uuid.UUID   // review awaiting a comment, by author Telegram ID
+	chats    map[int64]uuid.UUID   // open chat thread, by participant Telegram ID
+	drafts   map[int64]*orderDraft // order being created, by author Telegram ID
+	photos   map[int64]uuid.UUID   // order receiving photos, by author Telegram ID
+	imports  map[int64][][]string  // checked spreadsheet awaiting confirmation, by author Telegram ID
 }
 
 func NewDriverBot(token string, service *service.Service) (*DriverBot, error) {
@@ -33,24 +42,57 @@ func NewDriverBot(token string, service *service.Service) (*DriverBot, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &DriverBot{
-		bot:     bot,
-		service: service,
-		ctx:     ctx,
-		cancel:  cancel,
+		bot:      bot,
+		service:  service,
+		ctx:      ctx,
+		cancel:   cancel,
+		comments: make(map[int64]uuid.UUID),
+		drafts:   make(map[int64]*orderDraft),
+		photos:   make(map[int64]uuid.UUID),
+		imports:  make(map[int64][][]string),
+		chats:    make(map[int64]uuid.UUID),
 	}, nil
 }
 
 func (b *DriverBot) Start() {
+	b.bot.Use(b.trackActivity, b.rejectBanned)
+
 	// Driver bot commands
 	b.bot.Handle("/start", b.handleStart)
 	b.bot.Handle("/help", b.handleHelp)
 	b.bot.Handle("/orders", b.handleOrders)
 	b.bot.Handle("/create_order", b.handleCreateOrder)
+	b.bot.Handle("/my_orders", b.handleMyOrders)
 	b.bot.Handle("/profile", b.handleProfile)
+	b.bot.Handle("/vehicle", b.handleVehicle)
+	b.bot.Handle("/skip", b.handleSkip)
+	b.bot.Handle("/cancel", b.handleCancel)
+	b.bot.Handle("/done", b.handleDone)
+	b.bot.Handle("/stop_chat", b.handleStopChat)
+	b.bot.Handle("/api_token", b.handleAPIToken)
+	b.bot.Handle("/subscribe", b.handleSubscribe)
+	b.bot.Handle("/unsubscribe", b.handleUnsubscribe)
+
+	// Order and review buttons
+	b.bot.Handle(&btnTakeOrder, b.handleTakeOrder)
+	b.bot.Handle(&btnCompleteOrder, b.handleCompleteOrder)
+	b.bot.Handle(&btnCancelOrder, b.handleCancelOrder)
+	b.bot.Handle(&btnOrderPhotos, b.handleOrderPhotos)
+	b.bot.Handle(&btnWaybill, b.handleWaybill)
+	b.bot.Handle(&btnImportConfirm, b.handleImportConfirm)
+	b.bot.Handle(&btnImportCancel, b.handleImportCancel)
+	b.bot.Handle(&btnRate, b.handleRate)
+	b.bot.Handle(&btnChatOpen, b.handleChatOpen)
+	b.bot.Handle(&btnChatReply, b.handleChatReply)
+	b.bot.Handle(&btnChatReveal, b.handleChatReveal)
+	b.bot.Handle(&btnRevealContacts, b.handleRevealContacts)
 
 	// Inline handlers
 	b.bot.Handle(telebot.OnText, b.handleText)
+	b.bot.Handle(telebot.OnPhoto, b.handlePhoto)
+	b.bot.Handle(telebot.OnDocument, b.handleDocument)
 	b.bot.Handle(telebot.OnCallback, b.handleCallback)
+	b.bot.Handle(telebot.OnQuery, b.handleQuery)
 
 	log.Println("Driver Bot started...")
 	b.bot.Start()
@@ -61,8 +103,57 @@ func (b *DriverBot) Stop() {
 	b.bot.Stop()
 }
 
+// trackActivity is a middleware recording the sender's last interaction for statistics
+func (b *DriverBot) trackActivity(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		if user := c.Sender(); user != nil {
+			if err := b.service.TouchCustomer(b.ctx, user.ID); err != nil {
+				log.Printf("Failed to record activity of %d: %v", user.ID, err)
+			}
+		}
+		return next(c)
+	}
+}
+
+// rejectBanned is a middleware that stops updates from banned users
+func (b *DriverBot) rejectBanned(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(c telebot.Context) error {
+		user := c.Sender()
+		if user == nil {
+			return next(c)
+		}
+
+		ban, err := b.service.GetActiveBanByTelegramID(b.ctx, user.ID)
+		if err != nil {
+			// An unknown ban status must not let banned users through
+			log.Printf("Failed to check ban of %d: %v", user.ID, err)
+			switch {
+			case c.Query() != nil:
+				return c.Answer(&telebot.QueryResponse{CacheTime: 1})
+			case c.Callback() != nil:
+				return c.Respond(&telebot.CallbackResponse{Text: "Произошла ошибка, попробуйте позже."})
+			default:
+				return c.Send("Произошла ошибка, попробуйте позже.")
+			}
+		}
+		if ban == nil {
+			return next(c)
+		}
+
+		switch {
+		case c.Query() != nil:
+			return c.Answer(&telebot.QueryResponse{CacheTime: 60})
+		case c.Callback() != nil:
+			return c.Respond(&telebot.CallbackResponse{Text: "🚫 Доступ ограничен."})
+		default:
+			return c.Send(formatBan(*ban))
+		}
+	}
+}
+
 func (b *DriverBot) handleStart(c telebot.Context) error {
 	user := c.Sender()
+	payload := strings.TrimSpace(c.Message().Payload)
 	
 	// Check if user exists
 	customer, err := b.service.GetCustomerByTelegramID(b.ctx, user.ID)
@@ -70,10 +161,15 @@ func (b *DriverBot) handleStart(c telebot.Context) error {
 		return c.Send("Произошла ошибка при проверке профиля.")
 	}
 
+	// A returning user is reachable by broadcasts again
+	if err := b.service.MarkBotUnblocked(b.ctx, user.ID); err != nil {
+		log.Printf("Failed to unblock %d: %v", user.ID, err)
+	}
+
 	if customer == nil {
 		// Create new customer
 		input := models.CreateCustomerInput{
-			Name:       user.FirstName + " " + user.LastName,
+			Name:       strings.TrimSpace(user.FirstName + " " + user.LastName),
 			Phone:      "", // Will be asked later
 			TelegramID: &user.ID,
 		}
@@ -81,10 +177,21 @@ func (b *DriverBot) handleStart(c telebot.Context) error {
 			input.TelegramTag = &user.Username
 		}
 
-		_, err = b.service.CreateCustomer(b.ctx, input)
+		created, err := b.service.CreateCustomer(b.actorContext(c), input)
 		if err != nil {
 			return c.Send("Произошла ошибка при создании профиля.")
 		}
+
+		// Only new customers can be attributed to a referrer
+		if code, ok := strings.CutPrefix(payload, "ref_"); ok {
+			if err := b.service.RegisterReferral(b.ctx, code, created.UUID); err != nil {
+				log.Printf("Failed to register referral %q for %s: %v", code, created.UUID, err)
+			}
+		}
+	}
+
+	if orderID, ok := strings.CutPrefix(payload, "order_"); ok {
+		return b.sendOrder(c, orderID)
 	}
 
 	msg := `🚛 Добро пожаловать в "Грузы рядом"!
@@ -92,24 +199,91 @@ func (b *DriverBot) handleStart(c telebot.Context) error {
 Доступные команды:
 /orders - Посмотреть доступные заказы
 /create_order - Создать новый заказ
+/my_orders - Ваши заказы
 /profile - Ваш профиль
 /help - Помощь`
 
 	return c.Send(msg)
 }
 
+// sendOrder shows a single order card opened through a deep link
+func (b *DriverBot) sendOrder(c telebot.Context, orderID string) error {
+	order, err := b.service.GetOrder(b.ctx, orderID)
+	if err != nil || order == nil {
+		return c.Send("Заказ не найден или уже снят с публикации.")
+	}
+
+	// Orders under moderation or hidden by an admin are visible to their author only
+	isOwner := order.Customer != nil && order.Customer.TelegramID != nil && *order.Customer.TelegramID == c.Sender().ID
+	if (order.ModerationStatus != models.ModerationApproved || order.Hidden) && !isOwner {
+		return c.Send("Заказ не найден или уже снят с публикации.")
+	}
+
+	if !isOwner {
+		viewer := fmt.Sprintf("telegram:%d", c.Sender().ID)
+		if customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID); err == nil && customer != nil {
+			viewer = service.CustomerViewer(customer.UUID)
+		}
+		if err := b.service.RecordOrderView(b.actorContext(c), *order, viewer); err != nil {
+			log.Printf("Failed to record view of order %s: %v", order.UUID, err)
+		}
+	}
+
+	// Contacts are opened with a button so that every reveal is logged and capped
+	msg := formatOrderCard(*order)
+	if order.Customer != nil {
+		msg += fmt.Sprintf("\n\n👤 %s\n%s", order.Customer.Name, formatReputation(*order.Customer))
+	}
+
+	// Open orders can be taken by any driver but their author
+	var markup *telebot.ReplyMarkup
+	if !isOwner {
+		markup = &telebot.ReplyMarkup{}
+		row := markup.Row(markup.Data("✉️ Написать заказчику", btnChatOpen.Unique, order.UUID.String()))
+		if order.Status == models.OrderOpen && order.CarrierUUID == nil {
+			row = append(row, markup.Data("🚚 Взять заказ", btnTakeOrder.Unique, order.UUID.String()))
+		}
+		markup.Inline(row, markup.Row(markup.Data("📞 Показать контакты", btnRevealContacts.Unique, order.UUID.String())))
+	}
+
+	return sendOrderCard(c, order.CoverFileID, msg, markup)
+}
+
+// Notify sends a service message to a user on behalf of the driver bot,
+// e.g. a moderation decision made in the admin bot
+func (b *DriverBot) Notify(telegramID int64, what interface{}, opts ...interface{}) error {
+	_, err := b.bot.Send(telebot.ChatID(telegramID), what, opts...)
+	return err
+}
+
 func (b *DriverBot) handleHelp(c telebot.Context) error {
 	msg := `📋 Помощь по командам:
 
 /start - Начать работу с ботом
 /orders - Посмотреть доступные заказы
 /create_order - Создать новый заказ
+/my_orders - Ваши заказы: отметить выполнение, отменить, добавить фото или скачать накладную
 /profile - Ваш профиль
+/vehicle <марка>, <госномер> - Указать машину для накладных
+/subscribe <запрос> - Получать новые заказы по поиску, например /subscribe Казань
+/unsubscribe - Удалить все подписки
+/api_token - Получить токен для HTTP API
 /help - Показать эту справку
 
-Для создания заказа используйте команду /create_order и следуйте инструкциям.`
+Для создания заказа используйте команду /create_order и отвечайте на вопросы бота. На шаге цены бот подскажет рекомендуемый диапазон по похожим заказам. Пропустить необязательный шаг: /skip, отменить: /cancel
 
-	return c.Send(msg)
+📷 После создания заказа можно прикрепить до 5 фото груза — первое увидят водители в карточке. Закончить: /done
+
+📥 Много заказов сразу: пришлите таблицу CSV или XLSX со столбцами Название, Откуда, Куда, Вес, Цена — бот проверит ее и предложит создать заказы.
+
+🚚 Водитель берет заказ кнопкой в карточке. Обе стороны могут сразу скачать транспортную накладную (ТТН или CMR) в PDF. После выполнения заказчик и водитель оценивают друг друга от 1 до 5 — рейтинг виден в карточках заказов.
+
+✉️ Кнопка «Написать заказчику» открывает анонимный чат: бот пересылает сообщения, не раскрывая контактов, пока вы сами не решите ими поделиться. Завершить чат: /stop_chat
+
+🔎 Поиск в любом чате: наберите @%s и запрос, например
+"Казань 500кг", "Москва - Казань #тент" или "Алматы 100000тг" — заказы от этой суммы в тенге.`
+
+	return c.Send(fmt.Sprintf(msg, b.bot.Me.Username))
 }
 
 func (b *DriverBot) handleOrders(c telebot.Context) error {
@@ -133,42 +307,22 @@ func (b *DriverBot) handleOrders(c telebot.Context) error {
 	for i, order := range orders {
 		msg.WriteString(fmt.Sprintf("%d. %s\n", i+1, order.Title))
 		msg.WriteString(fmt.Sprintf("   Вес: %.1f кг\n", order.WeightKg))
-		msg.WriteString(fmt.Sprintf("   Цена: %.0f ₽\n", order.Price))
+		msg.WriteString(fmt.Sprintf("   Цена: %s\n", formatPrice(order)))
 		if order.FromLocation != nil {
 			msg.WriteString(fmt.Sprintf("   Откуда: %s\n", *order.FromLocation))
 		}
 		if order.ToLocation != nil {
 			msg.WriteString(fmt.Sprintf("   Куда: %s\n", *order.ToLocation))
 		}
+		if len(order.Stops) > 2 {
+			msg.WriteString(fmt.Sprintf("   Промежуточных точек: %d\n", len(order.Stops)-2))
+		}
 		msg.WriteString("\n")
 	}
 
 	return c.Send(msg.String())
 }
 
-func (b *DriverBot) handleCreateOrder(c telebot.Context) error {
-	msg := `📝 Создание нового заказа
-
-Пожалуйста, отправьте информацию о заказе в следующем формате:
-
-Название заказа
-Вес (кг)
-Цена (₽)
-Откуда
-Куда
-Описание (необязательно)
-
-Пример:
-Перевезти холодильник
-70
-5000
-Москва
-Казань
-Тонкости: грузить только стоя`
-
-	return c.Send(msg)
-}
-
 func (b *DriverBot) handleProfile(c telebot.Context) error {
 	user := c.Sender()
 	
@@ -189,18 +343,38 @@ func (b *DriverBot) handleProfile(c telebot.Context) error {
 	if customer.TelegramTag != nil {
 		msg += fmt.Sprintf("\nTelegram: @%s", *customer.TelegramTag)
 	}
+	if customer.VehicleModel != nil && customer.VehiclePlate != nil {
+		msg += fmt.Sprintf("\nМашина: %s, %s", *customer.VehicleModel, *customer.VehiclePlate)
+	}
+
+	code, err := b.service.GetReferralCode(b.ctx, customer.UUID)
+	if err != nil {
+		log.Printf("Failed to get referral code for %s: %v", customer.UUID, err)
+		return c.Send(msg)
+	}
+	referrals, err := b.service.CountReferrals(b.ctx, customer.UUID)
+	if err != nil {
+		log.Printf("Failed to count referrals for %s: %v", customer.UUID, err)
+	}
+
+	msg += fmt.Sprintf(`
+
+🤝 Приглашено: %d
+Ваша ссылка для приглашений:
+https://t.me/%s?start=ref_%s`, referrals, b.bot.Me.Username, code)
 
 	return c.Send(msg)
 }
 
 func (b *DriverBot) handleText(c telebot.Context) error {
-	// Handle text input for order creation
-	text := c.Text()
-	
-	// Simple order creation from text
-	// In production you should implement proper state management
-	if strings.Contains(text, "кг") || strings.Contains(text, "₽") {
-		return c.Send("Получена информация о заказе. Обрабатываем...")
+	if handled, err := b.captureReviewComment(c); handled {
+		return err
+	}
+	if handled, err := b.relayChatMessage(c); handled {
+		return err
+	}
+	if handled, err := b.captureOrderDraft(c); handled {
+		return err
 	}
 
 	return nil
@@ -209,4 +383,44 @@ func (b *DriverBot) handleText(c telebot.Context) error {
 func (b *DriverBot) handleCallback(c telebot.Context) error {
 	// Handle inline keyboard callbacks
 	return nil
-} 
\ No newline at end of file
+}
+
+func (b *DriverBot) handleSubscribe(c telebot.Context) error {
+	query := strings.TrimSpace(c.Message().Payload)
+	if query == "" {
+		searches, err := b.service.ListSavedSearches(b.ctx, c.Sender().ID)
+		if err != nil {
+			return c.Send("Произошла ошибка при получении подписок.")
+		}
+		if len(searches) == 0 {
+			return c.Send("У вас нет подписок. Пример: /subscribe Москва - Казань")
+		}
+
+		var msg strings.Builder
+		msg.WriteString("🔔 Ваши подписки:\n\n")
+		for i, search := range searches {
+			msg.WriteString(fmt.Sprintf("%d. %s\n", i+1, search.Query))
+		}
+		msg.WriteString("\nУдалить все: /unsubscribe")
+		return c.Send(msg.String())
+	}
+
+	if _, err := b.service.SaveSearch(b.ctx, c.Sender().ID, query); err != nil {
+		return c.Send("Произошла ошибка при сохранении подписки.")
+	}
+
+	return c.Send(fmt.Sprintf("🔔 Подписка сохранена: %s\n\nНовые заказы по этому поиску будут приходить сюда.", query))
+}
+
+func (b *DriverBot) handleUnsubscribe(c telebot.Context) error {
+	deleted, err := b.service.DeleteSavedSearches(b.ctx, c.Sender().ID)
+	if err != nil {
+		return c.Send("Произошла ошибка при удалении подписок.")
+	}
+	if deleted == 0 {
+		return c.Send("У вас нет подписок.")
+	}
+
+	return c.Send(fmt.Sprintf("🔕 Удалено подписок: %d", deleted))
+}
+
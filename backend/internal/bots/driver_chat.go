@@ -0,0 +1,195 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+var (
+	btnChatOpen   = telebot.Btn{Unique: "chat_open"}
+	btnChatReply  = telebot.Btn{Unique: "chat_reply"}
+	btnChatReveal = telebot.Btn{Unique: "chat_reveal"}
+)
+
+// chatAlias names the driver of a conversation without revealing who they are
+func chatAlias(thread models.ChatThread) string {
+	return "Водитель #" + strings.ToUpper(thread.UUID.String()[:4])
+}
+
+// handleChatOpen starts a conversation of a driver with the customer of an order
+func (b *DriverBot) handleChatOpen(c telebot.Context) error {
+	driver, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || driver == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Сначала зарегистрируйтесь через /start.", ShowAlert: true})
+	}
+
+	thread, err := b.service.OpenChat(b.ctx, c.Data(), driver.UUID)
+	switch {
+	case errors.Is(err, service.ErrBanned):
+		return c.Respond(&telebot.CallbackResponse{Text: "🚫 Доступ ограничен."})
+	case err != nil:
+		log.Printf("Failed to open chat about %s for %d: %v", c.Data(), c.Sender().ID, err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ недоступен."})
+	}
+	c.Respond()
+
+	b.setActiveChat(c.Sender().ID, thread.UUID)
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data("👤 Показать мои контакты", btnChatReveal.Unique, thread.UUID.String())))
+	return c.Send(fmt.Sprintf(`✉️ Чат по заказу «%s»
+
+Напишите сообщение — бот перешлет его заказчику, не раскрывая ваших контактов. Поделиться ими можно в любой момент кнопкой под сообщениями.
+
+Завершить: /stop_chat`, thread.OrderTitle), markup)
+}
+
+// handleChatReply switches the sender to the conversation they are answering
+func (b *DriverBot) handleChatReply(c telebot.Context) error {
+	thread, _, err := b.chatParty(c, c.Data())
+	if err != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Чат не найден."})
+	}
+	c.Respond()
+
+	b.setActiveChat(c.Sender().ID, thread.UUID)
+	return c.Send(fmt.Sprintf("✉️ Чат по заказу «%s». Напишите ответ.\n\nЗавершить: /stop_chat", thread.OrderTitle))
+}
+
+// handleChatReveal sends the sender's contacts to the other side of the conversation
+func (b *DriverBot) handleChatReveal(c telebot.Context) error {
+	thread, sender, err := b.chatParty(c, c.Data())
+	if err != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Чат не найден."})
+	}
+
+	if err := b.service.RevealChatContacts(b.ctx, *thread, sender.UUID); err != nil {
+		log.Printf("Failed to reveal contacts in chat %s: %v", thread.UUID, err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Не удалось отправить контакты."})
+	}
+
+	who := "Заказчик"
+	if sender.UUID == thread.DriverUUID {
+		who = chatAlias(*thread)
+	}
+	msg := fmt.Sprintf("👤 %s по заказу «%s» поделился контактами:\n\n%s", who, thread.OrderTitle, sender.Name)
+	if sender.Phone != "" {
+		msg += fmt.Sprintf("\n📞 %s", sender.Phone)
+	}
+	if sender.TelegramTag != nil {
+		msg += fmt.Sprintf("\n📱 @%s", *sender.TelegramTag)
+	}
+
+	if err := b.notifyChatPeer(*thread, sender.UUID, msg, nil); err != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Не удалось отправить контакты."})
+	}
+	return c.Respond(&telebot.CallbackResponse{Text: "✅ Контакты отправлены собеседнику."})
+}
+
+func (b *DriverBot) handleStopChat(c telebot.Context) error {
+	b.mu.Lock()
+	_, ok := b.chats[c.Sender().ID]
+	delete(b.chats, c.Sender().ID)
+	b.mu.Unlock()
+
+	if !ok {
+		return c.Send("У вас нет открытого чата.")
+	}
+	return c.Send("Чат закрыт. Новые сообщения собеседника придут с кнопкой «Ответить».")
+}
+
+// relayChatMessage forwards a text message to the other side of the sender's open conversation
+func (b *DriverBot) relayChatMessage(c telebot.Context) (bool, error) {
+	b.mu.Lock()
+	threadID, ok := b.chats[c.Sender().ID]
+	b.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	thread, sender, err := b.chatParty(c, threadID.String())
+	if err != nil {
+		b.mu.Lock()
+		delete(b.chats, c.Sender().ID)
+		b.mu.Unlock()
+		return true, c.Send("Чат больше недоступен.")
+	}
+
+	message, err := b.service.SendChatMessage(b.ctx, *thread, sender.UUID, c.Text())
+	if err != nil {
+		log.Printf("Failed to store message in chat %s: %v", thread.UUID, err)
+		return true, c.Send("Не удалось отправить сообщение. Слишком длинный текст?")
+	}
+
+	who := "Заказчик"
+	if sender.UUID == thread.DriverUUID {
+		who = chatAlias(*thread)
+	}
+	text := fmt.Sprintf("💬 %s · «%s»:\n\n%s", who, thread.OrderTitle, message.Text)
+
+	if err := b.notifyChatPeer(*thread, sender.UUID, text, chatMarkup(thread.UUID)); err != nil {
+		return true, c.Send("Не удалось доставить сообщение собеседнику.")
+	}
+	return true, nil
+}
+
+// chatParty loads a conversation and the sender's profile, checking that they take part in it
+func (b *DriverBot) chatParty(c telebot.Context, threadID string) (*models.ChatThread, *models.Customer, error) {
+	id, err := uuid.Parse(threadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	thread, err := b.service.GetChatThread(b.ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if thread == nil || sender == nil || (sender.UUID != thread.DriverUUID && sender.UUID != thread.CustomerUUID) {
+		return nil, nil, fmt.Errorf("chat %s not found", threadID)
+	}
+	return thread, sender, nil
+}
+
+func (b *DriverBot) notifyChatPeer(thread models.ChatThread, senderUUID uuid.UUID, text string, markup *telebot.ReplyMarkup) error {
+	peerUUID := thread.DriverUUID
+	if senderUUID == thread.DriverUUID {
+		peerUUID = thread.CustomerUUID
+	}
+
+	peer, err := b.service.GetCustomer(b.ctx, peerUUID.String())
+	if err != nil || peer == nil || peer.TelegramID == nil {
+		return fmt.Errorf("chat peer %s is not reachable: %v", peerUUID, err)
+	}
+	if err := b.Notify(*peer.TelegramID, text, markup); err != nil {
+		log.Printf("Failed to relay chat %s message to %d: %v", thread.UUID, *peer.TelegramID, err)
+		return err
+	}
+	return nil
+}
+
+func chatMarkup(threadID uuid.UUID) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data("↩️ Ответить", btnChatReply.Unique, threadID.String()),
+		markup.Data("👤 Показать мои контакты", btnChatReveal.Unique, threadID.String()),
+	))
+	return markup
+}
+
+func (b *DriverBot) setActiveChat(telegramID int64, threadID uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.drafts, telegramID)
+	delete(b.photos, telegramID)
+	b.chats[telegramID] = threadID
+}
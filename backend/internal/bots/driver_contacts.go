@@ -0,0 +1,71 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+var btnRevealContacts = telebot.Btn{Unique: "contacts"}
+
+// handleRevealContacts shows the customer's contacts of an order to a driver
+func (b *DriverBot) handleRevealContacts(c telebot.Context) error {
+	driver, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || driver == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Сначала зарегистрируйтесь через /start.", ShowAlert: true})
+	}
+
+	contacts, err := b.service.RevealContacts(b.actorContext(c), c.Data(), driver.UUID)
+	switch {
+	case errors.Is(err, service.ErrRevealLimit):
+		return c.Respond(&telebot.CallbackResponse{
+			Text:      fmt.Sprintf("Лимит на сегодня исчерпан: можно открыть контакты %d заказов в сутки.", service.ContactRevealsPerDay),
+			ShowAlert: true,
+		})
+	case errors.Is(err, service.ErrBanned):
+		return c.Respond(&telebot.CallbackResponse{Text: "🚫 Доступ ограничен."})
+	case err != nil:
+		log.Printf("Failed to reveal contacts of %s to %d: %v", c.Data(), c.Sender().ID, err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ недоступен."})
+	}
+	c.Respond()
+
+	return c.Send(formatContacts(contacts))
+}
+
+func (b *DriverBot) handleAPIToken(c telebot.Context) error {
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil {
+		return c.Send("Произошла ошибка при получении профиля.")
+	}
+	if customer == nil {
+		return c.Send("Профиль не найден. Используйте /start для создания профиля.")
+	}
+
+	token, err := b.service.IssueAPIToken(b.ctx, customer.UUID)
+	if err != nil {
+		log.Printf("Failed to issue api token for %s: %v", customer.UUID, err)
+		return c.Send("Не удалось выпустить токен.")
+	}
+
+	return c.Send(fmt.Sprintf(`🔑 Ваш токен для HTTP API:
+
+<code>%s</code>
+
+Передавайте его в заголовке Authorization: Bearer &lt;токен&gt;. Предыдущий токен больше не действует. Никому не сообщайте токен.`, token), telebot.ModeHTML)
+}
+
+func formatContacts(contacts models.Contacts) string {
+	msg := fmt.Sprintf("👤 %s", contacts.Name)
+	if contacts.Phone != "" {
+		msg += fmt.Sprintf("\n📞 %s", contacts.Phone)
+	}
+	if contacts.TelegramTag != nil {
+		msg += fmt.Sprintf("\n📱 @%s", *contacts.TelegramTag)
+	}
+	return msg
+}
@@ -0,0 +1,193 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/export"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+var (
+	btnImportConfirm = telebot.Btn{Unique: "import_ok"}
+	btnImportCancel  = telebot.Btn{Unique: "import_cancel"}
+)
+
+// importFieldTitles name the order fields of an import in error reports
+var importFieldTitles = map[string]string{
+	"title":          "название",
+	"description":    "описание",
+	"from":           "откуда",
+	"to":             "куда",
+	"via":            "промежуточные точки",
+	"weight_kg":      "вес",
+	"length_cm":      "длина",
+	"width_cm":       "ширина",
+	"height_cm":      "высота",
+	"dimensions":     "габариты",
+	"tags":           "теги",
+	"price":          "цена",
+	"currency":       "валюта",
+	"available_from": "дата",
+}
+
+const importHelp = `📥 Массовая загрузка заказов: пришлите файл CSV или XLSX, по заказу в строке.
+
+Обязательные столбцы: Название, Откуда, Куда, Вес, Цена.
+Необязательные: Описание, Промежуточные точки (через «;»), Габариты (180x60x70), Теги, Валюта, Дата.`
+
+// maxImportErrorsShown keeps the dry run report within one message
+const maxImportErrorsShown = 15
+
+// handleDocument checks a spreadsheet of orders and asks to confirm the import
+func (b *DriverBot) handleDocument(c telebot.Context) error {
+	doc := c.Message().Document
+	format, ok := export.FormatOf(doc.FileName)
+	if !ok {
+		return c.Send("Пришлите таблицу в формате CSV или XLSX.\n\n" + importHelp)
+	}
+	if doc.FileSize > service.MaxImportBytes {
+		return c.Send(fmt.Sprintf("Файл слишком большой: не больше %d МБ.", service.MaxImportBytes>>20))
+	}
+
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil {
+		return c.Send("Произошла ошибка. Попробуйте позже.")
+	}
+	if customer == nil {
+		return c.Send("Сначала зарегистрируйтесь через /start.")
+	}
+
+	file, err := b.bot.File(&doc.File)
+	if err != nil {
+		log.Printf("Failed to download import from %d: %v", c.Sender().ID, err)
+		return c.Send("Не удалось загрузить файл. Попробуйте еще раз.")
+	}
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxImportBytes+1))
+	file.Close()
+	if err != nil || len(data) > service.MaxImportBytes {
+		return c.Send("Не удалось прочитать файл.")
+	}
+
+	rows, err := export.ReadRows(data, format, service.MaxImportRows+1)
+	if errors.Is(err, service.ErrImportTooLarge) {
+		return c.Send(importErrorText(err))
+	}
+	if err != nil {
+		return c.Send("Не удалось разобрать таблицу. Проверьте, что файл сохранен как CSV или XLSX.")
+	}
+
+	result, err := b.service.ImportOrders(b.ctx, customer.UUID, rows, nil, true)
+	if err != nil {
+		return c.Send(importErrorText(err))
+	}
+
+	b.mu.Lock()
+	b.imports[c.Sender().ID] = rows
+	b.mu.Unlock()
+
+	msg := formatImportReport(result)
+	if result.Valid == 0 {
+		return c.Send(msg + "\n\nИсправьте файл и пришлите его снова.")
+	}
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(fmt.Sprintf("✅ Создать %d", result.Valid), btnImportConfirm.Unique),
+		markup.Data("❌ Отмена", btnImportCancel.Unique),
+	))
+	return c.Send(msg, markup)
+}
+
+// handleImportConfirm creates the valid orders of the checked file
+func (b *DriverBot) handleImportConfirm(c telebot.Context) error {
+	b.mu.Lock()
+	rows, ok := b.imports[c.Sender().ID]
+	delete(b.imports, c.Sender().ID)
+	b.mu.Unlock()
+	if !ok {
+		return c.Respond(&telebot.CallbackResponse{Text: "Файл уже загружен или отменен."})
+	}
+
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || customer == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Произошла ошибка. Попробуйте позже."})
+	}
+	c.Respond()
+
+	result, err := b.service.ImportOrders(b.actorContext(c), customer.UUID, rows, nil, false)
+	if err != nil {
+		log.Printf("Failed to import orders of %s: %v", customer.UUID, err)
+		return c.Edit(importErrorText(err))
+	}
+	return c.Edit(fmt.Sprintf("✅ Создано заказов: %d. Заказы новых заказчиков публикуются после проверки модератором.\n\nВаши заказы: /my_orders",
+		len(result.Created)))
+}
+
+func (b *DriverBot) handleImportCancel(c telebot.Context) error {
+	b.mu.Lock()
+	delete(b.imports, c.Sender().ID)
+	b.mu.Unlock()
+
+	c.Respond()
+	return c.Edit("❌ Загрузка отменена.")
+}
+
+func importErrorText(err error) string {
+	switch {
+	case errors.Is(err, service.ErrBanned):
+		return "🚫 Ваш доступ к сервису ограничен."
+	case errors.Is(err, service.ErrImportColumns):
+		return "В таблице не найдены нужные столбцы.\n\n" + importHelp
+	case errors.Is(err, service.ErrImportTooLarge):
+		return fmt.Sprintf("Слишком много строк: не больше %d заказов в одном файле.", service.MaxImportRows)
+	}
+	log.Printf("Failed to import orders: %v", err)
+	return "Произошла ошибка при загрузке. Попробуйте позже."
+}
+
+func formatImportReport(result models.ImportResult) string {
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("📥 Проверка файла: строк %d, готово к созданию %d.", result.Rows, result.Valid))
+
+	var pending, outliers int
+	for _, preview := range result.Preview {
+		if preview.ModerationStatus == models.ModerationPending {
+			pending++
+		}
+		if preview.PriceCheck != nil && preview.PriceCheck.Flag != "" {
+			outliers++
+		}
+	}
+	if pending > 0 {
+		msg.WriteString(fmt.Sprintf("\n⏳ Уйдут на модерацию: %d", pending))
+	}
+	if outliers > 0 {
+		msg.WriteString(fmt.Sprintf("\n⚠️ Цена заметно отличается от похожих заказов: %d", outliers))
+	}
+	if len(result.Errors) == 0 {
+		return msg.String()
+	}
+
+	msg.WriteString(fmt.Sprintf("\n\n⚠️ Пропущено строк: %d\n", len(result.Errors)))
+	for i, rowErr := range result.Errors {
+		if i == maxImportErrorsShown {
+			msg.WriteString(fmt.Sprintf("…и еще %d\n", len(result.Errors)-i))
+			break
+		}
+		problem := "неверное значение"
+		if rowErr.Code == "required" {
+			problem = "не заполнено"
+		}
+		if rowErr.Field == "" {
+			msg.WriteString(fmt.Sprintf("Строка %d: %s\n", rowErr.Row, rowErr.Message))
+			continue
+		}
+		msg.WriteString(fmt.Sprintf("Строка %d, %s: %s\n", rowErr.Row, importFieldTitles[rowErr.Field], problem))
+	}
+	return strings.TrimRight(msg.String(), "\n")
+}
@@ -0,0 +1,112 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/service"
+)
+
+var btnOrderPhotos = telebot.Btn{Unique: "order_photos"}
+
+// handleOrderPhotos starts collecting photos for an order of the sender
+func (b *DriverBot) handleOrderPhotos(c telebot.Context) error {
+	orderID, err := uuid.Parse(c.Data())
+	if err != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ не найден."})
+	}
+	c.Respond()
+	return b.startPhotoUpload(c, orderID)
+}
+
+func (b *DriverBot) startPhotoUpload(c telebot.Context, orderID uuid.UUID) error {
+	b.mu.Lock()
+	delete(b.chats, c.Sender().ID)
+	delete(b.comments, c.Sender().ID)
+	b.photos[c.Sender().ID] = orderID
+	b.mu.Unlock()
+
+	return c.Send(fmt.Sprintf("📷 Отправьте фото груза — до %d на заказ, каждое до %d МБ.\n\nЗакончить: /done",
+		service.MaxOrderPhotos, service.MaxPhotoBytes>>20))
+}
+
+func (b *DriverBot) handleDone(c telebot.Context) error {
+	b.mu.Lock()
+	_, ok := b.photos[c.Sender().ID]
+	delete(b.photos, c.Sender().ID)
+	b.mu.Unlock()
+
+	if !ok {
+		return c.Send("Нечего завершать.")
+	}
+	return c.Send("✅ Фото сохранены. Ваши заказы: /my_orders")
+}
+
+// handlePhoto attaches a photo to the order the sender is adding photos to
+func (b *DriverBot) handlePhoto(c telebot.Context) error {
+	b.mu.Lock()
+	orderID, ok := b.photos[c.Sender().ID]
+	b.mu.Unlock()
+	if !ok {
+		return c.Send("Чтобы прикрепить фото к заказу, нажмите «📷 Фото» в /my_orders.")
+	}
+
+	photo := c.Message().Photo
+	if photo.FileSize > service.MaxPhotoBytes {
+		return c.Send(fmt.Sprintf("Фото слишком большое: не больше %d МБ.", service.MaxPhotoBytes>>20))
+	}
+
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || customer == nil {
+		return c.Send("Произошла ошибка. Попробуйте позже.")
+	}
+
+	file, err := b.bot.File(&photo.File)
+	if err != nil {
+		log.Printf("Failed to download photo from %d: %v", c.Sender().ID, err)
+		return c.Send("Не удалось загрузить фото. Попробуйте еще раз.")
+	}
+	defer file.Close()
+
+	_, err = b.service.AddOrderPhoto(b.actorContext(c), orderID.String(), customer.UUID, &photo.FileID, file)
+	switch {
+	case errors.Is(err, service.ErrPhotoLimit):
+		b.mu.Lock()
+		delete(b.photos, c.Sender().ID)
+		b.mu.Unlock()
+		return c.Send(fmt.Sprintf("У заказа уже %d фото — это максимум.", service.MaxOrderPhotos))
+	case errors.Is(err, service.ErrPhotoTooLarge):
+		return c.Send("Фото слишком большое. Отправьте снимок поменьше.")
+	case errors.Is(err, service.ErrNotAnImage):
+		return c.Send("Это не похоже на фото. Поддерживаются JPEG и PNG.")
+	case errors.Is(err, service.ErrOrderNotFound):
+		b.mu.Lock()
+		delete(b.photos, c.Sender().ID)
+		b.mu.Unlock()
+		return c.Send("Заказ не найден.")
+	case err != nil:
+		log.Printf("Failed to add photo to order %s: %v", orderID, err)
+		return c.Send("Не удалось сохранить фото. Попробуйте еще раз.")
+	}
+	return c.Send("📷 Фото добавлено. Отправьте еще или завершите: /done")
+}
+
+// sendOrderCard sends an order card with its first photo when it has one
+func sendOrderCard(c telebot.Context, coverFileID *string, msg string, markup *telebot.ReplyMarkup) error {
+	if coverFileID == nil {
+		return c.Send(msg, markup)
+	}
+	photo := &telebot.Photo{File: telebot.File{FileID: *coverFileID}}
+	// Telegram limits photo captions, longer cards follow the photo as text
+	if len([]rune(msg)) <= 1024 {
+		photo.Caption = msg
+		return c.Send(photo, markup)
+	}
+	if err := c.Send(photo); err != nil {
+		log.Printf("Failed to send order photo: %v", err)
+	}
+	return c.Send(msg, markup)
+}
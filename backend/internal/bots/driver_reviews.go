@@ -0,0 +1,301 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+	"gruzy-ryadom/internal/waybill"
+)
+
+const myOrdersLimit = 10
+
+var (
+	btnTakeOrder     = telebot.Btn{Unique: "take_order"}
+	btnCompleteOrder = telebot.Btn{Unique: "order_done"}
+	btnCancelOrder   = telebot.Btn{Unique: "order_cancel"}
+	btnRate          = telebot.Btn{Unique: "rate"}
+)
+
+// handleTakeOrder assigns the order to the driver who pressed the button
+// and tells the customer who is going to carry their cargo
+func (b *DriverBot) handleTakeOrder(c telebot.Context) error {
+	carrier, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || carrier == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Сначала зарегистрируйтесь через /start.", ShowAlert: true})
+	}
+
+	order, err := b.service.TakeOrder(b.actorContext(c), c.Data(), carrier.UUID)
+	switch {
+	case errors.Is(err, service.ErrOrderTaken):
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ уже взят другим водителем.", ShowAlert: true})
+	case errors.Is(err, service.ErrBanned):
+		return c.Respond(&telebot.CallbackResponse{Text: "🚫 Доступ ограничен."})
+	case err != nil:
+		log.Printf("Failed to take order %s by %d: %v", c.Data(), c.Sender().ID, err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Не удалось взять заказ."})
+	}
+
+	c.Respond(&telebot.CallbackResponse{Text: "✅ Заказ ваш!"})
+	if _, err := b.bot.EditReplyMarkup(c.Message(), nil); err != nil {
+		log.Printf("Failed to remove take button: %v", err)
+	}
+
+	if order.Customer != nil && order.Customer.TelegramID != nil {
+		msg := fmt.Sprintf("🚚 Ваш заказ «%s» взял водитель %s\n%s", order.Title, carrier.Name, formatReputation(*carrier))
+		if carrier.Phone != "" {
+			msg += fmt.Sprintf("\n📞 %s", carrier.Phone)
+		}
+		if carrier.TelegramTag != nil {
+			msg += fmt.Sprintf("\n📱 @%s", *carrier.TelegramTag)
+		}
+		msg += "\n\nКогда перевозка будет выполнена, отметьте это в /my_orders."
+		if err := b.Notify(*order.Customer.TelegramID, msg, waybillMarkup(order.UUID)); err != nil {
+			log.Printf("Failed to notify %d about taken order: %v", *order.Customer.TelegramID, err)
+		}
+	}
+
+	// The carrier needs the contacts anyway, this reveal does not count towards the daily cap
+	msg := fmt.Sprintf("✅ Вы взяли заказ «%s».", order.Title)
+	contacts, err := b.service.RevealContacts(b.actorContext(c), order.UUID.String(), carrier.UUID)
+	if err != nil {
+		log.Printf("Failed to reveal contacts of %s to carrier: %v", order.UUID, err)
+		return c.Send(msg, waybillMarkup(order.UUID))
+	}
+	return c.Send(msg+" Свяжитесь с заказчиком:\n\n"+formatContacts(contacts), waybillMarkup(order.UUID))
+}
+
+func (b *DriverBot) handleMyOrders(c telebot.Context) error {
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil {
+		return c.Send("Произошла ошибка при получении заказов.")
+	}
+	if customer == nil {
+		return c.Send("Профиль не найден. Используйте /start для создания профиля.")
+	}
+
+	text, markup, err := b.renderMyOrders(*customer)
+	if err != nil {
+		return c.Send("Произошла ошибка при получении заказов.")
+	}
+	return c.Send(text, markup)
+}
+
+// renderMyOrders lists the latest orders of a customer with buttons to close the active ones
+func (b *DriverBot) renderMyOrders(customer models.Customer) (string, *telebot.ReplyMarkup, error) {
+	orders, total, err := b.service.ListOrders(b.ctx, models.OrderFilter{
+		CustomerUUID:       &customer.UUID,
+		IncludeUnmoderated: true,
+		IncludeHidden:      true,
+		Page:               1,
+		Limit:              myOrdersLimit,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(orders) == 0 {
+		return "У вас пока нет заказов. Создать: /create_order", nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(orders))
+	for _, order := range orders {
+		ids = append(ids, order.UUID)
+	}
+	stats, err := b.service.GetOrderStats(b.ctx, ids)
+	if err != nil {
+		log.Printf("Failed to get stats of orders of %s: %v", customer.UUID, err)
+	}
+
+	var msg strings.Builder
+	msg.WriteString(fmt.Sprintf("📋 Ваши заказы (%d):\n\n", total))
+	msg.WriteString("👁 — просмотры, 📞 — открытия контактов\n\n")
+
+	markup := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for i, order := range orders {
+		msg.WriteString(fmt.Sprintf("%d. %s\n   %s · %s", i+1, order.Title, formatRoute(order), orderStatusTitles[order.Status]))
+		if order.ModerationStatus != models.ModerationApproved {
+			msg.WriteString(" · " + moderationTitles[order.ModerationStatus])
+		}
+		if s, ok := stats[order.UUID]; ok {
+			msg.WriteString(fmt.Sprintf("\n   👁 %d · 📞 %d", s.Views, s.ContactReveals))
+		}
+		msg.WriteString("\n\n")
+
+		id := order.UUID.String()
+		var row telebot.Row
+		if order.Status == models.OrderInProgress {
+			row = append(row, markup.Data(fmt.Sprintf("✅ Выполнен №%d", i+1), btnCompleteOrder.Unique, id))
+		}
+		if order.Status == models.OrderOpen || order.Status == models.OrderInProgress {
+			row = append(row, markup.Data(fmt.Sprintf("❌ Отменить №%d", i+1), btnCancelOrder.Unique, id))
+		}
+		if order.CarrierUUID != nil && order.Status != models.OrderCancelled {
+			row = append(row, markup.Data(fmt.Sprintf("📄 ТТН №%d", i+1), btnWaybill.Unique, id+"|"+string(waybill.TTN)))
+		}
+		if order.Status == models.OrderOpen && order.PhotosCount < service.MaxOrderPhotos {
+			row = append(row, markup.Data(fmt.Sprintf("📷 Фото №%d", i+1), btnOrderPhotos.Unique, id))
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return strings.TrimRight(msg.String(), "\n"), nil, nil
+	}
+	markup.Inline(rows...)
+
+	return strings.TrimRight(msg.String(), "\n"), markup, nil
+}
+
+func (b *DriverBot) handleCompleteOrder(c telebot.Context) error {
+	return b.closeOrder(c, models.OrderCompleted)
+}
+
+func (b *DriverBot) handleCancelOrder(c telebot.Context) error {
+	return b.closeOrder(c, models.OrderCancelled)
+}
+
+// closeOrder finishes an order of the sender. Completing an order asks both
+// the customer and the driver to rate each other.
+func (b *DriverBot) closeOrder(c telebot.Context, status models.OrderStatus) error {
+	order, err := b.service.GetOrder(b.ctx, c.Data())
+	if err != nil || order == nil || order.Customer == nil ||
+		order.Customer.TelegramID == nil || *order.Customer.TelegramID != c.Sender().ID {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ не найден."})
+	}
+
+	allowed := order.Status == models.OrderInProgress ||
+		(status == models.OrderCancelled && order.Status == models.OrderOpen)
+	if !allowed {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ уже закрыт."})
+	}
+
+	if _, err := b.service.UpdateOrder(b.actorContext(c), c.Data(), models.UpdateOrderInput{Status: &status}); err != nil {
+		log.Printf("Failed to close order %s: %v", c.Data(), err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Не удалось обновить заказ."})
+	}
+	c.Respond(&telebot.CallbackResponse{Text: "Статус заказа: " + orderStatusTitles[status]})
+
+	if text, markup, err := b.renderMyOrders(*order.Customer); err == nil {
+		if err := c.Edit(text, markup); err != nil && !errors.Is(err, telebot.ErrSameMessageContent) {
+			log.Printf("Failed to refresh orders list: %v", err)
+		}
+	}
+
+	if order.CarrierUUID == nil {
+		return nil
+	}
+	carrier, err := b.service.GetCustomer(b.ctx, order.CarrierUUID.String())
+	if err != nil || carrier == nil || carrier.TelegramID == nil {
+		log.Printf("Failed to get carrier of order %s: %v", order.UUID, err)
+		return nil
+	}
+
+	if status == models.OrderCancelled {
+		msg := fmt.Sprintf("❌ Заказчик отменил заказ «%s».", order.Title)
+		if err := b.Notify(*carrier.TelegramID, msg); err != nil {
+			log.Printf("Failed to notify %d about cancelled order: %v", *carrier.TelegramID, err)
+		}
+		return nil
+	}
+
+	markup := ratingMarkup(order.UUID)
+	prompt := fmt.Sprintf("🏁 Заказ «%s» выполнен!\n\nОцените водителя %s:", order.Title, carrier.Name)
+	if err := c.Send(prompt, markup); err != nil {
+		log.Printf("Failed to ask %d for a review: %v", c.Sender().ID, err)
+	}
+	prompt = fmt.Sprintf("🏁 Заказ «%s» выполнен!\n\nОцените заказчика %s:", order.Title, order.Customer.Name)
+	if err := b.Notify(*carrier.TelegramID, prompt, markup); err != nil {
+		log.Printf("Failed to ask %d for a review: %v", *carrier.TelegramID, err)
+	}
+	return nil
+}
+
+func ratingMarkup(orderID uuid.UUID) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	var row telebot.Row
+	for stars := 1; stars <= 5; stars++ {
+		row = append(row, markup.Data(fmt.Sprintf("%d ⭐", stars), btnRate.Unique, fmt.Sprintf("%s|%d", orderID, stars)))
+	}
+	markup.Inline(row)
+	return markup
+}
+
+// handleRate stores the rating and offers to add a comment
+func (b *DriverBot) handleRate(c telebot.Context) error {
+	id, starsText, _ := strings.Cut(c.Data(), "|")
+	orderID, err := uuid.Parse(id)
+	stars, convErr := strconv.Atoi(starsText)
+	if err != nil || convErr != nil {
+		return c.Respond()
+	}
+
+	author, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || author == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Профиль не найден."})
+	}
+
+	review, err := b.service.CreateReview(b.ctx, models.CreateReviewInput{
+		OrderUUID:  orderID,
+		AuthorUUID: author.UUID,
+		Rating:     stars,
+	})
+	switch {
+	case errors.Is(err, service.ErrAlreadyReviewed):
+		c.Respond(&telebot.CallbackResponse{Text: "Вы уже оценили этот заказ."})
+		_, err := b.bot.EditReplyMarkup(c.Message(), nil)
+		return err
+	case err != nil:
+		log.Printf("Failed to save review of %s by %d: %v", orderID, c.Sender().ID, err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Не удалось сохранить оценку."})
+	}
+	c.Respond()
+
+	b.mu.Lock()
+	b.comments[c.Sender().ID] = review.UUID
+	b.mu.Unlock()
+
+	msg := fmt.Sprintf("Спасибо! Ваша оценка для %s: %s\n\nНапишите комментарий к отзыву одним сообщением или отправьте /skip.",
+		review.TargetName, strings.Repeat("⭐", review.Rating))
+	return c.Edit(msg)
+}
+
+// captureReviewComment saves a text reply as the comment of the sender's last review
+func (b *DriverBot) captureReviewComment(c telebot.Context) (bool, error) {
+	b.mu.Lock()
+	reviewID, ok := b.comments[c.Sender().ID]
+	delete(b.comments, c.Sender().ID)
+	b.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	if _, err := b.service.SetReviewComment(b.ctx, reviewID, c.Text()); err != nil {
+		log.Printf("Failed to save comment of review %s: %v", reviewID, err)
+		return true, c.Send("Не удалось сохранить комментарий.")
+	}
+	return true, c.Send("✅ Отзыв сохранён. Спасибо!")
+}
+
+func (b *DriverBot) handleSkip(c telebot.Context) error {
+	if handled, err := b.skipDraftStep(c); handled {
+		return err
+	}
+
+	b.mu.Lock()
+	_, ok := b.comments[c.Sender().ID]
+	delete(b.comments, c.Sender().ID)
+	b.mu.Unlock()
+
+	if !ok {
+		return c.Send("Нечего пропускать.")
+	}
+	return c.Send("✅ Отзыв сохранён без комментария.")
+}
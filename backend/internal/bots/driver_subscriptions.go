@@ -0,0 +1,132 @@
+package bots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+// NotifySavedSearches is the outbox handler that sends a newly published
+// order to the drivers whose saved searches match it. A driver gets an order
+// once, however many of their searches match and however often the event is
+// retried; only the failed sends are repeated.
+func (b *DriverBot) NotifySavedSearches(ctx context.Context, event models.DomainEvent) error {
+	order, err := service.NewlyPublished(event)
+	if err != nil || order == nil {
+		return err
+	}
+
+	recipients, err := b.matchSavedSearches(ctx, order.UUID)
+	if err != nil || len(recipients) == 0 {
+		return err
+	}
+
+	// The customer is not told about their own order
+	if customer, err := b.service.GetCustomer(ctx, order.CustomerUUID.String()); err == nil && customer != nil && customer.TelegramID != nil {
+		delete(recipients, *customer.TelegramID)
+	}
+
+	limiter := time.NewTicker(time.Second / broadcastRate)
+	defer limiter.Stop()
+
+	var failed int
+	var lastErr error
+	for telegramID, query := range recipients {
+		if ban, err := b.service.GetActiveBanByTelegramID(ctx, telegramID); err != nil || ban != nil {
+			continue
+		}
+		claimed, err := b.service.ClaimSearchNotification(ctx, telegramID, order.UUID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			b.releaseSearchNotification(ctx, telegramID, order.UUID)
+			return ctx.Err()
+		case <-limiter.C:
+		}
+
+		err = b.sendSearchMatch(telegramID, query, *order)
+		switch {
+		case err == nil:
+		case errors.Is(err, telebot.ErrBlockedByUser),
+			errors.Is(err, telebot.ErrUserIsDeactivated),
+			errors.Is(err, telebot.ErrChatNotFound):
+			if err := b.service.SetCustomerBotBlocked(context.WithoutCancel(ctx), telegramID, true); err != nil {
+				log.Printf("Saved searches: %v", err)
+			}
+		default:
+			b.releaseSearchNotification(ctx, telegramID, order.UUID)
+			failed, lastErr = failed+1, err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to notify %d drivers: %w", failed, lastErr)
+	}
+	return nil
+}
+
+// matchSavedSearches returns the drivers with a saved search the order
+// matches, each with the first matching query. The order is matched with the
+// same query as the search itself; searches with the same text are matched once.
+func (b *DriverBot) matchSavedSearches(ctx context.Context, orderUUID uuid.UUID) (map[int64]string, error) {
+	searches, err := b.service.ListSubscribedSearches(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byQuery := make(map[string][]int64)
+	var queries []string
+	for _, search := range searches {
+		if _, ok := byQuery[search.Query]; !ok {
+			queries = append(queries, search.Query)
+		}
+		byQuery[search.Query] = append(byQuery[search.Query], search.TelegramID)
+	}
+
+	recipients := make(map[int64]string)
+	for _, query := range queries {
+		filter := parseSearchQuery(query)
+		filter.UUIDs = []uuid.UUID{orderUUID}
+		filter.Page, filter.Limit = 1, 1
+		orders, _, err := b.service.ListOrders(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(orders) == 0 {
+			continue
+		}
+		for _, telegramID := range byQuery[query] {
+			if _, ok := recipients[telegramID]; !ok {
+				recipients[telegramID] = query
+			}
+		}
+	}
+	return recipients, nil
+}
+
+func (b *DriverBot) sendSearchMatch(telegramID int64, query string, order models.Order) error {
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.URL("📦 Открыть заказ", b.orderDeepLink(order))))
+
+	text := fmt.Sprintf("🔔 Новый заказ по подписке «%s»\n\n%s", query, formatOrderCard(order))
+	_, err := b.bot.Send(telebot.ChatID(telegramID), text, markup)
+	return err
+}
+
+func (b *DriverBot) releaseSearchNotification(ctx context.Context, telegramID int64, orderUUID uuid.UUID) {
+	if err := b.service.ReleaseSearchNotification(context.WithoutCancel(ctx), telegramID, orderUUID); err != nil {
+		log.Printf("Saved searches: %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+// Steps of the order creation wizard, in the order they are asked
+type wizardStep int
+
+const (
+	stepTitle wizardStep = iota
+	stepFrom
+	stepTo
+	stepStops
+	stepWeight
+	stepDimensions
+	stepTags
+	stepDate
+	stepPrice
+	stepDescription
+)
+
+// orderDraft is an order being filled in step by step
+type orderDraft struct {
+	step  wizardStep
+	input models.CreateOrderInput
+	stops []models.OrderStop // between the origin and the destination
+}
+
+// optionalSteps can be answered with /skip
+var optionalSteps = map[wizardStep]bool{
+	stepStops:       true,
+	stepDimensions:  true,
+	stepTags:        true,
+	stepDate:        true,
+	stepDescription: true,
+}
+
+func (b *DriverBot) handleCreateOrder(c telebot.Context) error {
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil {
+		return c.Send("Произошла ошибка. Попробуйте позже.")
+	}
+	if customer == nil {
+		return c.Send("Сначала зарегистрируйтесь через /start.")
+	}
+
+	b.mu.Lock()
+	delete(b.chats, c.Sender().ID)
+	delete(b.comments, c.Sender().ID)
+	delete(b.photos, c.Sender().ID)
+	b.drafts[c.Sender().ID] = &orderDraft{input: models.CreateOrderInput{CustomerUUID: customer.UUID}}
+	b.mu.Unlock()
+
+	return c.Send("📝 Создание нового заказа\n\nОтменить в любой момент: /cancel\n\n" + wizardPrompt(stepTitle))
+}
+
+func (b *DriverBot) handleCancel(c telebot.Context) error {
+	b.mu.Lock()
+	_, ok := b.drafts[c.Sender().ID]
+	delete(b.drafts, c.Sender().ID)
+	b.mu.Unlock()
+
+	if !ok {
+		return c.Send("Нечего отменять.")
+	}
+	return c.Send("❌ Создание заказа отменено.")
+}
+
+// captureOrderDraft feeds a text message to the sender's order wizard
+func (b *DriverBot) captureOrderDraft(c telebot.Context) (bool, error) {
+	b.mu.Lock()
+	draft, ok := b.drafts[c.Sender().ID]
+	b.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	if err := parseWizardAnswer(draft, strings.TrimSpace(c.Text())); err != nil {
+		return true, c.Send(err.Error())
+	}
+	return true, b.advanceDraft(c, draft)
+}
+
+// skipDraftStep leaves an optional wizard answer empty; it reports false if
+// the sender has no order in progress
+func (b *DriverBot) skipDraftStep(c telebot.Context) (bool, error) {
+	b.mu.Lock()
+	draft, ok := b.drafts[c.Sender().ID]
+	b.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	if !optionalSteps[draft.step] {
+		return true, c.Send("Этот шаг обязательный.\n\n" + wizardPrompt(draft.step))
+	}
+	return true, b.advanceDraft(c, draft)
+}
+
+// advanceDraft asks the next question or creates the order after the last one
+func (b *DriverBot) advanceDraft(c telebot.Context, draft *orderDraft) error {
+	if draft.step < stepDescription {
+		draft.step++
+		prompt := wizardPrompt(draft.step)
+		if draft.step == stepPrice {
+			prompt = b.priceHint(draft.input) + prompt
+		}
+		return c.Send(prompt)
+	}
+
+	b.mu.Lock()
+	delete(b.drafts, c.Sender().ID)
+	b.mu.Unlock()
+
+	if len(draft.stops) > 0 {
+		stops := append([]models.OrderStop{{Address: *draft.input.FromLocation}}, draft.stops...)
+		draft.input.Stops = append(stops, models.OrderStop{Address: *draft.input.ToLocation})
+	}
+
+	order, err := b.service.CreateOrder(b.actorContext(c), draft.input)
+	switch {
+	case errors.Is(err, service.ErrBanned):
+		return c.Send("🚫 Ваш доступ к сервису ограничен.")
+	case err != nil:
+		log.Printf("Failed to create order for %d: %v", c.Sender().ID, err)
+		return c.Send("Произошла ошибка при создании заказа. Попробуйте еще раз: /create_order")
+	}
+
+	msg := "✅ Заказ опубликован!\n\n" + formatOrderCard(order)
+	if order.ModerationStatus == models.ModerationPending {
+		msg = "✅ Заказ создан и отправлен на модерацию.\n\n" + formatOrderCard(order)
+	}
+	if order.PriceCheck != nil && order.PriceCheck.Flag != "" {
+		msg += fmt.Sprintf("\n\n⚠️ Цена заметно отличается от похожих заказов (%s – %s), модератор проверит ее вручную.",
+			order.PriceCheck.Low, order.PriceCheck.High)
+	}
+	if err := c.Send(msg); err != nil {
+		return err
+	}
+	return b.startPhotoUpload(c, order.UUID)
+}
+
+// priceHint recommends a price for the cargo described so far, or returns "" without enough history
+func (b *DriverBot) priceHint(input models.CreateOrderInput) string {
+	estimate, err := b.service.EstimatePrice(b.ctx, models.PriceEstimateInput{
+		From:     input.FromLocation,
+		To:       input.ToLocation,
+		WeightKg: input.WeightKg,
+		LengthCm: input.LengthCm,
+		WidthCm:  input.WidthCm,
+		HeightCm: input.HeightCm,
+		Tags:     input.Tags,
+		Date:     input.AvailableFrom,
+	})
+	if err != nil {
+		log.Printf("Failed to estimate price: %v", err)
+		return ""
+	}
+	if estimate == nil {
+		return ""
+	}
+
+	hint := fmt.Sprintf("💡 Рекомендуемая цена: %s – %s (медиана %s, по %d %s",
+		estimate.Low, estimate.High, estimate.Median, estimate.Samples,
+		pluralRu(estimate.Samples, "похожему заказу", "похожим заказам", "похожим заказам"))
+	if estimate.DistanceKm != nil {
+		hint += fmt.Sprintf(", ~%.0f км", *estimate.DistanceKm)
+	}
+	return hint + ")\n\n"
+}
+
+func wizardPrompt(step wizardStep) string {
+	switch step {
+	case stepTitle:
+		return "Что везем? Кратко опишите груз, например «Перевезти холодильник»."
+	case stepFrom:
+		return "📍 Откуда? Город и адрес."
+	case stepTo:
+		return "🎯 Куда? Город и адрес."
+	case stepStops:
+		return `🛣 Есть промежуточные точки? Отправьте их по порядку маршрута, по одной в строке.
+Через «|» можно указать время и что погрузить или выгрузить:
+Тверь, ул. Ленина 1 | 12.05 10:00-14:00 | выгрузить 2 паллеты
+
+Пропустить: /skip`
+	case stepWeight:
+		return "⚖️ Вес груза в кг, например 70."
+	case stepDimensions:
+		return "📐 Габариты в см в формате ДxШxВ, например 180x60x70. Пропустить: /skip"
+	case stepTags:
+		return "🏷 Теги через запятую, например «тент, хрупкое». Пропустить: /skip"
+	case stepDate:
+		return "📅 С какой даты можно забрать груз? Формат ДД.ММ.ГГГГ. Пропустить: /skip"
+	case stepPrice:
+		return "💰 Ваша цена, например 5000. Для другой валюты укажите ее после суммы: 120000 тг, 300 Br."
+	case stepDescription:
+		return "📝 Дополнительные детали для водителя. Пропустить: /skip"
+	}
+	return ""
+}
+
+// parseWizardAnswer stores the answer to the current step or explains what is wrong with it
+func parseWizardAnswer(draft *orderDraft, text string) error {
+	if text == "" {
+		return fmt.Errorf("Пустой ответ.\n\n%s", wizardPrompt(draft.step))
+	}
+	input := &draft.input
+
+	switch draft.step {
+	case stepTitle:
+		input.Title = text
+	case stepFrom:
+		input.FromLocation = &text
+	case stepTo:
+		input.ToLocation = &text
+	case stepStops:
+		stops, err := parseStops(text, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		draft.stops = stops
+	case stepWeight:
+		weight, err := parsePositive(text)
+		if err != nil {
+			return fmt.Errorf("Укажите вес числом, например 70.")
+		}
+		input.WeightKg = weight
+	case stepDimensions:
+		parts := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return r == 'x' || r == 'х' || r == '*' || r == ' ' })
+		if len(parts) != 3 {
+			return fmt.Errorf("Укажите габариты в формате ДxШxВ, например 180x60x70, или /skip.")
+		}
+		dims := make([]float64, 3)
+		for i, part := range parts {
+			value, err := parsePositive(part)
+			if err != nil {
+				return fmt.Errorf("Укажите габариты в формате ДxШxВ, например 180x60x70, или /skip.")
+			}
+			dims[i] = value
+		}
+		input.LengthCm, input.WidthCm, input.HeightCm = &dims[0], &dims[1], &dims[2]
+	case stepTags:
+		for _, tag := range strings.Split(text, ",") {
+			if tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")); tag != "" {
+				input.Tags = append(input.Tags, tag)
+			}
+		}
+	case stepDate:
+		date, err := time.Parse("02.01.2006", text)
+		if err != nil {
+			return fmt.Errorf("Укажите дату в формате ДД.ММ.ГГГГ, например %s, или /skip.", time.Now().Format("02.01.2006"))
+		}
+		input.AvailableFrom = &date
+	case stepPrice:
+		price, err := parsePrice(text)
+		if err != nil || price.IsZero() {
+			return fmt.Errorf("Укажите цену числом, например 5000 или 120000 тг.")
+		}
+		input.Price = price
+	case stepDescription:
+		input.Description = &text
+	}
+	return nil
+}
+
+func parsePositive(text string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(text), ",", "."), " ", ""), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	return value, nil
+}
+
+var stopWindowRe = regexp.MustCompile(`^(\d{1,2}\.\d{1,2}(?:\.\d{4})?)\s+(\d{1,2}:\d{2})\s*[-–]\s*(\d{1,2}:\d{2})$`)
+
+// parseStops reads intermediate stops, one per line: "address | 12.05 10:00-14:00 | notes",
+// where the time window and the notes are optional. A date without a year is
+// the nearest one from now on.
+func parseStops(text string, now time.Time) ([]models.OrderStop, error) {
+	var stops []models.OrderStop
+	for _, line := range strings.Split(text, "\n") {
+		parts := strings.Split(line, "|")
+		stop := models.OrderStop{Address: strings.TrimSpace(parts[0])}
+		if stop.Address == "" {
+			continue
+		}
+		for _, part := range parts[1:] {
+			part = strings.TrimSpace(part)
+			if m := stopWindowRe.FindStringSubmatch(part); m != nil {
+				date, withYear := m[1], strings.Count(m[1], ".") == 2
+				if !withYear {
+					date += fmt.Sprintf(".%d", now.Year())
+				}
+				from, errFrom := time.Parse("2.1.2006 15:04", date+" "+m[2])
+				to, errTo := time.Parse("2.1.2006 15:04", date+" "+m[3])
+				if !withYear && errFrom == nil && errTo == nil && to.Before(now.AddDate(0, 0, -1)) {
+					from, to = from.AddDate(1, 0, 0), to.AddDate(1, 0, 0)
+				}
+				if errFrom != nil || errTo != nil || to.Before(from) {
+					return nil, fmt.Errorf("Неверное время у точки «%s». Пример: 12.05 10:00-14:00", stop.Address)
+				}
+				stop.WindowFrom, stop.WindowTo = &from, &to
+			} else if part != "" {
+				stop.Notes = &part
+			}
+		}
+		stops = append(stops, stop)
+	}
+	if len(stops)+2 > service.MaxOrderStops {
+		return nil, fmt.Errorf("Слишком много точек: не больше %d промежуточных.", service.MaxOrderStops-2)
+	}
+	return stops, nil
+}
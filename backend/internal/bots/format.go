@@ -0,0 +1,163 @@
+package bots
+
+import (
+	"fmt"
+	"strings"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// formatOrderCard renders an order as a multi-line card for chats and inline results
+func formatOrderCard(order models.Order) string {
+	var msg strings.Builder
+
+	msg.WriteString(fmt.Sprintf("📦 %s\n", order.Title))
+	msg.WriteString(fmt.Sprintf("⚖️ Вес: %.1f кг\n", order.WeightKg))
+	msg.WriteString(fmt.Sprintf("💰 Цена: %s\n", formatPrice(order)))
+	if hasStopDetails(order) {
+		msg.WriteString("🛣 Маршрут:\n")
+		for i, stop := range order.Stops {
+			msg.WriteString(fmt.Sprintf("%d. %s\n", i+1, formatStop(stop)))
+		}
+	} else {
+		if order.FromLocation != nil {
+			msg.WriteString(fmt.Sprintf("📍 Откуда: %s\n", *order.FromLocation))
+		}
+		if order.ToLocation != nil {
+			msg.WriteString(fmt.Sprintf("🎯 Куда: %s\n", *order.ToLocation))
+		}
+	}
+	if order.AvailableFrom != nil {
+		msg.WriteString(fmt.Sprintf("📅 С %s\n", order.AvailableFrom.Format("02.01.2006")))
+	}
+	if len(order.Tags) > 0 {
+		msg.WriteString(fmt.Sprintf("🏷 %s\n", strings.Join(order.Tags, ", ")))
+	}
+	if order.Description != nil && *order.Description != "" {
+		msg.WriteString(fmt.Sprintf("\n%s\n", *order.Description))
+	}
+
+	return strings.TrimRight(msg.String(), "\n")
+}
+
+// formatPrice shows the price with the price per kilogram, e.g. "5 000 ₽ (71,43 ₽/кг)"
+func formatPrice(order models.Order) string {
+	perKg, ok := order.PricePerKg()
+	if !ok || order.Price.IsZero() {
+		return order.Price.String()
+	}
+	return fmt.Sprintf("%s (%s/кг)", order.Price, perKg)
+}
+
+// currencyWords maps what people type after an amount to a currency
+var currencyWords = map[string]models.Currency{
+	"₽": models.RUB, "р": models.RUB, "руб": models.RUB, "rub": models.RUB,
+	"₸": models.KZT, "тг": models.KZT, "тенге": models.KZT, "kzt": models.KZT,
+	"br": models.BYN, "byn": models.BYN, "бел.руб": models.BYN,
+}
+
+// parsePrice reads an amount with an optional currency, e.g. "5000", "5 000 ₽"
+// or "120000 тг". The currency is left empty if it is not given.
+func parsePrice(text string) (models.Money, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	// The longest matching word wins: "бел.руб" over "руб"
+	currency, suffix := models.Currency(""), ""
+	for word, value := range currencyWords {
+		if strings.HasSuffix(text, word) && len(word) > len(suffix) {
+			currency, suffix = value, word
+		}
+	}
+	return models.ParseMoney(strings.TrimSuffix(text, suffix), currency)
+}
+
+// formatRoute returns a short "from → to" line for list items and titles
+func formatRoute(order models.Order) string {
+	from, to := "?", "?"
+	if order.FromLocation != nil {
+		from = *order.FromLocation
+	}
+	if order.ToLocation != nil {
+		to = *order.ToLocation
+	}
+	if len(order.Stops) > 2 {
+		return fmt.Sprintf("%s → %s (+%d %s)", from, to, len(order.Stops)-2,
+			pluralRu(len(order.Stops)-2, "точка", "точки", "точек"))
+	}
+	return from + " → " + to
+}
+
+// hasStopDetails reports whether an order needs the full list of stops
+// rather than the from and to lines
+func hasStopDetails(order models.Order) bool {
+	if len(order.Stops) > 2 {
+		return true
+	}
+	for _, stop := range order.Stops {
+		if stop.WindowFrom != nil || stop.Notes != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// formatStop renders a stop as "Тверь, ул. Ленина 1 (12.05 10:00–14:00) — выгрузить 2 паллеты"
+func formatStop(stop models.OrderStop) string {
+	text := stop.Address
+	if stop.WindowFrom != nil && stop.WindowTo != nil {
+		end := stop.WindowTo.Format("15:04")
+		if stop.WindowTo.Format("02.01") != stop.WindowFrom.Format("02.01") {
+			end = stop.WindowTo.Format("02.01 15:04")
+		}
+		text += fmt.Sprintf(" (%s–%s)", stop.WindowFrom.Format("02.01 15:04"), end)
+	}
+	if stop.Notes != nil {
+		text += " — " + *stop.Notes
+	}
+	return text
+}
+
+// formatBan tells a banned user why and for how long their access is restricted
+func formatBan(ban models.Ban) string {
+	msg := "🚫 Ваш доступ к сервису ограничен"
+	if ban.ExpiresAt != nil {
+		msg += fmt.Sprintf(" до %s (UTC)", ban.ExpiresAt.Format("02.01.2006 15:04"))
+	}
+	return msg + fmt.Sprintf(".\nПричина: %s", ban.Reason)
+}
+
+// formatReputation renders a customer's average rating, e.g. "⭐ 4.8 (12 отзывов)"
+func formatReputation(customer models.Customer) string {
+	if customer.Rating == nil || customer.ReviewsCount == 0 {
+		return "⭐ Нет отзывов"
+	}
+	return fmt.Sprintf("⭐ %.1f (%d %s)", *customer.Rating, customer.ReviewsCount,
+		pluralRu(customer.ReviewsCount, "отзыв", "отзыва", "отзывов"))
+}
+
+// pluralRu picks the Russian word form for a count: 1 отзыв, 2 отзыва, 5 отзывов
+func pluralRu(n int, one, few, many string) string {
+	n %= 100
+	switch {
+	case n >= 11 && n <= 14:
+		return many
+	case n%10 == 1:
+		return one
+	case n%10 >= 2 && n%10 <= 4:
+		return few
+	default:
+		return many
+	}
+}
+
+// formatPriceCheck warns moderators about a price far from similar orders, or returns ""
+func formatPriceCheck(order models.Order) string {
+	if order.PriceCheck == nil || order.PriceCheck.Flag == "" {
+		return ""
+	}
+	what := "ниже"
+	if order.PriceCheck.Flag == models.PriceTooHigh {
+		what = "выше"
+	}
+	return fmt.Sprintf("⚠️ Цена %s рынка: рекомендуемо %s – %s", what, order.PriceCheck.Low, order.PriceCheck.High)
+}
@@ -0,0 +1,127 @@
+package bots
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+)
+
+const inlinePageSize = 10
+
+var (
+	weightRe     = regexp.MustCompile(`(?i)^(\d+(?:[.,]\d+)?)\s*(кг|kg|т|t)$`)
+	weightGlueRe = regexp.MustCompile(`(\d)\s+(кг|kg|т|t)(\s|$)`)
+	priceRe      = regexp.MustCompile(`(?i)^\d+(?:[.,]\d{1,2})?(₽|р|руб|rub|₸|тг|тенге|kzt|br|byn)$`)
+	priceGlueRe  = regexp.MustCompile(`(?i)(\d)\s+(₽|р|руб|rub|₸|тг|тенге|kzt|br|byn)(\s|$)`)
+	routeRe      = regexp.MustCompile(`\s*(?:->|→)\s*|\s+[-—]\s+`) // keeps "Ростов-на-Дону" intact
+)
+
+// parseSearchQuery turns free text like "Москва - Казань 500кг #тент" into an
+// order filter. A weight is treated as the driver's capacity (upper bound),
+// a price like "5000₽" or "120000тг" as the least they accept in that currency,
+// "A - B" as a route and a single place name matches either end of the route.
+func parseSearchQuery(text string) models.OrderFilter {
+	filter := models.OrderFilter{}
+
+	// Glue "500 кг" and "5000 ₽" into single tokens before splitting
+	text = weightGlueRe.ReplaceAllString(text, "$1$2$3")
+	text = priceGlueRe.ReplaceAllString(text, "$1$2$3")
+
+	var place []string
+	for _, token := range strings.Fields(text) {
+		if m := weightRe.FindStringSubmatch(token); m != nil {
+			value, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+			if err == nil {
+				unit := strings.ToLower(m[2])
+				if unit == "т" || unit == "t" {
+					value *= 1000
+				}
+				filter.MaxWeight = value
+			}
+			continue
+		}
+		if priceRe.MatchString(token) {
+			if price, err := parsePrice(token); err == nil {
+				filter.MinPrice = price.Amount
+				filter.Currency = price.Currency
+			}
+			continue
+		}
+		if strings.HasPrefix(token, "#") && len(token) > 1 {
+			filter.Tags = append(filter.Tags, strings.TrimPrefix(token, "#"))
+			continue
+		}
+		place = append(place, token)
+	}
+
+	location := strings.Join(place, " ")
+	if parts := routeRe.Split(location, 2); len(parts) == 2 {
+		filter.From = strings.TrimSpace(parts[0])
+		filter.To = strings.TrimSpace(parts[1])
+	} else {
+		filter.Location = strings.TrimSpace(location)
+	}
+
+	return filter
+}
+
+// orderDeepLink returns a t.me link that opens the order card in the bot
+func (b *DriverBot) orderDeepLink(order models.Order) string {
+	return fmt.Sprintf("https://t.me/%s?start=order_%s", b.bot.Me.Username, order.UUID)
+}
+
+func (b *DriverBot) handleQuery(c telebot.Context) error {
+	query := c.Query()
+
+	filter := parseSearchQuery(query.Text)
+	filter.Limit = inlinePageSize
+	filter.Page = 1
+	if page, err := strconv.Atoi(query.Offset); err == nil && page > 0 {
+		filter.Page = page
+	}
+
+	orders, total, err := b.service.ListOrders(b.ctx, filter)
+	if err != nil {
+		log.Printf("Inline query %q failed: %v", query.Text, err)
+		return c.Answer(&telebot.QueryResponse{CacheTime: 10})
+	}
+
+	results := make(telebot.Results, 0, len(orders))
+	for _, order := range orders {
+		markup := &telebot.ReplyMarkup{}
+		markup.Inline(markup.Row(markup.URL("Подробнее в боте", b.orderDeepLink(order))))
+
+		description := fmt.Sprintf("%s • %.0f кг • %s", formatRoute(order), order.WeightKg, order.Price)
+		if order.Customer != nil && order.Customer.ReviewsCount > 0 {
+			description += " • " + formatReputation(*order.Customer)
+		}
+
+		result := &telebot.ArticleResult{
+			Title:       order.Title,
+			Description: description,
+			Text:        formatOrderCard(order),
+		}
+		result.SetResultID(order.UUID.String())
+		result.ReplyMarkup = markup
+		results = append(results, result)
+	}
+
+	response := &telebot.QueryResponse{
+		Results:   results,
+		CacheTime: 30,
+	}
+	if filter.Page*filter.Limit < total {
+		response.NextOffset = strconv.Itoa(filter.Page + 1)
+	}
+	if len(results) == 0 && filter.Page == 1 {
+		response.SwitchPMText = "Заказов не найдено — открыть бота"
+		response.SwitchPMParameter = "search"
+	}
+
+	return c.Answer(response)
+}
@@ -0,0 +1,104 @@
+package bots
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/telebot.v3"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+	"gruzy-ryadom/internal/waybill"
+)
+
+var btnWaybill = telebot.Btn{Unique: "waybill"}
+
+var waybillTitles = map[waybill.Form]string{
+	waybill.TTN: "Транспортная накладная",
+	waybill.CMR: "Накладная CMR",
+}
+
+// waybillMarkup offers both waybill forms of a taken order, data is "<uuid>|<form>"
+func waybillMarkup(orderID uuid.UUID) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data("📄 ТТН", btnWaybill.Unique, orderID.String()+"|"+string(waybill.TTN)),
+		markup.Data("🌍 CMR", btnWaybill.Unique, orderID.String()+"|"+string(waybill.CMR)),
+	))
+	return markup
+}
+
+// waybillDocument wraps a rendered waybill for sending
+func waybillDocument(order models.Order, form waybill.Form, pdf []byte) *telebot.Document {
+	return &telebot.Document{
+		File:     telebot.FromReader(bytes.NewReader(pdf)),
+		FileName: fmt.Sprintf("%s-%s.pdf", form, service.WaybillNumber(order)),
+		MIME:     "application/pdf",
+		Caption:  fmt.Sprintf("📄 %s № %s\n«%s»", waybillTitles[form], service.WaybillNumber(order), order.Title),
+	}
+}
+
+// handleWaybill sends a waybill to the customer or the carrier of an order
+func (b *DriverBot) handleWaybill(c telebot.Context) error {
+	id, formName, _ := strings.Cut(c.Data(), "|")
+	form, err := waybill.ParseForm(formName)
+	if err != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Неизвестная форма накладной."})
+	}
+
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil || customer == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Сначала зарегистрируйтесь через /start.", ShowAlert: true})
+	}
+
+	order, err := b.service.GetOrder(b.ctx, id)
+	if err != nil || order == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ не найден."})
+	}
+
+	pdf, err := b.service.Waybill(b.ctx, id, &customer.UUID, form)
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound):
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ не найден."})
+	case errors.Is(err, service.ErrNoCarrier):
+		return c.Respond(&telebot.CallbackResponse{Text: "Заказ еще не взял водитель.", ShowAlert: true})
+	case err != nil:
+		log.Printf("Failed to render waybill of %s: %v", id, err)
+		return c.Respond(&telebot.CallbackResponse{Text: "Не удалось сформировать накладную."})
+	}
+
+	c.Respond()
+	msg := waybillDocument(*order, form, pdf)
+	if customer.VehiclePlate == nil && order.CarrierUUID != nil && *order.CarrierUUID == customer.UUID {
+		msg.Caption += "\n\n🚚 Укажите машину командой /vehicle, чтобы она попала в накладную."
+	}
+	return c.Send(msg)
+}
+
+func (b *DriverBot) handleVehicle(c telebot.Context) error {
+	customer, err := b.service.GetCustomerByTelegramID(b.ctx, c.Sender().ID)
+	if err != nil {
+		return c.Send("Произошла ошибка. Попробуйте позже.")
+	}
+	if customer == nil {
+		return c.Send("Сначала зарегистрируйтесь через /start.")
+	}
+
+	model, plate, ok := strings.Cut(c.Message().Payload, ",")
+	model, plate = strings.TrimSpace(model), strings.ToUpper(strings.Join(strings.Fields(plate), ""))
+	if !ok || model == "" || plate == "" {
+		return c.Send("Укажите марку и госномер через запятую, например:\n/vehicle ГАЗель Next, А123БВ777")
+	}
+
+	if _, err := b.service.UpdateCustomer(b.actorContext(c), customer.UUID.String(), models.UpdateCustomerInput{
+		VehicleModel: &model,
+		VehiclePlate: &plate,
+	}); err != nil {
+		log.Printf("Failed to update vehicle of %s: %v", customer.UUID, err)
+		return c.Send("Произошла ошибка. Попробуйте позже.")
+	}
+	return c.Send(fmt.Sprintf("🚚 Машина сохранена: %s, %s. Она будет указана в накладных.", model, plate))
+}
@@ -0,0 +1,271 @@
+// Package charts renders simple PNG bar charts for the admin bot without
+// any external tools, using the Go fonts for Cyrillic labels.
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	width   = 1000
+	height  = 560
+	padding = 40
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	axisColor  = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	gridColor  = color.RGBA{0xe6, 0xe6, 0xe6, 0xff}
+	barColor   = color.RGBA{0x2f, 0x80, 0xed, 0xff}
+	textColor  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+)
+
+// Point is a labelled value of a chart
+type Point struct {
+	Label string
+	Value float64
+}
+
+type faces struct {
+	title, label, small font.Face
+}
+
+var (
+	loadOnce   sync.Once
+	fontFaces  faces
+	fontLoaded error
+)
+
+func loadFaces() (faces, error) {
+	loadOnce.Do(func() {
+		regular, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			fontLoaded = err
+			return
+		}
+		bold, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			fontLoaded = err
+			return
+		}
+
+		newFace := func(f *opentype.Font, size float64) font.Face {
+			face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+			if err != nil && fontLoaded == nil {
+				fontLoaded = err
+			}
+			return face
+		}
+		fontFaces = faces{
+			title: newFace(bold, 24),
+			label: newFace(regular, 16),
+			small: newFace(regular, 13),
+		}
+	})
+	return fontFaces, fontLoaded
+}
+
+// Bars renders a vertical bar chart, e.g. a time series or a histogram
+func Bars(title string, points []Point) ([]byte, error) {
+	f, err := loadFaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fonts: %w", err)
+	}
+
+	img := newCanvas()
+	drawText(img, f.title, padding, padding, title)
+
+	left, top := padding+60, padding+40
+	right, bottom := width-padding, height-padding-40
+	maxValue := niceMax(maxOf(points))
+
+	drawGrid(img, f.small, left, top, right, bottom, maxValue)
+
+	if len(points) > 0 {
+		slot := float64(right-left) / float64(len(points))
+		barWidth := int(math.Max(1, slot*0.7))
+		// Skip labels so that they do not overlap
+		every := int(math.Ceil(float64(len(points)) * 70 / float64(right-left)))
+
+		for i, p := range points {
+			x := left + int(slot*float64(i)+(slot-float64(barWidth))/2)
+			h := int(float64(bottom-top) * p.Value / maxValue)
+			fill(img, image.Rect(x, bottom-h, x+barWidth, bottom), barColor)
+
+			if i%every == 0 {
+				w := textWidth(f.small, p.Label)
+				drawText(img, f.small, x+barWidth/2-w/2, bottom+20, p.Label)
+			}
+			if len(points) <= 31 && p.Value > 0 {
+				value := formatValue(p.Value)
+				w := textWidth(f.small, value)
+				drawText(img, f.small, x+barWidth/2-w/2, bottom-h-6, value)
+			}
+		}
+	}
+
+	return encode(img)
+}
+
+// HorizontalBars renders labelled horizontal bars, e.g. a top-N ranking
+func HorizontalBars(title string, points []Point) ([]byte, error) {
+	f, err := loadFaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fonts: %w", err)
+	}
+
+	img := newCanvas()
+	drawText(img, f.title, padding, padding, title)
+
+	top, bottom := padding+40, height-padding
+	labelWidth := 0
+	for _, p := range points {
+		if w := textWidth(f.label, p.Label); w > labelWidth {
+			labelWidth = w
+		}
+	}
+	if labelWidth > width/2 {
+		labelWidth = width / 2
+	}
+	left, right := padding+labelWidth+15, width-padding-80
+	maxValue := maxOf(points)
+	if maxValue == 0 {
+		maxValue = 1
+	}
+
+	if len(points) > 0 {
+		slot := float64(bottom-top) / float64(len(points))
+		barHeight := int(math.Min(48, slot*0.7))
+
+		for i, p := range points {
+			y := top + int(slot*float64(i)+(slot-float64(barHeight))/2)
+			w := int(float64(right-left) * p.Value / maxValue)
+			fill(img, image.Rect(left, y, left+w, y+barHeight), barColor)
+
+			baseline := y + barHeight/2 + 6
+			drawText(img, f.label, padding, baseline, truncate(f.label, p.Label, labelWidth))
+			drawText(img, f.label, left+w+8, baseline, formatValue(p.Value))
+		}
+	}
+	line(img, left, top, left, bottom, axisColor)
+
+	return encode(img)
+}
+
+func newCanvas() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+	return img
+}
+
+func drawGrid(img *image.RGBA, face font.Face, left, top, right, bottom int, maxValue float64) {
+	const steps = 5
+	for i := 0; i <= steps; i++ {
+		y := bottom - (bottom-top)*i/steps
+		c := gridColor
+		if i == 0 {
+			c = axisColor
+		}
+		line(img, left, y, right, y, c)
+
+		label := formatValue(maxValue * float64(i) / steps)
+		drawText(img, face, left-10-textWidth(face, label), y+5, label)
+	}
+	line(img, left, top, left, bottom, axisColor)
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+func line(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	if y0 == y1 {
+		fill(img, image.Rect(x0, y0, x1+1, y0+1), c)
+	} else {
+		fill(img, image.Rect(x0, y0, x0+1, y1+1), c)
+	}
+}
+
+func drawText(img *image.RGBA, face font.Face, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  &image.Uniform{textColor},
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+func textWidth(face font.Face, text string) int {
+	return font.MeasureString(face, text).Ceil()
+}
+
+// truncate shortens text with an ellipsis to fit into maxWidth pixels
+func truncate(face font.Face, text string, maxWidth int) string {
+	if textWidth(face, text) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && textWidth(face, string(runes)+"…") > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+func maxOf(points []Point) float64 {
+	max := 0.0
+	for _, p := range points {
+		if p.Value > max {
+			max = p.Value
+		}
+	}
+	return max
+}
+
+// niceMax rounds the axis maximum up to 1, 2 or 5 times a power of ten
+func niceMax(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+func formatValue(v float64) string {
+	switch {
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e4:
+		return fmt.Sprintf("%.0fk", v/1e3)
+	case v == math.Trunc(v):
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return fmt.Sprintf("%.1f", v)
+	}
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
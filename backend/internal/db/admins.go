@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// Admins methods
+func (db *DB) ListAdmins(ctx context.Context) ([]models.Admin, error) {
+	rows, err := db.QueryContext(ctx, "SELECT telegram_id, role, added_by, created_at FROM admins ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query admins: %w", err)
+	}
+	defer rows.Close()
+
+	var admins []models.Admin
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan admin: %w", err)
+		}
+		admins = append(admins, admin)
+	}
+
+	return admins, nil
+}
+
+func (db *DB) GetAdmin(ctx context.Context, telegramID int64) (*models.Admin, error) {
+	query := "SELECT telegram_id, role, added_by, created_at FROM admins WHERE telegram_id = $1"
+
+	admin, err := scanAdmin(db.QueryRowContext(ctx, query, telegramID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+
+	return &admin, nil
+}
+
+// SaveAdmin grants a role, replacing the previous one if the user is already an admin
+func (db *DB) SaveAdmin(ctx context.Context, telegramID int64, role models.AdminRole, addedBy int64) (models.Admin, error) {
+	query := `
+		INSERT INTO admins (telegram_id, role, added_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (telegram_id) DO UPDATE SET role = EXCLUDED.role, added_by = EXCLUDED.added_by
+		RETURNING telegram_id, role, added_by, created_at
+	`
+
+	admin, err := scanAdmin(db.QueryRowContext(ctx, query, telegramID, role, addedBy))
+	if err != nil {
+		return models.Admin{}, fmt.Errorf("failed to save admin: %w", err)
+	}
+
+	return admin, nil
+}
+
+// DeleteAdmin revokes access and reports whether the user was an admin
+func (db *DB) DeleteAdmin(ctx context.Context, telegramID int64) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM admins WHERE telegram_id = $1", telegramID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete admin: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete admin: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func scanAdmin(row rowScanner) (models.Admin, error) {
+	var admin models.Admin
+	var addedBy sql.NullInt64
+
+	if err := row.Scan(&admin.TelegramID, &admin.Role, &addedBy, &admin.CreatedAt); err != nil {
+		return models.Admin{}, err
+	}
+	if addedBy.Valid {
+		admin.AddedBy = &addedBy.Int64
+	}
+
+	return admin, nil
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gruzy-ryadom/internal/models"
+)
+
+const auditColumns = `id, created_at, channel, actor_id, request_id, entity_type, entity_uuid, action, changes`
+
+// Audit log methods
+func (db *DB) InsertAudit(ctx context.Context, entry models.AuditEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_log (channel, actor_id, request_id, entity_type, entity_uuid, action, changes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = db.ExecContext(ctx, query,
+		entry.Channel, entry.ActorID, entry.RequestID, entry.EntityType, entry.EntityUUID, entry.Action, changes,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns audit entries matching the filter, newest first
+func (db *DB) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, int, error) {
+	var where strings.Builder
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where.WriteString(" AND ")
+		where.WriteString(strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
+
+	if filter.EntityType != "" {
+		add("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityUUID != nil {
+		add("entity_uuid = ?", *filter.EntityUUID)
+	}
+	if filter.ActorID != nil {
+		add("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Channel != "" {
+		add("channel = ?", filter.Channel)
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM audit_log WHERE 1=1" + where.String()
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	query := fmt.Sprintf("SELECT %s FROM audit_log WHERE 1=1%s ORDER BY id DESC LIMIT $%d OFFSET $%d",
+		auditColumns, where.String(), len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var actorID sql.NullInt64
+		var requestID sql.NullString
+		var changes []byte
+		err := rows.Scan(&entry.ID, &entry.CreatedAt, &entry.Channel, &actorID, &requestID,
+			&entry.EntityType, &entry.EntityUUID, &entry.Action, &changes)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if actorID.Valid {
+			entry.ActorID = &actorID.Int64
+		}
+		if requestID.Valid {
+			entry.RequestID = &requestID.String
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode audit changes: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, nil
+}
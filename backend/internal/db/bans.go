@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+const banColumns = `b.uuid, b.telegram_id, b.phone, b.customer_uuid, b.reason, b.expires_at, b.created_by, b.created_at`
+
+const (
+	// activeBan matches bans that are neither lifted nor expired
+	activeBan = `b.lifted_at IS NULL AND (b.expires_at IS NULL OR b.expires_at > now())`
+
+	// banMatchesCustomer matches bans of the customer aliased "c" by any of its identifiers
+	banMatchesCustomer = `(b.customer_uuid = c.uuid OR b.telegram_id = c.telegram_id
+		OR b.phone = regexp_replace(c.phone, '\D', '', 'g'))`
+
+	// customerBanned is a condition on the customer aliased "c"
+	customerBanned = `EXISTS (SELECT 1 FROM bans b WHERE ` + activeBan + ` AND ` + banMatchesCustomer + `)`
+)
+
+// Bans methods
+func (db *DB) CreateBan(ctx context.Context, input models.CreateBanInput) (models.Ban, error) {
+	query := `
+		INSERT INTO bans AS b (telegram_id, phone, customer_uuid, reason, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + banColumns
+
+	ban, err := scanBan(db.QueryRowContext(ctx, query,
+		input.TelegramID, input.Phone, input.CustomerUUID, input.Reason, input.ExpiresAt, input.CreatedBy,
+	))
+	if err != nil {
+		return models.Ban{}, fmt.Errorf("failed to create ban: %w", err)
+	}
+	return ban, nil
+}
+
+// ListActiveBans returns bans in force, newest first
+func (db *DB) ListActiveBans(ctx context.Context, limit int) ([]models.Ban, error) {
+	query := "SELECT " + banColumns + " FROM bans b WHERE " + activeBan + " ORDER BY b.created_at DESC LIMIT $1"
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bans: %w", err)
+	}
+	defer rows.Close()
+
+	var bans []models.Ban
+	for rows.Next() {
+		ban, err := scanBan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ban: %w", err)
+		}
+		bans = append(bans, ban)
+	}
+
+	return bans, nil
+}
+
+// LiftBan ends a ban early and reports whether it was in force
+func (db *DB) LiftBan(ctx context.Context, id uuid.UUID, liftedBy int64) (bool, error) {
+	query := "UPDATE bans b SET lifted_at = now(), lifted_by = $1 WHERE b.uuid = $2 AND " + activeBan
+	result, err := db.ExecContext(ctx, query, liftedBy, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to lift ban: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to lift ban: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// GetActiveBanByTelegramID returns the ban in force for a Telegram user,
+// either directly or through their customer profile, or nil
+func (db *DB) GetActiveBanByTelegramID(ctx context.Context, telegramID int64) (*models.Ban, error) {
+	query := `
+		SELECT ` + banColumns + ` FROM bans b
+		WHERE ` + activeBan + ` AND (b.telegram_id = $1 OR EXISTS (
+			SELECT 1 FROM customers c WHERE c.telegram_id = $1 AND ` + banMatchesCustomer + `
+		))
+		ORDER BY b.expires_at DESC NULLS FIRST
+		LIMIT 1
+	`
+	return db.getBan(ctx, query, telegramID)
+}
+
+// GetActiveBanByCustomer returns the ban in force for a customer, or nil
+func (db *DB) GetActiveBanByCustomer(ctx context.Context, customerUUID uuid.UUID) (*models.Ban, error) {
+	query := `
+		SELECT ` + banColumns + ` FROM bans b
+		JOIN customers c ON c.uuid = $1
+		WHERE ` + activeBan + ` AND ` + banMatchesCustomer + `
+		ORDER BY b.expires_at DESC NULLS FIRST
+		LIMIT 1
+	`
+	return db.getBan(ctx, query, customerUUID)
+}
+
+func (db *DB) getBan(ctx context.Context, query string, args ...interface{}) (*models.Ban, error) {
+	ban, err := scanBan(db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ban: %w", err)
+	}
+	return &ban, nil
+}
+
+func scanBan(row rowScanner) (models.Ban, error) {
+	var ban models.Ban
+	var telegramID sql.NullInt64
+	var phone sql.NullString
+	var customerUUID uuid.NullUUID
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&ban.UUID, &telegramID, &phone, &customerUUID, &ban.Reason, &expiresAt, &ban.CreatedBy, &ban.CreatedAt)
+	if err != nil {
+		return models.Ban{}, err
+	}
+
+	if telegramID.Valid {
+		ban.TelegramID = &telegramID.Int64
+	}
+	if phone.Valid {
+		ban.Phone = &phone.String
+	}
+	if customerUUID.Valid {
+		ban.CustomerUUID = &customerUUID.UUID
+	}
+	if expiresAt.Valid {
+		ban.ExpiresAt = &expiresAt.Time
+	}
+
+	return ban, nil
+}
@@ -0,0 +1,290 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+const broadcastColumns = `uuid, created_by, audience, region, text, entities, photo, photo_file_id,
+	status, total, sent, failed, blocked, progress_chat_id, progress_message_id, created_at, finished_at`
+
+// audienceQuery returns a query selecting the Telegram IDs of a broadcast audience.
+// Users who blocked the bot are excluded.
+func audienceQuery(audience models.BroadcastAudience, region *string) (string, []interface{}, error) {
+	base := `
+		SELECT DISTINCT c.telegram_id FROM customers c
+		WHERE c.telegram_id IS NOT NULL AND c.bot_blocked_at IS NULL
+	`
+	hasOrders := "EXISTS (SELECT 1 FROM orders o WHERE o.customer_uuid = c.uuid)"
+
+	switch audience {
+	case models.AudienceAll:
+		return base, nil, nil
+	case models.AudienceCustomers:
+		return base + " AND " + hasOrders, nil, nil
+	case models.AudienceDrivers:
+		return base + " AND NOT " + hasOrders, nil, nil
+	case models.AudienceRegion:
+		if region == nil || *region == "" {
+			return "", nil, fmt.Errorf("region is required for audience %q", audience)
+		}
+		query := `
+			SELECT DISTINCT s.telegram_id FROM saved_searches s
+			WHERE s.query ILIKE $1
+			AND NOT EXISTS (
+				SELECT 1 FROM customers c
+				WHERE c.telegram_id = s.telegram_id AND c.bot_blocked_at IS NOT NULL
+			)
+		`
+		return query, []interface{}{"%" + *region + "%"}, nil
+	default:
+		return "", nil, fmt.Errorf("unknown audience %q", audience)
+	}
+}
+
+// Broadcasts methods
+func (db *DB) CountBroadcastAudience(ctx context.Context, audience models.BroadcastAudience, region *string) (int, error) {
+	query, args, err := audienceQuery(audience, region)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") a", args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count broadcast audience: %w", err)
+	}
+	return count, nil
+}
+
+// CreateBroadcast stores the broadcast and enqueues a delivery for every
+// recipient of its audience in a single transaction.
+func (db *DB) CreateBroadcast(ctx context.Context, input models.CreateBroadcastInput) (models.Broadcast, error) {
+	audience, audienceArgs, err := audienceQuery(input.Audience, input.Region)
+	if err != nil {
+		return models.Broadcast{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Broadcast{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO broadcasts (created_by, audience, region, text, entities, photo)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING uuid
+	`, input.CreatedBy, input.Audience, input.Region, input.Text, nullBytes(input.Entities), nullBytes(input.Photo)).Scan(&id)
+	if err != nil {
+		return models.Broadcast{}, fmt.Errorf("failed to create broadcast: %w", err)
+	}
+
+	// Shift the audience placeholders past the broadcast UUID
+	enqueue := fmt.Sprintf(`
+		INSERT INTO broadcast_deliveries (broadcast_uuid, telegram_id)
+		SELECT $%d, a.telegram_id FROM (%s) a
+	`, len(audienceArgs)+1, audience)
+	result, err := tx.ExecContext(ctx, enqueue, append(audienceArgs, id)...)
+	if err != nil {
+		return models.Broadcast{}, fmt.Errorf("failed to enqueue broadcast deliveries: %w", err)
+	}
+	total, err := result.RowsAffected()
+	if err != nil {
+		return models.Broadcast{}, fmt.Errorf("failed to enqueue broadcast deliveries: %w", err)
+	}
+
+	query := "UPDATE broadcasts SET total = $1 WHERE uuid = $2 RETURNING " + broadcastColumns
+	broadcast, err := scanBroadcast(tx.QueryRowContext(ctx, query, total, id))
+	if err != nil {
+		return models.Broadcast{}, fmt.Errorf("failed to create broadcast: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Broadcast{}, fmt.Errorf("failed to commit broadcast: %w", err)
+	}
+
+	return broadcast, nil
+}
+
+func (db *DB) GetBroadcast(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	query := "SELECT " + broadcastColumns + " FROM broadcasts WHERE uuid = $1"
+
+	broadcast, err := scanBroadcast(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get broadcast: %w", err)
+	}
+
+	return &broadcast, nil
+}
+
+// ListActiveBroadcasts returns pending and running broadcasts, oldest first
+func (db *DB) ListActiveBroadcasts(ctx context.Context) ([]models.Broadcast, error) {
+	query := "SELECT " + broadcastColumns + " FROM broadcasts WHERE status IN ('pending', 'running') ORDER BY created_at"
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcasts: %w", err)
+	}
+	defer rows.Close()
+
+	var broadcasts []models.Broadcast
+	for rows.Next() {
+		broadcast, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast: %w", err)
+		}
+		broadcasts = append(broadcasts, broadcast)
+	}
+
+	return broadcasts, nil
+}
+
+// SetBroadcastStatus moves an active broadcast to a new status and reports
+// whether it was still active. Finished broadcasts are never changed.
+func (db *DB) SetBroadcastStatus(ctx context.Context, id uuid.UUID, status models.BroadcastStatus) (bool, error) {
+	query := `
+		UPDATE broadcasts SET status = $1,
+			finished_at = CASE WHEN $1 IN ('done', 'cancelled') THEN now() END
+		WHERE uuid = $2 AND status IN ('pending', 'running')
+	`
+	result, err := db.ExecContext(ctx, query, status, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set broadcast status: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to set broadcast status: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (db *DB) SetBroadcastProgressMessage(ctx context.Context, id uuid.UUID, chatID int64, messageID int) error {
+	query := "UPDATE broadcasts SET progress_chat_id = $1, progress_message_id = $2 WHERE uuid = $3"
+	if _, err := db.ExecContext(ctx, query, chatID, messageID, id); err != nil {
+		return fmt.Errorf("failed to set broadcast progress message: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) SetBroadcastPhotoFileID(ctx context.Context, id uuid.UUID, fileID string) error {
+	if _, err := db.ExecContext(ctx, "UPDATE broadcasts SET photo_file_id = $1 WHERE uuid = $2", fileID, id); err != nil {
+		return fmt.Errorf("failed to set broadcast photo: %w", err)
+	}
+	return nil
+}
+
+// NextBroadcastRecipients returns up to limit Telegram IDs still waiting for delivery
+func (db *DB) NextBroadcastRecipients(ctx context.Context, id uuid.UUID, limit int) ([]int64, error) {
+	query := `
+		SELECT telegram_id FROM broadcast_deliveries
+		WHERE broadcast_uuid = $1 AND status = 'pending'
+		ORDER BY telegram_id
+		LIMIT $2
+	`
+	rows, err := db.QueryContext(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcast recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var telegramID int64
+		if err := rows.Scan(&telegramID); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast recipient: %w", err)
+		}
+		ids = append(ids, telegramID)
+	}
+
+	return ids, nil
+}
+
+// MarkBroadcastDelivery records the outcome of a delivery and updates the
+// broadcast counters. A recipient who blocked the bot is flagged on the customer.
+func (db *DB) MarkBroadcastDelivery(ctx context.Context, id uuid.UUID, telegramID int64, status string, deliveryErr *string) error {
+	query := `
+		WITH delivery AS (
+			UPDATE broadcast_deliveries SET status = $3, error = $4, updated_at = now()
+			WHERE broadcast_uuid = $1 AND telegram_id = $2 AND status = 'pending'
+			RETURNING status
+		)
+		UPDATE broadcasts SET
+			sent = sent + (SELECT COUNT(*) FROM delivery WHERE status = 'sent'),
+			failed = failed + (SELECT COUNT(*) FROM delivery WHERE status = 'failed'),
+			blocked = blocked + (SELECT COUNT(*) FROM delivery WHERE status = 'blocked')
+		WHERE uuid = $1
+	`
+	if _, err := db.ExecContext(ctx, query, id, telegramID, status, deliveryErr); err != nil {
+		return fmt.Errorf("failed to mark broadcast delivery: %w", err)
+	}
+
+	if status == models.DeliveryBlocked {
+		return db.SetCustomerBotBlocked(ctx, telegramID, true)
+	}
+	return nil
+}
+
+// SetCustomerBotBlocked flags or unflags a customer who blocked the driver bot
+func (db *DB) SetCustomerBotBlocked(ctx context.Context, telegramID int64, blocked bool) error {
+	query := "UPDATE customers SET bot_blocked_at = now() WHERE telegram_id = $1 AND bot_blocked_at IS NULL"
+	if !blocked {
+		query = "UPDATE customers SET bot_blocked_at = NULL WHERE telegram_id = $1 AND bot_blocked_at IS NOT NULL"
+	}
+	if _, err := db.ExecContext(ctx, query, telegramID); err != nil {
+		return fmt.Errorf("failed to update customer bot status: %w", err)
+	}
+	return nil
+}
+
+func scanBroadcast(row rowScanner) (models.Broadcast, error) {
+	var broadcast models.Broadcast
+	var region, photoFileID sql.NullString
+	var progressChatID sql.NullInt64
+	var progressMessageID sql.NullInt32
+	var finishedAt sql.NullTime
+
+	err := row.Scan(
+		&broadcast.UUID, &broadcast.CreatedBy, &broadcast.Audience, &region, &broadcast.Text,
+		&broadcast.Entities, &broadcast.Photo, &photoFileID,
+		&broadcast.Status, &broadcast.Total, &broadcast.Sent, &broadcast.Failed, &broadcast.Blocked,
+		&progressChatID, &progressMessageID, &broadcast.CreatedAt, &finishedAt,
+	)
+	if err != nil {
+		return models.Broadcast{}, err
+	}
+
+	if region.Valid {
+		broadcast.Region = &region.String
+	}
+	if photoFileID.Valid {
+		broadcast.PhotoFileID = &photoFileID.String
+	}
+	if progressChatID.Valid {
+		broadcast.ProgressChatID = &progressChatID.Int64
+	}
+	if progressMessageID.Valid {
+		messageID := int(progressMessageID.Int32)
+		broadcast.ProgressMessageID = &messageID
+	}
+	if finishedAt.Valid {
+		broadcast.FinishedAt = &finishedAt.Time
+	}
+
+	return broadcast, nil
+}
+
+// nullBytes maps an empty slice to NULL
+func nullBytes(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+const chatThreadColumns = `t.uuid, t.order_uuid, o.title, t.driver_uuid, d.name, o.customer_uuid, c.name,
+	t.driver_revealed, t.customer_revealed, t.created_at`
+
+const chatThreadJoins = `
+	JOIN orders o ON o.uuid = t.order_uuid
+	JOIN customers d ON d.uuid = t.driver_uuid
+	JOIN customers c ON c.uuid = o.customer_uuid`
+
+// Chats methods
+
+// GetOrCreateChatThread returns the conversation of a driver about an order, starting it if needed
+func (db *DB) GetOrCreateChatThread(ctx context.Context, orderID, driverUUID uuid.UUID) (models.ChatThread, error) {
+	query := `
+		WITH t AS (
+			INSERT INTO chat_threads (order_uuid, driver_uuid) VALUES ($1, $2)
+			ON CONFLICT (order_uuid, driver_uuid) DO UPDATE SET order_uuid = EXCLUDED.order_uuid
+			RETURNING *
+		)
+		SELECT ` + chatThreadColumns + ` FROM t` + chatThreadJoins
+
+	thread, err := scanChatThread(db.QueryRowContext(ctx, query, orderID, driverUUID))
+	if err != nil {
+		return models.ChatThread{}, fmt.Errorf("failed to open chat thread: %w", err)
+	}
+	return thread, nil
+}
+
+// GetChatThread returns a conversation, or nil if it does not exist
+func (db *DB) GetChatThread(ctx context.Context, id uuid.UUID) (*models.ChatThread, error) {
+	query := "SELECT " + chatThreadColumns + " FROM chat_threads t" + chatThreadJoins + " WHERE t.uuid = $1"
+
+	thread, err := scanChatThread(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get chat thread: %w", err)
+	}
+	return &thread, nil
+}
+
+// ListChatThreads returns the conversations about an order, oldest first
+func (db *DB) ListChatThreads(ctx context.Context, orderID uuid.UUID) ([]models.ChatThread, error) {
+	query := "SELECT " + chatThreadColumns + " FROM chat_threads t" + chatThreadJoins + " WHERE t.order_uuid = $1 ORDER BY t.created_at"
+
+	rows, err := db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat threads: %w", err)
+	}
+	defer rows.Close()
+
+	var threads []models.ChatThread
+	for rows.Next() {
+		thread, err := scanChatThread(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat thread: %w", err)
+		}
+		threads = append(threads, thread)
+	}
+
+	return threads, nil
+}
+
+// SetChatRevealed records that one side of a conversation shared their contacts
+func (db *DB) SetChatRevealed(ctx context.Context, id uuid.UUID, driver bool) error {
+	column := "customer_revealed"
+	if driver {
+		column = "driver_revealed"
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE chat_threads SET "+column+" = TRUE WHERE uuid = $1", id); err != nil {
+		return fmt.Errorf("failed to reveal chat contacts: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) InsertChatMessage(ctx context.Context, threadID, senderUUID uuid.UUID, text string) (models.ChatMessage, error) {
+	query := `
+		INSERT INTO chat_messages (thread_uuid, sender_uuid, text) VALUES ($1, $2, $3)
+		RETURNING id, thread_uuid, sender_uuid, text, created_at
+	`
+	var message models.ChatMessage
+	err := db.QueryRowContext(ctx, query, threadID, senderUUID, text).Scan(
+		&message.ID, &message.ThreadUUID, &message.SenderUUID, &message.Text, &message.CreatedAt,
+	)
+	if err != nil {
+		return models.ChatMessage{}, fmt.Errorf("failed to insert chat message: %w", err)
+	}
+	return message, nil
+}
+
+// ListChatMessages returns the last messages of a conversation in chronological order
+func (db *DB) ListChatMessages(ctx context.Context, threadID uuid.UUID, limit int) ([]models.ChatMessage, error) {
+	query := `
+		SELECT id, thread_uuid, sender_uuid, text, created_at FROM (
+			SELECT * FROM chat_messages WHERE thread_uuid = $1 ORDER BY id DESC LIMIT $2
+		) m
+		ORDER BY id
+	`
+	rows, err := db.QueryContext(ctx, query, threadID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []models.ChatMessage
+	for rows.Next() {
+		var message models.ChatMessage
+		if err := rows.Scan(&message.ID, &message.ThreadUUID, &message.SenderUUID, &message.Text, &message.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+func scanChatThread(row rowScanner) (models.ChatThread, error) {
+	var thread models.ChatThread
+	err := row.Scan(
+		&thread.UUID, &thread.OrderUUID, &thread.OrderTitle, &thread.DriverUUID, &thread.DriverName,
+		&thread.CustomerUUID, &thread.CustomerName, &thread.DriverRevealed, &thread.CustomerRevealed, &thread.CreatedAt,
+	)
+	return thread, err
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// API tokens methods
+
+// ReplaceAPIToken stores a new token hash of a customer and revokes their previous tokens
+func (db *DB) ReplaceAPIToken(ctx context.Context, customerUUID uuid.UUID, tokenHash string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE api_tokens SET revoked_at = now() WHERE customer_uuid = $1 AND revoked_at IS NULL", customerUUID,
+	); err != nil {
+		return fmt.Errorf("failed to revoke api tokens: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO api_tokens (token_hash, customer_uuid) VALUES ($1, $2)", tokenHash, customerUUID,
+	); err != nil {
+		return fmt.Errorf("failed to insert api token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit api token: %w", err)
+	}
+	return nil
+}
+
+// GetCustomerByAPIToken returns the owner of a valid token hash, or nil
+func (db *DB) GetCustomerByAPIToken(ctx context.Context, tokenHash string) (*models.Customer, error) {
+	query := `
+		WITH t AS (
+			UPDATE api_tokens SET last_used_at = now()
+			WHERE token_hash = $1 AND revoked_at IS NULL
+			RETURNING customer_uuid
+		)
+		SELECT ` + customerColumns + ` FROM customers c JOIN t ON t.customer_uuid = c.uuid
+	`
+	customer, err := scanCustomer(db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get customer by api token: %w", err)
+	}
+	return &customer, nil
+}
+
+// Contact reveals methods
+
+// InsertContactReveal logs a reveal unless the viewer has already revealed
+// limit other orders in the last 24 hours; reopening an order is always
+// allowed and limit 0 means no cap. The viewer's row is locked so that
+// concurrent reveals cannot both pass the check. It reports whether the
+// reveal was logged.
+func (db *DB) InsertContactReveal(ctx context.Context, viewerUUID, orderID uuid.UUID, channel models.Channel, limit int) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if limit > 0 {
+		if _, err := tx.ExecContext(ctx, "SELECT 1 FROM customers WHERE uuid = $1 FOR UPDATE", viewerUUID); err != nil {
+			return false, fmt.Errorf("failed to lock viewer: %w", err)
+		}
+
+		query := `
+			SELECT COUNT(DISTINCT order_uuid), COALESCE(bool_or(order_uuid = $2), FALSE)
+			FROM contact_reveals
+			WHERE viewer_uuid = $1 AND created_at > now() - interval '24 hours'
+		`
+		var count int
+		var revealed bool
+		if err := tx.QueryRowContext(ctx, query, viewerUUID, orderID).Scan(&count, &revealed); err != nil {
+			return false, fmt.Errorf("failed to count contact reveals: %w", err)
+		}
+		if !revealed && count >= limit {
+			return false, nil
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO contact_reveals (viewer_uuid, order_uuid, channel) VALUES ($1, $2, $3)", viewerUUID, orderID, channel,
+	); err != nil {
+		return false, fmt.Errorf("failed to insert contact reveal: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit contact reveal: %w", err)
+	}
+	return true, nil
+}
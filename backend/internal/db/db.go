@@ -31,106 +31,16 @@ func New(dsn string) (*DB, error) {
 // Orders methods
 func (db *DB) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error) {
 	query := `
-		SELECT 
-			o.uuid, o.customer_uuid, o.title, o.description, o.weight_kg,
-			o.length_cm, o.width_cm, o.height_cm, o.from_location, o.to_location,
-			o.tags, o.price, o.available_from, o.created_at,
-			c.uuid, c.name, c.phone, c.telegram_id, c.telegram_tag, c.created_at
+		SELECT ` + orderColumns + `, ` + customerColumns + `
 		FROM orders o
 		JOIN customers c ON o.customer_uuid = c.uuid
 		WHERE 1=1
 	`
-	args := []interface{}{}
-	argCount := 0
+	where, args := orderFilterClause(filter)
+	query += where
+	argCount := len(args)
 
-	// Add filters
-	if filter.MinWeight > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.weight_kg >= $%d", argCount)
-		args = append(args, filter.MinWeight)
-	}
-	if filter.MaxWeight > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.weight_kg <= $%d", argCount)
-		args = append(args, filter.MaxWeight)
-	}
-	if filter.MinLength > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.length_cm >= $%d", argCount)
-		args = append(args, filter.MinLength)
-	}
-	if filter.MaxLength > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.length_cm <= $%d", argCount)
-		args = append(args, filter.MaxLength)
-	}
-	if filter.MinWidth > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.width_cm >= $%d", argCount)
-		args = append(args, filter.MinWidth)
-	}
-	if filter.MaxWidth > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.width_cm <= $%d", argCount)
-		args = append(args, filter.MaxWidth)
-	}
-	if filter.MinHeight > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.height_cm >= $%d", argCount)
-		args = append(args, filter.MinHeight)
-	}
-	if filter.MaxHeight > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.height_cm <= $%d", argCount)
-		args = append(args, filter.MaxHeight)
-	}
-	if filter.MinPrice > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.price >= $%d", argCount)
-		args = append(args, filter.MinPrice)
-	}
-	if filter.MaxPrice > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.price <= $%d", argCount)
-		args = append(args, filter.MaxPrice)
-	}
-	if len(filter.Tags) > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND o.tags && $%d", argCount)
-		args = append(args, pq.Array(filter.Tags))
-	}
-	if filter.From != "" {
-		argCount++
-		query += fmt.Sprintf(" AND o.from_location ILIKE $%d", argCount)
-		args = append(args, "%"+filter.From+"%")
-	}
-	if filter.To != "" {
-		argCount++
-		query += fmt.Sprintf(" AND o.to_location ILIKE $%d", argCount)
-		args = append(args, "%"+filter.To+"%")
-	}
-
-	// Add sorting
-	if filter.SortBy != "" {
-		query += " ORDER BY "
-		switch filter.SortBy {
-		case "price":
-			query += "o.price"
-		case "weight":
-			query += "o.weight_kg"
-		case "price/weight":
-			query += "o.price / o.weight_kg"
-		default:
-			query += "o.created_at"
-		}
-		if filter.SortOrder == "desc" {
-			query += " DESC"
-		} else {
-			query += " ASC"
-		}
-	} else {
-		query += " ORDER BY o.created_at DESC"
-	}
+	query += orderSortClause(filter)
 
 	// Add pagination
 	if filter.Limit <= 0 {
@@ -155,195 +65,238 @@ func (db *DB) ListOrders(ctx context.Context, filter models.OrderFilter) ([]mode
 
 	var orders []models.Order
 	for rows.Next() {
-		var order models.Order
-		var customer models.Customer
-		var description, fromLocation, toLocation sql.NullString
-		var lengthCm, widthCm, heightCm sql.NullFloat64
-		var availableFrom sql.NullTime
-		var telegramID sql.NullInt64
-		var telegramTag sql.NullString
-
-		err := rows.Scan(
-			&order.UUID, &order.CustomerUUID, &order.Title, &description, &order.WeightKg,
-			&lengthCm, &widthCm, &heightCm, &fromLocation, &toLocation,
-			pq.Array(&order.Tags), &order.Price, &availableFrom, &order.CreatedAt,
-			&customer.UUID, &customer.Name, &customer.Phone, &telegramID, &telegramTag, &customer.CreatedAt,
-		)
+		order, err := scanOrderWithCustomer(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan order: %w", err)
 		}
-
-		if description.Valid {
-			order.Description = &description.String
-		}
-		if fromLocation.Valid {
-			order.FromLocation = &fromLocation.String
-		}
-		if toLocation.Valid {
-			order.ToLocation = &toLocation.String
-		}
-		if lengthCm.Valid {
-			order.LengthCm = &lengthCm.Float64
-		}
-		if widthCm.Valid {
-			order.WidthCm = &widthCm.Float64
-		}
-		if heightCm.Valid {
-			order.HeightCm = &heightCm.Float64
-		}
-		if availableFrom.Valid {
-			order.AvailableFrom = &availableFrom.Time
-		}
-		if telegramID.Valid {
-			customer.TelegramID = &telegramID.Int64
-		}
-		if telegramTag.Valid {
-			customer.TelegramTag = &telegramTag.String
-		}
-
-		order.Customer = &customer
 		orders = append(orders, order)
 	}
 
 	// Get total count
 	countQuery := `
 		SELECT COUNT(*) FROM orders o
+		JOIN customers c ON o.customer_uuid = c.uuid
 		WHERE 1=1
 	`
-	countArgs := []interface{}{}
-	argCount = 0
+	countWhere, countArgs := orderFilterClause(filter)
+	countQuery += countWhere
+
+	var total int
+	err = db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	return orders, total, nil
+}
+
+// EachOrder calls fn for every order matching the filter, ignoring pagination.
+// Rows are read one by one, so any number of orders can be exported.
+func (db *DB) EachOrder(ctx context.Context, filter models.OrderFilter, fn func(models.Order) error) error {
+	where, args := orderFilterClause(filter)
+	query := `
+		SELECT ` + orderColumns + `, ` + customerColumns + `
+		FROM orders o
+		JOIN customers c ON o.customer_uuid = c.uuid
+		WHERE 1=1` + where + orderSortClause(filter)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order, err := scanOrderWithCustomer(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan order: %w", err)
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func orderSortClause(filter models.OrderFilter) string {
+	if filter.SortBy == "" {
+		return " ORDER BY o.created_at DESC"
+	}
+
+	clause := " ORDER BY "
+	switch filter.SortBy {
+	case "price":
+		clause += "o.currency, o.price_minor"
+	case "weight":
+		clause += "o.weight_kg"
+	case "price/weight":
+		clause += "o.currency, o.price_minor::numeric / NULLIF(o.weight_kg, 0)"
+	default:
+		clause += "o.created_at"
+	}
+	if filter.SortOrder == "desc" {
+		return clause + " DESC"
+	}
+	return clause + " ASC"
+}
+
+// orderFilterClause builds the AND-conditions shared by the order list and
+// count queries. Placeholders are numbered from $1.
+func orderFilterClause(filter models.OrderFilter) (string, []interface{}) {
+	var clause strings.Builder
+	args := []interface{}{}
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		clause.WriteString(" AND ")
+		clause.WriteString(strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
 
-	// Add same filters for count
 	if filter.MinWeight > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.weight_kg >= $%d", argCount)
-		countArgs = append(countArgs, filter.MinWeight)
+		add("o.weight_kg >= ?", filter.MinWeight)
 	}
 	if filter.MaxWeight > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.weight_kg <= $%d", argCount)
-		countArgs = append(countArgs, filter.MaxWeight)
+		add("o.weight_kg <= ?", filter.MaxWeight)
 	}
 	if filter.MinLength > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.length_cm >= $%d", argCount)
-		countArgs = append(countArgs, filter.MinLength)
+		add("o.length_cm >= ?", filter.MinLength)
 	}
 	if filter.MaxLength > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.length_cm <= $%d", argCount)
-		countArgs = append(countArgs, filter.MaxLength)
+		add("o.length_cm <= ?", filter.MaxLength)
 	}
 	if filter.MinWidth > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.width_cm >= $%d", argCount)
-		countArgs = append(countArgs, filter.MinWidth)
+		add("o.width_cm >= ?", filter.MinWidth)
 	}
 	if filter.MaxWidth > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.width_cm <= $%d", argCount)
-		countArgs = append(countArgs, filter.MaxWidth)
+		add("o.width_cm <= ?", filter.MaxWidth)
 	}
 	if filter.MinHeight > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.height_cm >= $%d", argCount)
-		countArgs = append(countArgs, filter.MinHeight)
+		add("o.height_cm >= ?", filter.MinHeight)
 	}
 	if filter.MaxHeight > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.height_cm <= $%d", argCount)
-		countArgs = append(countArgs, filter.MaxHeight)
+		add("o.height_cm <= ?", filter.MaxHeight)
+	}
+	currency := filter.Currency
+	if currency == "" && (filter.MinPrice > 0 || filter.MaxPrice > 0) {
+		currency = models.DefaultCurrency
+	}
+	if currency != "" {
+		add("o.currency = ?", currency)
 	}
 	if filter.MinPrice > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.price >= $%d", argCount)
-		countArgs = append(countArgs, filter.MinPrice)
+		add("o.price_minor >= ?", filter.MinPrice)
 	}
 	if filter.MaxPrice > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.price <= $%d", argCount)
-		countArgs = append(countArgs, filter.MaxPrice)
+		add("o.price_minor <= ?", filter.MaxPrice)
 	}
 	if len(filter.Tags) > 0 {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.tags && $%d", argCount)
-		countArgs = append(countArgs, pq.Array(filter.Tags))
-	}
-	if filter.From != "" {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.from_location ILIKE $%d", argCount)
-		countArgs = append(countArgs, "%"+filter.From+"%")
-	}
-	if filter.To != "" {
-		argCount++
-		countQuery += fmt.Sprintf(" AND o.to_location ILIKE $%d", argCount)
-		countArgs = append(countArgs, "%"+filter.To+"%")
+		add("o.tags && ?", pq.Array(filter.Tags))
+	}
+	// With both ends From must match a stop followed by one matching To, so a
+	// route through several cities is found by any leg of it. A single end
+	// matches any stop, like Location.
+	stop := "EXISTS (SELECT 1 FROM order_stops s WHERE s.order_uuid = o.uuid AND (s.address ILIKE ? OR s.city ILIKE ?))"
+	switch {
+	case filter.From != "" && filter.To != "":
+		args = append(args, "%"+filter.From+"%", "%"+filter.To+"%")
+		clause.WriteString(fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM order_stops a
+			JOIN order_stops b ON b.order_uuid = a.order_uuid AND b.sequence > a.sequence
+			WHERE a.order_uuid = o.uuid
+				AND (a.address ILIKE $%[1]d OR a.city ILIKE $%[1]d)
+				AND (b.address ILIKE $%[2]d OR b.city ILIKE $%[2]d))`, len(args)-1, len(args)))
+	case filter.From != "":
+		add(stop, "%"+filter.From+"%")
+	case filter.To != "":
+		add(stop, "%"+filter.To+"%")
+	}
+	if filter.Location != "" {
+		add(stop, "%"+filter.Location+"%")
+	}
+	if filter.CustomerUUID != nil {
+		add("o.customer_uuid = ?", *filter.CustomerUUID)
+	}
+	if filter.UUIDs != nil {
+		add("o.uuid = ANY(?)", pq.Array(filter.UUIDs))
+	}
+	if !filter.IncludeUnmoderated {
+		clause.WriteString(" AND o.moderation_status = 'approved'")
+	}
+	if !filter.IncludeHidden {
+		clause.WriteString(" AND o.hidden_at IS NULL AND NOT " + customerBanned)
+	}
+
+	return clause.String(), args
+}
+
+func (db *DB) CreateOrder(ctx context.Context, input models.CreateOrderInput, events OrderEvents) (models.Order, error) {
+	orders, err := db.CreateOrders(ctx, []models.CreateOrderInput{input}, events)
+	if err != nil {
+		return models.Order{}, err
 	}
+	return orders[0], nil
+}
 
-	var total int
-	err = db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+// CreateOrders inserts orders in one transaction: either all of them are created or none
+func (db *DB) CreateOrders(ctx context.Context, inputs []models.CreateOrderInput, events OrderEvents) ([]models.Order, error) {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return orders, total, nil
+	orders := make([]models.Order, 0, len(inputs))
+	for _, input := range inputs {
+		order, err := createOrder(ctx, tx, input)
+		if err != nil {
+			return nil, err
+		}
+		if err := insertOrderEvents(ctx, tx, events, order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit orders: %w", err)
+	}
+
+	return orders, nil
 }
 
-func (db *DB) CreateOrder(ctx context.Context, input models.CreateOrderInput) (models.Order, error) {
+func createOrder(ctx context.Context, tx *sql.Tx, input models.CreateOrderInput) (models.Order, error) {
 	query := `
-		INSERT INTO orders (
+		INSERT INTO orders AS o (
 			customer_uuid, title, description, weight_kg, length_cm, width_cm, height_cm,
-			from_location, to_location, tags, price, available_from
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING uuid, customer_uuid, title, description, weight_kg, length_cm, width_cm, height_cm,
-			from_location, to_location, tags, price, available_from, created_at
-	`
-
-	var order models.Order
-	var description, fromLocation, toLocation sql.NullString
-	var lengthCm, widthCm, heightCm sql.NullFloat64
-	var availableFrom sql.NullTime
-
-	err := db.QueryRowContext(ctx, query,
+			from_location, to_location, tags, price_minor, currency, available_from,
+			moderation_status, moderated_at, moderation_notified_at,
+			price_flag, price_estimate_low, price_estimate_high
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
+			CASE WHEN $14 = 'approved' THEN now() END,
+			CASE WHEN $14 = 'approved' THEN now() END,
+			$15, $16, $17)
+		RETURNING uuid`
+
+	var id uuid.UUID
+	flag, low, high := priceCheckArgs(input.PriceCheck)
+	err := tx.QueryRowContext(ctx, query,
 		input.CustomerUUID, input.Title, input.Description, input.WeightKg,
 		input.LengthCm, input.WidthCm, input.HeightCm, input.FromLocation, input.ToLocation,
-		pq.Array(input.Tags), input.Price, input.AvailableFrom,
-	).Scan(
-		&order.UUID, &order.CustomerUUID, &order.Title, &description, &order.WeightKg,
-		&lengthCm, &widthCm, &heightCm, &fromLocation, &toLocation,
-		pq.Array(&order.Tags), &order.Price, &availableFrom, &order.CreatedAt,
-	)
+		pq.Array(input.Tags), input.Price.Amount, input.Price.Currency, input.AvailableFrom, input.ModerationStatus,
+		flag, low, high,
+	).Scan(&id)
 	if err != nil {
 		return models.Order{}, fmt.Errorf("failed to create order: %w", err)
 	}
-
-	if description.Valid {
-		order.Description = &description.String
-	}
-	if fromLocation.Valid {
-		order.FromLocation = &fromLocation.String
-	}
-	if toLocation.Valid {
-		order.ToLocation = &toLocation.String
-	}
-	if lengthCm.Valid {
-		order.LengthCm = &lengthCm.Float64
-	}
-	if widthCm.Valid {
-		order.WidthCm = &widthCm.Float64
-	}
-	if heightCm.Valid {
-		order.HeightCm = &heightCm.Float64
-	}
-	if availableFrom.Valid {
-		order.AvailableFrom = &availableFrom.Time
+	if err := replaceStops(ctx, tx, id, input.Stops); err != nil {
+		return models.Order{}, err
 	}
 
-	return order, nil
+	// Read back after the stops are inserted, RETURNING would not see them
+	return readOrder(ctx, tx, id)
 }
 
-func (db *DB) UpdateOrder(ctx context.Context, id uuid.UUID, input models.UpdateOrderInput) (models.Order, error) {
-	query := "UPDATE orders SET "
+func (db *DB) UpdateOrder(ctx context.Context, id uuid.UUID, input models.UpdateOrderInput, events OrderEvents) (models.Order, error) {
+	query := "UPDATE orders AS o SET "
 	args := []interface{}{}
 	argCount := 0
 
@@ -395,15 +348,34 @@ func (db *DB) UpdateOrder(ctx context.Context, id uuid.UUID, input models.Update
 		args = append(args, pq.Array(*input.Tags))
 	}
 	if input.Price != nil {
-		argCount++
-		updates = append(updates, fmt.Sprintf("price = $%d", argCount))
-		args = append(args, *input.Price)
+		updates = append(updates, fmt.Sprintf("price_minor = $%d, currency = $%d", argCount+1, argCount+2))
+		args = append(args, input.Price.Amount, input.Price.Currency)
+		argCount += 2
 	}
 	if input.AvailableFrom != nil {
 		argCount++
 		updates = append(updates, fmt.Sprintf("available_from = $%d", argCount))
 		args = append(args, *input.AvailableFrom)
 	}
+	if input.Status != nil {
+		argCount++
+		updates = append(updates, fmt.Sprintf("status = $%d", argCount))
+		args = append(args, *input.Status)
+	}
+	if input.PriceCheck != nil {
+		flag, low, high := priceCheckArgs(input.PriceCheck)
+		updates = append(updates, fmt.Sprintf("price_flag = $%d, price_estimate_low = $%d, price_estimate_high = $%d",
+			argCount+1, argCount+2, argCount+3))
+		args = append(args, flag, low, high)
+		argCount += 3
+	}
+	if input.Hidden != nil {
+		if *input.Hidden {
+			updates = append(updates, "hidden_at = COALESCE(hidden_at, now())")
+		} else {
+			updates = append(updates, "hidden_at = NULL")
+		}
+	}
 
 	if len(updates) == 0 {
 		return models.Order{}, fmt.Errorf("no fields to update")
@@ -414,84 +386,76 @@ func (db *DB) UpdateOrder(ctx context.Context, id uuid.UUID, input models.Update
 	query += fmt.Sprintf(" WHERE uuid = $%d", argCount)
 	args = append(args, id)
 
-	query += " RETURNING uuid, customer_uuid, title, description, weight_kg, length_cm, width_cm, height_cm, from_location, to_location, tags, price, available_from, created_at"
-
-	var order models.Order
-	var description, fromLocation, toLocation sql.NullString
-	var lengthCm, widthCm, heightCm sql.NullFloat64
-	var availableFrom sql.NullTime
+	query += " RETURNING " + orderColumns
 
-	err := db.QueryRowContext(ctx, query, args...).Scan(
-		&order.UUID, &order.CustomerUUID, &order.Title, &description, &order.WeightKg,
-		&lengthCm, &widthCm, &heightCm, &fromLocation, &toLocation,
-		pq.Array(&order.Tags), &order.Price, &availableFrom, &order.CreatedAt,
-	)
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.Order{}, fmt.Errorf("failed to update order: %w", err)
+		return models.Order{}, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if description.Valid {
-		order.Description = &description.String
-	}
-	if fromLocation.Valid {
-		order.FromLocation = &fromLocation.String
-	}
-	if toLocation.Valid {
-		order.ToLocation = &toLocation.String
-	}
-	if lengthCm.Valid {
-		order.LengthCm = &lengthCm.Float64
+	// Stops go first so that RETURNING sees them
+	if input.Stops != nil {
+		if err := replaceStops(ctx, tx, id, *input.Stops); err != nil {
+			return models.Order{}, err
+		}
 	}
-	if widthCm.Valid {
-		order.WidthCm = &widthCm.Float64
+
+	order, err := scanOrder(tx.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return models.Order{}, fmt.Errorf("failed to update order: %w", err)
 	}
-	if heightCm.Valid {
-		order.HeightCm = &heightCm.Float64
+	if err := insertOrderEvents(ctx, tx, events, order); err != nil {
+		return models.Order{}, err
 	}
-	if availableFrom.Valid {
-		order.AvailableFrom = &availableFrom.Time
+	if err := tx.Commit(); err != nil {
+		return models.Order{}, fmt.Errorf("failed to commit order: %w", err)
 	}
 
 	return order, nil
 }
 
-// Customers methods
-func (db *DB) ListCustomers(ctx context.Context, filter models.CustomerFilter) ([]models.Customer, int, error) {
-	query := "SELECT uuid, name, phone, telegram_id, telegram_tag, created_at FROM customers WHERE 1=1"
-	args := []interface{}{}
-	argCount := 0
+// GetOrder returns the order with its customer, or nil if it does not exist
+func (db *DB) GetOrder(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `, ` + customerColumns + `
+		FROM orders o
+		JOIN customers c ON o.customer_uuid = c.uuid
+		WHERE o.uuid = $1
+	`
 
-	if filter.Name != "" {
-		argCount++
-		query += fmt.Sprintf(" AND name ILIKE $%d", argCount)
-		args = append(args, "%"+filter.Name+"%")
-	}
-	if filter.Phone != "" {
-		argCount++
-		query += fmt.Sprintf(" AND phone ILIKE $%d", argCount)
-		args = append(args, "%"+filter.Phone+"%")
+	order, err := scanOrderWithCustomer(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
-	if filter.TelegramTag != "" {
-		argCount++
-		query += fmt.Sprintf(" AND telegram_tag ILIKE $%d", argCount)
-		args = append(args, "%"+filter.TelegramTag+"%")
+
+	return &order, nil
+}
+
+// DeleteOrder removes an order and reports whether it existed
+func (db *DB) DeleteOrder(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM orders WHERE uuid = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete order: %w", err)
 	}
-	if filter.TelegramID != 0 {
-		argCount++
-		query += fmt.Sprintf(" AND telegram_id = $%d", argCount)
-		args = append(args, filter.TelegramID)
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete order: %w", err)
 	}
+	return affected > 0, nil
+}
 
-	if filter.SortBy != "" {
-		query += " ORDER BY " + filter.SortBy
-		if filter.SortOrder == "desc" {
-			query += " DESC"
-		} else {
-			query += " ASC"
-		}
-	} else {
-		query += " ORDER BY created_at DESC"
-	}
+// Customers methods
+func (db *DB) ListCustomers(ctx context.Context, filter models.CustomerFilter) ([]models.Customer, int, error) {
+	query := "SELECT " + customerColumns + " FROM customers c WHERE 1=1"
+	where, args := customerFilterClause(filter)
+	query += where
+	argCount := len(args)
+
+	query += customerSortClause(filter)
 
 	if filter.Limit <= 0 {
 		filter.Limit = 20
@@ -515,94 +479,135 @@ func (db *DB) ListCustomers(ctx context.Context, filter models.CustomerFilter) (
 
 	var customers []models.Customer
 	for rows.Next() {
-		var customer models.Customer
-		var telegramID sql.NullInt64
-		var telegramTag sql.NullString
-
-		err := rows.Scan(
-			&customer.UUID, &customer.Name, &customer.Phone, &telegramID, &telegramTag, &customer.CreatedAt,
-		)
+		customer, err := scanCustomer(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan customer: %w", err)
 		}
+		customers = append(customers, customer)
+	}
+
+	// Get total count
+	countQuery := "SELECT COUNT(*) FROM customers c WHERE 1=1"
+	countWhere, countArgs := customerFilterClause(filter)
+	countQuery += countWhere
+
+	var total int
+	err = db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count customers: %w", err)
+	}
 
-		if telegramID.Valid {
-			customer.TelegramID = &telegramID.Int64
+	return customers, total, nil
+}
+
+// EachCustomer is the customers counterpart of EachOrder
+func (db *DB) EachCustomer(ctx context.Context, filter models.CustomerFilter, fn func(models.Customer) error) error {
+	where, args := customerFilterClause(filter)
+	query := "SELECT " + customerColumns + " FROM customers c WHERE 1=1" + where + customerSortClause(filter)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query customers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan customer: %w", err)
 		}
-		if telegramTag.Valid {
-			customer.TelegramTag = &telegramTag.String
+		if err := fn(customer); err != nil {
+			return err
 		}
+	}
+	return rows.Err()
+}
 
-		customers = append(customers, customer)
+func customerSortClause(filter models.CustomerFilter) string {
+	if filter.SortBy == "" {
+		return " ORDER BY c.created_at DESC"
 	}
 
-	// Get total count
-	countQuery := "SELECT COUNT(*) FROM customers WHERE 1=1"
-	countArgs := []interface{}{}
-	argCount = 0
+	clause := " ORDER BY "
+	switch filter.SortBy {
+	case "name":
+		clause += "c.name"
+	default:
+		clause += "c.created_at"
+	}
+	if filter.SortOrder == "desc" {
+		return clause + " DESC"
+	}
+	return clause + " ASC"
+}
+
+// customerFilterClause builds the AND-conditions shared by the customer list
+// and count queries. Placeholders are numbered from $1.
+func customerFilterClause(filter models.CustomerFilter) (string, []interface{}) {
+	query := ""
+	args := []interface{}{}
+	argCount := 0
 
 	if filter.Name != "" {
 		argCount++
-		countQuery += fmt.Sprintf(" AND name ILIKE $%d", argCount)
-		countArgs = append(countArgs, "%"+filter.Name+"%")
+		query += fmt.Sprintf(" AND c.name ILIKE $%d", argCount)
+		args = append(args, "%"+filter.Name+"%")
 	}
 	if filter.Phone != "" {
 		argCount++
-		countQuery += fmt.Sprintf(" AND phone ILIKE $%d", argCount)
-		countArgs = append(countArgs, "%"+filter.Phone+"%")
+		query += fmt.Sprintf(" AND c.phone ILIKE $%d", argCount)
+		args = append(args, "%"+filter.Phone+"%")
 	}
 	if filter.TelegramTag != "" {
 		argCount++
-		countQuery += fmt.Sprintf(" AND telegram_tag ILIKE $%d", argCount)
-		countArgs = append(countArgs, "%"+filter.TelegramTag+"%")
+		query += fmt.Sprintf(" AND c.telegram_tag ILIKE $%d", argCount)
+		args = append(args, "%"+filter.TelegramTag+"%")
 	}
 	if filter.TelegramID != 0 {
 		argCount++
-		countQuery += fmt.Sprintf(" AND telegram_id = $%d", argCount)
-		countArgs = append(countArgs, filter.TelegramID)
-	}
-
-	var total int
-	err = db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count customers: %w", err)
+		query += fmt.Sprintf(" AND c.telegram_id = $%d", argCount)
+		args = append(args, filter.TelegramID)
 	}
 
-	return customers, total, nil
+	return query, args
 }
 
-func (db *DB) CreateCustomer(ctx context.Context, input models.CreateCustomerInput) (models.Customer, error) {
+func (db *DB) CreateCustomer(ctx context.Context, input models.CreateCustomerInput, events CustomerEvents) (models.Customer, error) {
 	query := `
-		INSERT INTO customers (name, phone, telegram_id, telegram_tag)
+		INSERT INTO customers AS c (name, phone, telegram_id, telegram_tag)
 		VALUES ($1, $2, $3, $4)
-		RETURNING uuid, name, phone, telegram_id, telegram_tag, created_at
-	`
+		RETURNING ` + customerColumns
 
-	var customer models.Customer
-	var telegramID sql.NullInt64
-	var telegramTag sql.NullString
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Customer{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	err := db.QueryRowContext(ctx, query,
+	customer, err := scanCustomer(tx.QueryRowContext(ctx, query,
 		input.Name, input.Phone, input.TelegramID, input.TelegramTag,
-	).Scan(
-		&customer.UUID, &customer.Name, &customer.Phone, &telegramID, &telegramTag, &customer.CreatedAt,
-	)
+	))
 	if err != nil {
 		return models.Customer{}, fmt.Errorf("failed to create customer: %w", err)
 	}
-
-	if telegramID.Valid {
-		customer.TelegramID = &telegramID.Int64
+	if events != nil {
+		built, err := events(customer)
+		if err != nil {
+			return models.Customer{}, err
+		}
+		if err := insertEvents(ctx, tx, built); err != nil {
+			return models.Customer{}, err
+		}
 	}
-	if telegramTag.Valid {
-		customer.TelegramTag = &telegramTag.String
+	if err := tx.Commit(); err != nil {
+		return models.Customer{}, fmt.Errorf("failed to commit customer: %w", err)
 	}
 
 	return customer, nil
 }
 
 func (db *DB) UpdateCustomer(ctx context.Context, id uuid.UUID, input models.UpdateCustomerInput) (models.Customer, error) {
-	query := "UPDATE customers SET "
+	query := "UPDATE customers AS c SET "
 	args := []interface{}{}
 	argCount := 0
 
@@ -628,6 +633,16 @@ func (db *DB) UpdateCustomer(ctx context.Context, id uuid.UUID, input models.Upd
 		updates = append(updates, fmt.Sprintf("telegram_tag = $%d", argCount))
 		args = append(args, *input.TelegramTag)
 	}
+	if input.VehicleModel != nil {
+		argCount++
+		updates = append(updates, fmt.Sprintf("vehicle_model = $%d", argCount))
+		args = append(args, *input.VehicleModel)
+	}
+	if input.VehiclePlate != nil {
+		argCount++
+		updates = append(updates, fmt.Sprintf("vehicle_plate = $%d", argCount))
+		args = append(args, *input.VehiclePlate)
+	}
 
 	if len(updates) == 0 {
 		return models.Customer{}, fmt.Errorf("no fields to update")
@@ -638,42 +653,20 @@ func (db *DB) UpdateCustomer(ctx context.Context, id uuid.UUID, input models.Upd
 	query += fmt.Sprintf(" WHERE uuid = $%d", argCount)
 	args = append(args, id)
 
-	query += " RETURNING uuid, name, phone, telegram_id, telegram_tag, created_at"
+	query += " RETURNING " + customerColumns
 
-	var customer models.Customer
-	var telegramID sql.NullInt64
-	var telegramTag sql.NullString
-
-	err := db.QueryRowContext(ctx, query, args...).Scan(
-		&customer.UUID, &customer.Name, &customer.Phone, &telegramID, &telegramTag, &customer.CreatedAt,
-	)
+	customer, err := scanCustomer(db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		return models.Customer{}, fmt.Errorf("failed to update customer: %w", err)
 	}
 
-	if telegramID.Valid {
-		customer.TelegramID = &telegramID.Int64
-	}
-	if telegramTag.Valid {
-		customer.TelegramTag = &telegramTag.String
-	}
-
 	return customer, nil
 }
 
 func (db *DB) GetCustomerByTelegramID(ctx context.Context, telegramID int64) (*models.Customer, error) {
-	query := `
-		SELECT uuid, name, phone, telegram_id, telegram_tag, created_at
-		FROM customers WHERE telegram_id = $1
-	`
+	query := "SELECT " + customerColumns + " FROM customers c WHERE c.telegram_id = $1"
 
-	var customer models.Customer
-	var telegramIDNull sql.NullInt64
-	var telegramTag sql.NullString
-
-	err := db.QueryRowContext(ctx, query, telegramID).Scan(
-		&customer.UUID, &customer.Name, &customer.Phone, &telegramIDNull, &telegramTag, &customer.CreatedAt,
-	)
+	customer, err := scanCustomer(db.QueryRowContext(ctx, query, telegramID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -681,12 +674,34 @@ func (db *DB) GetCustomerByTelegramID(ctx context.Context, telegramID int64) (*m
 		return nil, fmt.Errorf("failed to get customer by telegram ID: %w", err)
 	}
 
-	if telegramIDNull.Valid {
-		customer.TelegramID = &telegramIDNull.Int64
-	}
-	if telegramTag.Valid {
-		customer.TelegramTag = &telegramTag.String
+	return &customer, nil
+}
+
+// GetCustomer returns the customer with the given UUID, or nil if it does not exist
+func (db *DB) GetCustomer(ctx context.Context, id uuid.UUID) (*models.Customer, error) {
+	query := "SELECT " + customerColumns + " FROM customers c WHERE c.uuid = $1"
+
+	customer, err := scanCustomer(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get customer: %w", err)
 	}
 
 	return &customer, nil
 }
+
+// DeleteCustomer removes a customer together with their orders and reports
+// whether the customer existed
+func (db *DB) DeleteCustomer(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM customers WHERE uuid = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete customer: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete customer: %w", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gruzy-ryadom/internal/models"
+)
+
+// Moderation methods
+func (db *DB) GetModerationHistory(ctx context.Context, customerUUID uuid.UUID) (models.ModerationHistory, error) {
+	query := `
+		SELECT c.trusted,
+			COUNT(o.uuid) FILTER (WHERE o.moderation_status = 'approved'),
+			COUNT(o.uuid) FILTER (WHERE o.moderation_status = 'rejected')
+		FROM customers c
+		LEFT JOIN orders o ON o.customer_uuid = c.uuid
+		WHERE c.uuid = $1
+		GROUP BY c.uuid
+	`
+
+	var history models.ModerationHistory
+	err := db.QueryRowContext(ctx, query, customerUUID).Scan(&history.Trusted, &history.Approved, &history.Rejected)
+	if err != nil {
+		return models.ModerationHistory{}, fmt.Errorf("failed to get moderation history: %w", err)
+	}
+
+	return history, nil
+}
+
+// ListUnnotifiedModeration returns pending orders that have not been sent to the moderators yet
+func (db *DB) ListUnnotifiedModeration(ctx context.Context, limit int) ([]models.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `, ` + customerColumns + `
+		FROM orders o
+		JOIN customers c ON o.customer_uuid = c.uuid
+		WHERE o.moderation_status = 'pending' AND o.moderation_notified_at IS NULL
+		ORDER BY o.created_at
+		LIMIT $1
+	`
+	return db.queryOrders(ctx, query, limit)
+}
+
+// ListPendingModeration returns every order waiting for a decision, oldest first
+func (db *DB) ListPendingModeration(ctx context.Context, limit int) ([]models.Order, int, error) {
+	query := `
+		SELECT ` + orderColumns + `, ` + customerColumns + `
+		FROM orders o
+		JOIN customers c ON o.customer_uuid = c.uuid
+		WHERE o.moderation_status = 'pending'
+		ORDER BY o.created_at
+		LIMIT $1
+	`
+	orders, err := db.queryOrders(ctx, query, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders WHERE moderation_status = 'pending'").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count pending orders: %w", err)
+	}
+
+	return orders, total, nil
+}
+
+func (db *DB) MarkModerationNotified(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query := "UPDATE orders SET moderation_notified_at = now() WHERE uuid = ANY($1)"
+	if _, err := db.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to mark orders as notified: %w", err)
+	}
+	return nil
+}
+
+// ModerateOrder records a decision on a pending order. It returns nil if the
+// order does not exist or has already been decided.
+func (db *DB) ModerateOrder(ctx context.Context, id uuid.UUID, status models.ModerationStatus, reason *string, moderatorID int64, events OrderEvents) (*models.Order, error) {
+	query := `
+		UPDATE orders SET moderation_status = $1, moderation_reason = $2, moderated_by = $3, moderated_at = now()
+		WHERE uuid = $4 AND moderation_status = 'pending'
+	`
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, status, reason, moderatorID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate order: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate order: %w", err)
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+
+	order, err := readOrder(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOrderEvents(ctx, tx, events, order); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit moderation: %w", err)
+	}
+
+	return db.GetOrder(ctx, id)
+}
+
+func (db *DB) SetCustomerTrusted(ctx context.Context, id uuid.UUID, trusted bool) error {
+	result, err := db.ExecContext(ctx, "UPDATE customers SET trusted = $1 WHERE uuid = $2", trusted, id)
+	if err != nil {
+		return fmt.Errorf("failed to update customer trust: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("customer %s not found", id)
+	}
+	return nil
+}
+
+// queryOrders runs a query selecting orderColumns and customerColumns
+func (db *DB) queryOrders(ctx context.Context, query string, args ...interface{}) ([]models.Order, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order, err := scanOrderWithCustomer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
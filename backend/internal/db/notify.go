@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Notify sends a notification to every connection listening on the channel,
+// across all instances of the application
+func (db *DB) Notify(ctx context.Context, channel string, payload []byte) error {
+	if _, err := db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Listen opens a dedicated connection listening on the channel. The listener
+// reconnects by itself; notifications sent while it was disconnected are lost.
+// While the database is unreachable it waits for it until ctx is cancelled.
+func Listen(ctx context.Context, dsn, channel string) (*pq.Listener, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Listener %s: %v", channel, err)
+		}
+	})
+
+	// pq blocks in Listen until it has a connection; closing the listener releases it
+	listening := make(chan error, 1)
+	go func() { listening <- listener.Listen(channel) }()
+	select {
+	case err := <-listening:
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+		return listener, nil
+	case <-ctx.Done():
+		listener.Close()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gruzy-ryadom/internal/models"
+)
+
+const outboxColumns = `e.id, e.event_type, e.aggregate_uuid, e.payload, e.created_at, e.attempts`
+
+// OrderEvents builds the domain events of an order write from the written
+// order; they are stored in the outbox in the transaction of the write.
+// A nil OrderEvents records nothing.
+type OrderEvents func(order models.Order) ([]models.DomainEvent, error)
+
+// CustomerEvents builds the domain events of a customer write, see OrderEvents
+type CustomerEvents func(customer models.Customer) ([]models.DomainEvent, error)
+
+// insertOrderEvents writes the events of an order write within its transaction
+func insertOrderEvents(ctx context.Context, tx *sql.Tx, events OrderEvents, order models.Order) error {
+	if events == nil {
+		return nil
+	}
+	built, err := events(order)
+	if err != nil {
+		return err
+	}
+	return insertEvents(ctx, tx, built)
+}
+
+func insertEvents(ctx context.Context, tx *sql.Tx, events []models.DomainEvent) error {
+	for _, event := range events {
+		query := "INSERT INTO outbox (event_type, aggregate_uuid, payload) VALUES ($1, $2, $3)"
+		if _, err := tx.ExecContext(ctx, query, event.Type, event.AggregateUUID, []byte(event.Payload)); err != nil {
+			return fmt.Errorf("failed to write %s event: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// Outbox methods
+
+// ClaimEvents picks unprocessed events that are due, oldest first, and counts
+// the attempt. The claimed events are postponed by lease, so another instance
+// does not handle them meanwhile and they are retried if this one dies.
+func (db *DB) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]models.DomainEvent, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM outbox
+			WHERE processed_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox e
+		SET attempts = e.attempts + 1, next_attempt_at = now() + $2::float8 * interval '1 second'
+		FROM due
+		WHERE e.id = due.id
+		RETURNING ` + outboxColumns
+
+	rows, err := db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.DomainEvent
+	for rows.Next() {
+		var event models.DomainEvent
+		err := rows.Scan(&event.ID, &event.Type, &event.AggregateUUID, &event.Payload, &event.CreatedAt, &event.Attempts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	// UPDATE ... RETURNING does not keep the order of the CTE
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// GetEvents returns the events with the given IDs that are still kept, in any order
+func (db *DB) GetEvents(ctx context.Context, ids []int64) ([]models.DomainEvent, error) {
+	query := "SELECT " + outboxColumns + " FROM outbox e WHERE e.id = ANY($1)"
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.DomainEvent
+	for rows.Next() {
+		var event models.DomainEvent
+		err := rows.Scan(&event.ID, &event.Type, &event.AggregateUUID, &event.Payload, &event.CreatedAt, &event.Attempts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+	return events, nil
+}
+
+// CompleteEvent marks an event handled by all its handlers
+func (db *DB) CompleteEvent(ctx context.Context, id int64) error {
+	if _, err := db.ExecContext(ctx, "UPDATE outbox SET processed_at = now(), last_error = NULL WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to complete outbox event: %w", err)
+	}
+	return nil
+}
+
+// RetryEvent schedules another attempt of an event after a handler failed
+func (db *DB) RetryEvent(ctx context.Context, id int64, retryAfter time.Duration, handlerErr string) error {
+	query := "UPDATE outbox SET next_attempt_at = now() + $2::float8 * interval '1 second', last_error = $3 WHERE id = $1"
+	if _, err := db.ExecContext(ctx, query, id, retryAfter.Seconds(), handlerErr); err != nil {
+		return fmt.Errorf("failed to postpone outbox event: %w", err)
+	}
+	return nil
+}
+
+// PurgeEvents deletes events processed more than age ago and returns their number
+func (db *DB) PurgeEvents(ctx context.Context, age time.Duration) (int, error) {
+	query := "DELETE FROM outbox WHERE processed_at < now() - $1::float8 * interval '1 second'"
+	result, err := db.ExecContext(ctx, query, age.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	return int(affected), nil
+}
+
+// readOrder reads an order without its customer within a transaction
+func readOrder(ctx context.Context, tx *sql.Tx, id uuid.UUID) (models.Order, error) {
+	order, err := scanOrder(tx.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders o WHERE o.uuid = $1", id))
+	if err != nil {
+		return models.Order{}, fmt.Errorf("failed to read order: %w", err)
+	}
+	return order, nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+const photoColumns = `uuid, order_uuid, position, telegram_file_id, storage_key, thumbnail_key,
+	content_type, size_bytes, width, height, created_at`
+
+// InsertOrderPhoto appends a photo to an order unless it already has limit
+// photos; it returns nil if the limit is reached
+func (db *DB) InsertOrderPhoto(ctx context.Context, photo models.OrderPhoto, limit int) (*models.OrderPhoto, error) {
+	// Concurrent uploads to one order collide on (order_uuid, position) rather than exceed the limit
+	query := `
+		INSERT INTO order_photos (uuid, order_uuid, position, telegram_file_id, storage_key, thumbnail_key,
+			content_type, size_bytes, width, height)
+		SELECT $1::uuid, $2::uuid, COALESCE(MAX(position) + 1, 0), $3::text, $4::text, $5::text, $6::text,
+			$7::bigint, $8::int, $9::int
+		FROM order_photos WHERE order_uuid = $2
+		HAVING COUNT(*) < $10
+		RETURNING ` + photoColumns
+
+	inserted, err := scanPhoto(db.QueryRowContext(ctx, query, photo.UUID, photo.OrderUUID, photo.TelegramFileID,
+		photo.StorageKey, photo.ThumbnailKey, photo.ContentType, photo.SizeBytes, photo.Width, photo.Height, limit))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert order photo: %w", err)
+	}
+	return &inserted, nil
+}
+
+func (db *DB) ListOrderPhotos(ctx context.Context, orderID uuid.UUID) ([]models.OrderPhoto, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+photoColumns+" FROM order_photos WHERE order_uuid = $1 ORDER BY position", orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order photos: %w", err)
+	}
+	defer rows.Close()
+
+	var photos []models.OrderPhoto
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+	return photos, nil
+}
+
+// GetOrderPhoto returns a photo, or nil if it does not exist
+func (db *DB) GetOrderPhoto(ctx context.Context, id uuid.UUID) (*models.OrderPhoto, error) {
+	photo, err := scanPhoto(db.QueryRowContext(ctx, "SELECT "+photoColumns+" FROM order_photos WHERE uuid = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order photo: %w", err)
+	}
+	return &photo, nil
+}
+
+func scanPhoto(row rowScanner) (models.OrderPhoto, error) {
+	var photo models.OrderPhoto
+	var fileID sql.NullString
+	err := row.Scan(&photo.UUID, &photo.OrderUUID, &photo.Position, &fileID, &photo.StorageKey, &photo.ThumbnailKey,
+		&photo.ContentType, &photo.SizeBytes, &photo.Width, &photo.Height, &photo.CreatedAt)
+	if err != nil {
+		return models.OrderPhoto{}, err
+	}
+	if fileID.Valid {
+		photo.TelegramFileID = &fileID.String
+	}
+	return photo, nil
+}
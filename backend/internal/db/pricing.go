@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"gruzy-ryadom/internal/models"
+)
+
+// ListPriceSamples returns recent published orders in a currency to base price
+// estimates on. Cancelled orders and orders flagged as outliers are left out.
+func (db *DB) ListPriceSamples(ctx context.Context, currency models.Currency, since time.Time, limit int) ([]models.PriceSample, error) {
+	query := `
+		SELECT from_location, to_location, weight_kg, length_cm, width_cm, height_cm, tags, price_minor, currency, created_at
+		FROM orders
+		WHERE moderation_status = 'approved' AND status <> 'cancelled' AND price_flag IS NULL
+			AND currency = $1 AND price_minor > 0 AND weight_kg > 0 AND created_at >= $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+	rows, err := db.QueryContext(ctx, query, currency, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price samples: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []models.PriceSample
+	for rows.Next() {
+		var sample models.PriceSample
+		var from, to sql.NullString
+		var lengthCm, widthCm, heightCm sql.NullFloat64
+		err := rows.Scan(&from, &to, &sample.WeightKg, &lengthCm, &widthCm, &heightCm,
+			pq.Array(&sample.Tags), &sample.Price.Amount, &sample.Price.Currency, &sample.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price sample: %w", err)
+		}
+		if from.Valid {
+			sample.From = &from.String
+		}
+		if to.Valid {
+			sample.To = &to.String
+		}
+		if lengthCm.Valid && widthCm.Valid && heightCm.Valid {
+			sample.LengthCm, sample.WidthCm, sample.HeightCm = &lengthCm.Float64, &widthCm.Float64, &heightCm.Float64
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, nil
+}
+
+// priceCheckArgs turns a price check into nullable column values
+func priceCheckArgs(check *models.PriceCheck) (interface{}, interface{}, interface{}) {
+	if check == nil {
+		return nil, nil, nil
+	}
+	var flag interface{}
+	if check.Flag != "" {
+		flag = string(check.Flag)
+	}
+	return flag, check.Low.Amount, check.High.Amount
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// Referrals methods
+func (db *DB) GetReferralCode(ctx context.Context, customerUUID uuid.UUID) (string, error) {
+	var code string
+	err := db.QueryRowContext(ctx, "SELECT referral_code FROM customers WHERE uuid = $1", customerUUID).Scan(&code)
+	if err != nil {
+		return "", fmt.Errorf("failed to get referral code: %w", err)
+	}
+	return code, nil
+}
+
+func (db *DB) GetCustomerByReferralCode(ctx context.Context, code string) (*models.Customer, error) {
+	query := "SELECT " + customerColumns + " FROM customers c WHERE c.referral_code = $1"
+
+	customer, err := scanCustomer(db.QueryRowContext(ctx, query, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get customer by referral code: %w", err)
+	}
+
+	return &customer, nil
+}
+
+// CreateReferral attributes a customer to a referrer. A customer that is
+// already attributed keeps its original referrer.
+func (db *DB) CreateReferral(ctx context.Context, referrerUUID, referredUUID uuid.UUID) error {
+	query := `
+		INSERT INTO referrals (referrer_uuid, referred_uuid)
+		VALUES ($1, $2)
+		ON CONFLICT (referred_uuid) DO NOTHING
+	`
+	if _, err := db.ExecContext(ctx, query, referrerUUID, referredUUID); err != nil {
+		return fmt.Errorf("failed to create referral: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) CountReferrals(ctx context.Context, referrerUUID uuid.UUID) (int, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM referrals WHERE referrer_uuid = $1", referrerUUID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count referrals: %w", err)
+	}
+	return count, nil
+}
+
+// ListTopReferrers returns customers ordered by the number of invited customers
+func (db *DB) ListTopReferrers(ctx context.Context, limit int) ([]models.ReferrerStats, int, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := `
+		SELECT ` + customerColumns + `, COUNT(*) AS referrals
+		FROM referrals r
+		JOIN customers c ON r.referrer_uuid = c.uuid
+		GROUP BY c.uuid
+		ORDER BY referrals DESC, c.created_at
+		LIMIT $1
+	`
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query referrers: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []models.ReferrerStats
+	for rows.Next() {
+		var item models.ReferrerStats
+		dest, finish := customerDest(&item.Customer)
+		if err := rows.Scan(append(dest, &item.Referrals)...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan referrer: %w", err)
+		}
+		finish()
+		stats = append(stats, item)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM referrals").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count referrals: %w", err)
+	}
+
+	return stats, total, nil
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+const reviewColumns = `r.uuid, r.order_uuid, r.author_uuid, r.target_uuid, r.rating, r.comment, r.created_at, r.removed_at,
+	a.name, t.name`
+
+const reviewJoins = `
+	JOIN customers a ON a.uuid = r.author_uuid
+	JOIN customers t ON t.uuid = r.target_uuid`
+
+// AssignCarrier hands an open order to a driver and reports whether it was still free
+func (db *DB) AssignCarrier(ctx context.Context, orderID, carrierUUID uuid.UUID, events OrderEvents) (bool, error) {
+	query := `
+		UPDATE orders SET carrier_uuid = $2, status = 'in_progress'
+		WHERE uuid = $1 AND status = 'open' AND carrier_uuid IS NULL
+	`
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, orderID, carrierUUID)
+	if err != nil {
+		return false, fmt.Errorf("failed to assign carrier: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to assign carrier: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	order, err := readOrder(ctx, tx, orderID)
+	if err != nil {
+		return false, err
+	}
+	if err := insertOrderEvents(ctx, tx, events, order); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit carrier: %w", err)
+	}
+	return true, nil
+}
+
+// Reviews methods
+func (db *DB) CreateReview(ctx context.Context, input models.CreateReviewInput, targetUUID uuid.UUID) (models.Review, error) {
+	query := `
+		WITH r AS (
+			INSERT INTO reviews (order_uuid, author_uuid, target_uuid, rating, comment)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT ` + reviewColumns + ` FROM r` + reviewJoins
+
+	review, err := scanReview(db.QueryRowContext(ctx, query,
+		input.OrderUUID, input.AuthorUUID, targetUUID, input.Rating, input.Comment,
+	))
+	if err != nil {
+		return models.Review{}, fmt.Errorf("failed to create review: %w", err)
+	}
+	return review, nil
+}
+
+// GetReview returns the review an author left for an order, or nil
+func (db *DB) GetReview(ctx context.Context, orderID, authorUUID uuid.UUID) (*models.Review, error) {
+	query := "SELECT " + reviewColumns + " FROM reviews r" + reviewJoins + " WHERE r.order_uuid = $1 AND r.author_uuid = $2"
+
+	review, err := scanReview(db.QueryRowContext(ctx, query, orderID, authorUUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return &review, nil
+}
+
+// SetReviewComment attaches a comment to a review that has none yet
+func (db *DB) SetReviewComment(ctx context.Context, id uuid.UUID, comment string) (bool, error) {
+	query := "UPDATE reviews SET comment = $1 WHERE uuid = $2 AND comment IS NULL AND removed_at IS NULL"
+	result, err := db.ExecContext(ctx, query, comment, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set review comment: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to set review comment: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ListReviews returns reviews matching the filter, newest first
+func (db *DB) ListReviews(ctx context.Context, filter models.ReviewFilter) ([]models.Review, error) {
+	query := "SELECT " + reviewColumns + " FROM reviews r" + reviewJoins + " WHERE 1=1"
+	args := []interface{}{}
+	if filter.TargetUUID != nil {
+		args = append(args, *filter.TargetUUID)
+		query += fmt.Sprintf(" AND r.target_uuid = $%d", len(args))
+	}
+	if !filter.IncludeRemoved {
+		query += " AND r.removed_at IS NULL"
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY r.created_at DESC LIMIT $%d", len(args))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []models.Review
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+// RemoveReview excludes a review from the reputation and reports whether it was shown
+func (db *DB) RemoveReview(ctx context.Context, id uuid.UUID, removedBy int64) (bool, error) {
+	query := "UPDATE reviews SET removed_at = now(), removed_by = $1 WHERE uuid = $2 AND removed_at IS NULL"
+	result, err := db.ExecContext(ctx, query, removedBy, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove review: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove review: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func scanReview(row rowScanner) (models.Review, error) {
+	var review models.Review
+	var comment sql.NullString
+	var removedAt sql.NullTime
+
+	err := row.Scan(
+		&review.UUID, &review.OrderUUID, &review.AuthorUUID, &review.TargetUUID, &review.Rating,
+		&comment, &review.CreatedAt, &removedAt, &review.AuthorName, &review.TargetName,
+	)
+	if err != nil {
+		return models.Review{}, err
+	}
+
+	if comment.Valid {
+		review.Comment = &comment.String
+	}
+	if removedAt.Valid {
+		review.RemovedAt = &removedAt.Time
+	}
+
+	return review, nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// Saved searches methods
+func (db *DB) SaveSearch(ctx context.Context, telegramID int64, query string) (models.SavedSearch, error) {
+	insert := `
+		INSERT INTO saved_searches (telegram_id, query)
+		VALUES ($1, $2)
+		ON CONFLICT (telegram_id, query) DO UPDATE SET query = EXCLUDED.query
+		RETURNING uuid, telegram_id, query, created_at
+	`
+
+	var search models.SavedSearch
+	err := db.QueryRowContext(ctx, insert, telegramID, query).Scan(
+		&search.UUID, &search.TelegramID, &search.Query, &search.CreatedAt,
+	)
+	if err != nil {
+		return models.SavedSearch{}, fmt.Errorf("failed to save search: %w", err)
+	}
+
+	return search, nil
+}
+
+func (db *DB) ListSavedSearches(ctx context.Context, telegramID int64) ([]models.SavedSearch, error) {
+	query := "SELECT uuid, telegram_id, query, created_at FROM saved_searches WHERE telegram_id = $1 ORDER BY created_at"
+
+	rows, err := db.QueryContext(ctx, query, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []models.SavedSearch
+	for rows.Next() {
+		var search models.SavedSearch
+		if err := rows.Scan(&search.UUID, &search.TelegramID, &search.Query, &search.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, nil
+}
+
+func (db *DB) DeleteSavedSearches(ctx context.Context, telegramID int64) (int, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM saved_searches WHERE telegram_id = $1", telegramID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete saved searches: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete saved searches: %w", err)
+	}
+	return int(affected), nil
+}
+
+// ListSubscribedSearches returns the saved searches of all users who have
+// not blocked the driver bot
+func (db *DB) ListSubscribedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	query := `
+		SELECT s.uuid, s.telegram_id, s.query, s.created_at FROM saved_searches s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM customers c
+			WHERE c.telegram_id = s.telegram_id AND c.bot_blocked_at IS NOT NULL
+		)
+		ORDER BY s.created_at
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []models.SavedSearch
+	for rows.Next() {
+		var search models.SavedSearch
+		if err := rows.Scan(&search.UUID, &search.TelegramID, &search.Query, &search.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
+
+// ClaimSearchNotification records that an order is being sent to a driver;
+// it returns false if the order was already sent to them
+func (db *DB) ClaimSearchNotification(ctx context.Context, telegramID int64, orderUUID uuid.UUID) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO search_notifications (telegram_id, order_uuid) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, telegramID, orderUUID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim search notification: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim search notification: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// ReleaseSearchNotification forgets a notification that could not be sent, so a retry sends it
+func (db *DB) ReleaseSearchNotification(ctx context.Context, telegramID int64, orderUUID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM search_notifications WHERE telegram_id = $1 AND order_uuid = $2", telegramID, orderUUID)
+	if err != nil {
+		return fmt.Errorf("failed to release search notification: %w", err)
+	}
+	return nil
+}
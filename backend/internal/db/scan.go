@@ -0,0 +1,189 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gruzy-ryadom/internal/models"
+)
+
+// Column lists are qualified with the "o" and "c" aliases used by every query
+// that reads orders and customers, so scanners stay in sync with the SELECTs.
+const (
+	orderColumns = `o.uuid, o.customer_uuid, o.title, o.description, o.weight_kg,
+		o.length_cm, o.width_cm, o.height_cm, o.from_location, o.to_location,
+		o.tags, o.price_minor, o.currency, o.available_from, o.status, o.moderation_status, o.moderation_reason,
+		o.hidden_at IS NOT NULL, o.carrier_uuid, o.price_flag, o.price_estimate_low, o.price_estimate_high,
+		o.created_at, ` + stopsColumn + `,
+		(SELECT COUNT(*) FROM order_photos p WHERE p.order_uuid = o.uuid),
+		(SELECT p.telegram_file_id FROM order_photos p WHERE p.order_uuid = o.uuid ORDER BY p.position LIMIT 1)`
+
+	// Stops are aggregated into JSON; timestamps are stored in UTC without a zone
+	stopsColumn = `(SELECT json_agg(json_build_object(
+			'sequence', s.sequence, 'address', s.address, 'city', s.city, 'lat', s.lat, 'lon', s.lon,
+			'window_from', to_char(s.window_from, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+			'window_to', to_char(s.window_to, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+			'notes', s.notes) ORDER BY s.sequence)
+		FROM order_stops s WHERE s.order_uuid = o.uuid)`
+
+	// The reputation is aggregated from reviews that were not removed by admins
+	customerColumns = `c.uuid, c.name, c.phone, c.telegram_id, c.telegram_tag, c.created_at,
+		c.vehicle_model, c.vehicle_plate,
+		(SELECT AVG(r.rating)::float8 FROM reviews r WHERE r.target_uuid = c.uuid AND r.removed_at IS NULL),
+		(SELECT COUNT(*) FROM reviews r WHERE r.target_uuid = c.uuid AND r.removed_at IS NULL)`
+)
+
+// stopsScanner decodes stopsColumn
+type stopsScanner []models.OrderStop
+
+func (s *stopsScanner) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]models.OrderStop)(s))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]models.OrderStop)(s))
+	default:
+		return fmt.Errorf("unexpected stops value %T", src)
+	}
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// orderDest returns scan destinations matching orderColumns and a function
+// that copies nullable values into the order once the row has been scanned.
+func orderDest(order *models.Order) ([]interface{}, func()) {
+	var description, fromLocation, toLocation, moderationReason sql.NullString
+	var lengthCm, widthCm, heightCm sql.NullFloat64
+	var availableFrom sql.NullTime
+	var carrierUUID uuid.NullUUID
+	var priceFlag sql.NullString
+	var priceLow, priceHigh sql.NullInt64
+	var coverFileID sql.NullString
+
+	dest := []interface{}{
+		&order.UUID, &order.CustomerUUID, &order.Title, &description, &order.WeightKg,
+		&lengthCm, &widthCm, &heightCm, &fromLocation, &toLocation,
+		pq.Array(&order.Tags), &order.Price.Amount, &order.Price.Currency, &availableFrom, &order.Status,
+		&order.ModerationStatus, &moderationReason, &order.Hidden, &carrierUUID,
+		&priceFlag, &priceLow, &priceHigh, &order.CreatedAt, (*stopsScanner)(&order.Stops),
+		&order.PhotosCount, &coverFileID,
+	}
+
+	finish := func() {
+		if description.Valid {
+			order.Description = &description.String
+		}
+		if fromLocation.Valid {
+			order.FromLocation = &fromLocation.String
+		}
+		if toLocation.Valid {
+			order.ToLocation = &toLocation.String
+		}
+		if lengthCm.Valid {
+			order.LengthCm = &lengthCm.Float64
+		}
+		if widthCm.Valid {
+			order.WidthCm = &widthCm.Float64
+		}
+		if heightCm.Valid {
+			order.HeightCm = &heightCm.Float64
+		}
+		if availableFrom.Valid {
+			order.AvailableFrom = &availableFrom.Time
+		}
+		if moderationReason.Valid {
+			order.ModerationReason = &moderationReason.String
+		}
+		if coverFileID.Valid {
+			order.CoverFileID = &coverFileID.String
+		}
+		if carrierUUID.Valid {
+			order.CarrierUUID = &carrierUUID.UUID
+		}
+		if priceLow.Valid && priceHigh.Valid {
+			order.PriceCheck = &models.PriceCheck{
+				Flag: models.PriceFlag(priceFlag.String),
+				Low:  models.Money{Amount: priceLow.Int64, Currency: order.Price.Currency},
+				High: models.Money{Amount: priceHigh.Int64, Currency: order.Price.Currency},
+			}
+		}
+	}
+
+	return dest, finish
+}
+
+// customerDest is the customerColumns counterpart of orderDest
+func customerDest(customer *models.Customer) ([]interface{}, func()) {
+	var telegramID sql.NullInt64
+	var telegramTag, vehicleModel, vehiclePlate sql.NullString
+	var rating sql.NullFloat64
+
+	dest := []interface{}{
+		&customer.UUID, &customer.Name, &customer.Phone, &telegramID, &telegramTag, &customer.CreatedAt,
+		&vehicleModel, &vehiclePlate, &rating, &customer.ReviewsCount,
+	}
+
+	finish := func() {
+		if telegramID.Valid {
+			customer.TelegramID = &telegramID.Int64
+		}
+		if telegramTag.Valid {
+			customer.TelegramTag = &telegramTag.String
+		}
+		if vehicleModel.Valid {
+			customer.VehicleModel = &vehicleModel.String
+		}
+		if vehiclePlate.Valid {
+			customer.VehiclePlate = &vehiclePlate.String
+		}
+		if rating.Valid {
+			customer.Rating = &rating.Float64
+		}
+	}
+
+	return dest, finish
+}
+
+func scanOrder(row rowScanner) (models.Order, error) {
+	var order models.Order
+	dest, finish := orderDest(&order)
+	if err := row.Scan(dest...); err != nil {
+		return models.Order{}, err
+	}
+	finish()
+	return order, nil
+}
+
+// scanOrderWithCustomer scans orderColumns followed by customerColumns
+func scanOrderWithCustomer(row rowScanner) (models.Order, error) {
+	var order models.Order
+	var customer models.Customer
+	orderFields, finishOrder := orderDest(&order)
+	customerFields, finishCustomer := customerDest(&customer)
+	if err := row.Scan(append(orderFields, customerFields...)...); err != nil {
+		return models.Order{}, err
+	}
+	finishOrder()
+	finishCustomer()
+	order.Customer = &customer
+	return order, nil
+}
+
+func scanCustomer(row rowScanner) (models.Customer, error) {
+	var customer models.Customer
+	dest, finish := customerDest(&customer)
+	if err := row.Scan(dest...); err != nil {
+		return models.Customer{}, err
+	}
+	finish()
+	return customer, nil
+}
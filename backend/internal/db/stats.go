@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"gruzy-ryadom/internal/models"
+)
+
+const topRoutesLimit = 5
+
+// GetStats computes aggregate statistics for orders and customers created
+// since the given time (all time if since is nil). bucket is "day" or "week"
+// and sets the granularity of the time series.
+func (db *DB) GetStats(ctx context.Context, since *time.Time, bucket string) (models.Stats, error) {
+	stats := models.Stats{
+		Since:          since,
+		Bucket:         bucket,
+		OrdersByStatus: map[models.OrderStatus]int{},
+	}
+
+	// A nil since is passed as NULL and disables the period condition
+	var sinceArg interface{}
+	if since != nil {
+		sinceArg = *since
+	}
+
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM customers),
+			(SELECT COUNT(*) FROM orders),
+			(SELECT COUNT(*) FROM customers WHERE $1::timestamp IS NULL OR created_at >= $1),
+			(SELECT COUNT(*) FROM orders WHERE $1::timestamp IS NULL OR created_at >= $1),
+			(SELECT COUNT(*) FROM customers WHERE last_active_at IS NOT NULL AND ($1::timestamp IS NULL OR last_active_at >= $1)),
+			(SELECT COUNT(*) FROM order_views WHERE $1::timestamp IS NULL OR created_at >= $1),
+			(SELECT COUNT(DISTINCT (viewer_uuid, order_uuid)) FROM contact_reveals WHERE $1::timestamp IS NULL OR created_at >= $1)
+	`, sinceArg).Scan(
+		&stats.TotalCustomers, &stats.TotalOrders, &stats.NewCustomers, &stats.NewOrders, &stats.ActiveUsers,
+		&stats.OrderViews, &stats.ContactReveals,
+	)
+	if err != nil {
+		return models.Stats{}, fmt.Errorf("failed to count totals: %w", err)
+	}
+
+	// Orders by status
+	rows, err := db.QueryContext(ctx, `
+		SELECT status, COUNT(*) FROM orders
+		WHERE $1::timestamp IS NULL OR created_at >= $1
+		GROUP BY status
+	`, sinceArg)
+	if err != nil {
+		return models.Stats{}, fmt.Errorf("failed to count orders by status: %w", err)
+	}
+	for rows.Next() {
+		var status models.OrderStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			rows.Close()
+			return models.Stats{}, fmt.Errorf("failed to scan order status: %w", err)
+		}
+		stats.OrdersByStatus[status] = count
+	}
+	rows.Close()
+
+	// GMV and median price per kg, per currency
+	rows, err = db.QueryContext(ctx, `
+		SELECT
+			currency,
+			SUM(price_minor),
+			round(percentile_cont(0.5) WITHIN GROUP (ORDER BY price_minor / weight_kg) FILTER (WHERE weight_kg > 0))::bigint
+		FROM orders
+		WHERE status <> 'cancelled' AND ($1::timestamp IS NULL OR created_at >= $1)
+		GROUP BY currency
+		ORDER BY SUM(price_minor) DESC
+	`, sinceArg)
+	if err != nil {
+		return models.Stats{}, fmt.Errorf("failed to compute prices: %w", err)
+	}
+	for rows.Next() {
+		var gmv models.Money
+		var medianPerKg sql.NullInt64
+		if err := rows.Scan(&gmv.Currency, &gmv.Amount, &medianPerKg); err != nil {
+			rows.Close()
+			return models.Stats{}, fmt.Errorf("failed to scan prices: %w", err)
+		}
+		stats.GMV = append(stats.GMV, gmv)
+		if medianPerKg.Valid {
+			stats.MedianPricePerKg = append(stats.MedianPricePerKg, models.Money{Amount: medianPerKg.Int64, Currency: gmv.Currency})
+		}
+	}
+	rows.Close()
+
+	// Top routes
+	rows, err = db.QueryContext(ctx, `
+		SELECT from_location, to_location, currency, COUNT(*), round(AVG(price_minor))::bigint
+		FROM orders
+		WHERE from_location IS NOT NULL AND to_location IS NOT NULL
+			AND ($1::timestamp IS NULL OR created_at >= $1)
+		GROUP BY from_location, to_location, currency
+		ORDER BY COUNT(*) DESC, AVG(price_minor) DESC
+		LIMIT $2
+	`, sinceArg, topRoutesLimit)
+	if err != nil {
+		return models.Stats{}, fmt.Errorf("failed to query top routes: %w", err)
+	}
+	for rows.Next() {
+		var route models.RouteStats
+		err := rows.Scan(&route.From, &route.To, &route.AvgPrice.Currency, &route.Orders, &route.AvgPrice.Amount)
+		if err != nil {
+			rows.Close()
+			return models.Stats{}, fmt.Errorf("failed to scan route: %w", err)
+		}
+		stats.TopRoutes = append(stats.TopRoutes, route)
+	}
+	rows.Close()
+
+	if stats.OrdersSeries, err = db.countSeries(ctx, "orders", sinceArg, bucket); err != nil {
+		return models.Stats{}, err
+	}
+	if stats.CustomerSeries, err = db.countSeries(ctx, "customers", sinceArg, bucket); err != nil {
+		return models.Stats{}, err
+	}
+
+	return stats, nil
+}
+
+// countSeries counts rows of a table per day or week of created_at
+func (db *DB) countSeries(ctx context.Context, table string, since interface{}, bucket string) ([]models.DailyCount, error) {
+	if bucket != "day" && bucket != "week" {
+		return nil, fmt.Errorf("unknown bucket %q", bucket)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT date_trunc('%s', created_at) AS day, COUNT(*)
+		FROM %s
+		WHERE $1::timestamp IS NULL OR created_at >= $1
+		GROUP BY day
+		ORDER BY day
+	`, bucket, table)
+
+	rows, err := db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s series: %w", table, err)
+	}
+	defer rows.Close()
+
+	var series []models.DailyCount
+	for rows.Next() {
+		var point models.DailyCount
+		if err := rows.Scan(&point.Day, &point.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s series: %w", table, err)
+		}
+		series = append(series, point)
+	}
+
+	return series, nil
+}
+
+// TouchCustomer records driver bot activity, at most once per few minutes per user
+func (db *DB) TouchCustomer(ctx context.Context, telegramID int64) error {
+	query := `
+		UPDATE customers SET last_active_at = now()
+		WHERE telegram_id = $1
+			AND (last_active_at IS NULL OR last_active_at < now() - interval '5 minutes')
+	`
+	if _, err := db.ExecContext(ctx, query, telegramID); err != nil {
+		return fmt.Errorf("failed to touch customer: %w", err)
+	}
+	return nil
+}
+
+// GetPriceDistribution splits non-cancelled order prices in a currency into
+// equal-width buckets between the minimum and the 95th percentile; pricier
+// orders fall into the last bucket.
+func (db *DB) GetPriceDistribution(ctx context.Context, since *time.Time, currency models.Currency, buckets int) ([]models.PriceBucket, error) {
+	var sinceArg interface{}
+	if since != nil {
+		sinceArg = *since
+	}
+
+	var lo, hi sql.NullFloat64
+	err := db.QueryRowContext(ctx, `
+		SELECT MIN(price_minor)::float8, percentile_cont(0.95) WITHIN GROUP (ORDER BY price_minor)
+		FROM orders
+		WHERE status <> 'cancelled' AND currency = $2 AND ($1::timestamp IS NULL OR created_at >= $1)
+	`, sinceArg, currency).Scan(&lo, &hi)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute price bounds: %w", err)
+	}
+	if !lo.Valid || !hi.Valid {
+		return nil, nil
+	}
+	if hi.Float64 <= lo.Float64 {
+		hi.Float64 = lo.Float64 + 100
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT LEAST(GREATEST(width_bucket(price_minor::float8, $3, $4, $5), 1), $5) AS bucket, COUNT(*)
+		FROM orders
+		WHERE status <> 'cancelled' AND currency = $2 AND ($1::timestamp IS NULL OR created_at >= $1)
+		GROUP BY bucket
+	`, sinceArg, currency, lo.Float64, hi.Float64, buckets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price distribution: %w", err)
+	}
+	defer rows.Close()
+
+	step := (hi.Float64 - lo.Float64) / float64(buckets)
+	distribution := make([]models.PriceBucket, buckets)
+	for i := range distribution {
+		distribution[i].From = models.Money{Amount: int64(math.Round(lo.Float64 + step*float64(i))), Currency: currency}
+		distribution[i].To = models.Money{Amount: int64(math.Round(lo.Float64 + step*float64(i+1))), Currency: currency}
+	}
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan price bucket: %w", err)
+		}
+		distribution[bucket-1].Orders = count
+	}
+
+	return distribution, nil
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// replaceStops stores the stops of an order in place of its previous ones,
+// numbering them in the given order
+func replaceStops(ctx context.Context, tx *sql.Tx, orderID uuid.UUID, stops []models.OrderStop) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM order_stops WHERE order_uuid = $1", orderID); err != nil {
+		return fmt.Errorf("failed to delete order stops: %w", err)
+	}
+
+	query := `
+		INSERT INTO order_stops (order_uuid, sequence, address, city, lat, lon, window_from, window_to, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	for i, stop := range stops {
+		_, err := tx.ExecContext(ctx, query, orderID, i, stop.Address, stop.City, stop.Lat, stop.Lon,
+			stop.WindowFrom, stop.WindowTo, stop.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to insert order stop: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gruzy-ryadom/internal/models"
+)
+
+// Order views methods
+
+// InsertOrderView records the first time a viewer opens an order; repeated views are ignored
+func (db *DB) InsertOrderView(ctx context.Context, orderID uuid.UUID, viewerKey string, channel models.Channel) error {
+	query := `
+		INSERT INTO order_views (order_uuid, viewer_key, channel) VALUES ($1, $2, $3)
+		ON CONFLICT (order_uuid, viewer_key) DO NOTHING
+	`
+	if _, err := db.ExecContext(ctx, query, orderID, viewerKey, channel); err != nil {
+		return fmt.Errorf("failed to insert order view: %w", err)
+	}
+	return nil
+}
+
+// GetOrderStats counts unique viewers and drivers who opened the contacts of the given orders
+func (db *DB) GetOrderStats(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.OrderStats, error) {
+	query := `
+		SELECT o.uuid,
+			(SELECT COUNT(*) FROM order_views v WHERE v.order_uuid = o.uuid),
+			(SELECT COUNT(DISTINCT r.viewer_uuid) FROM contact_reveals r WHERE r.order_uuid = o.uuid)
+		FROM orders o
+		WHERE o.uuid = ANY($1)
+	`
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[uuid.UUID]models.OrderStats, len(ids))
+	for rows.Next() {
+		var s models.OrderStats
+		if err := rows.Scan(&s.OrderUUID, &s.Views, &s.ContactReveals); err != nil {
+			return nil, fmt.Errorf("failed to scan order stats: %w", err)
+		}
+		stats[s.OrderUUID] = s
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,292 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gruzy-ryadom/internal/models"
+)
+
+const (
+	webhookColumns = `w.uuid, w.url, w.secret, w.events, w.active, w.created_at`
+
+	webhookDeliveryColumns = `d.uuid, d.webhook_uuid, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+		d.response_status, d.last_error, d.created_at, d.delivered_at`
+)
+
+// Webhooks methods
+func (db *DB) CreateWebhook(ctx context.Context, input models.CreateWebhookInput) (models.Webhook, error) {
+	query := `
+		INSERT INTO webhooks AS w (url, secret, events)
+		VALUES ($1, $2, $3)
+		RETURNING ` + webhookColumns
+
+	webhook, err := scanWebhook(db.QueryRowContext(ctx, query, input.URL, input.Secret, pq.Array(input.Events)))
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (db *DB) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks w ORDER BY w.created_at"
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+func (db *DB) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	query := "SELECT " + webhookColumns + " FROM webhooks w WHERE w.uuid = $1"
+
+	webhook, err := scanWebhook(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook changes the given fields of a webhook and returns nil if it does not exist
+func (db *DB) UpdateWebhook(ctx context.Context, id uuid.UUID, input models.UpdateWebhookInput) (*models.Webhook, error) {
+	var sets []string
+	args := []interface{}{}
+	set := func(column string, arg interface{}) {
+		args = append(args, arg)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if input.URL != nil {
+		set("url", *input.URL)
+	}
+	if input.Events != nil {
+		set("events", pq.Array(*input.Events))
+	}
+	if input.Active != nil {
+		set("active", *input.Active)
+	}
+	if len(sets) == 0 {
+		return db.GetWebhook(ctx, id)
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE webhooks w SET %s WHERE w.uuid = $%d RETURNING %s",
+		strings.Join(sets, ", "), len(args), webhookColumns)
+
+	webhook, err := scanWebhook(db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+func (db *DB) DeleteWebhook(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM webhooks WHERE uuid = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Webhook deliveries methods
+
+// EnqueueWebhookDeliveries queues an event for every active webhook subscribed
+// to it, or only for the given webhook if one is set, and returns the number
+// queued. Deliveries already queued for the same outbox event are skipped.
+func (db *DB) EnqueueWebhookDeliveries(ctx context.Context, event models.WebhookEvent, payload []byte, webhookUUID *uuid.UUID, outboxID *int64) (int, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_uuid, event, payload, outbox_id)
+		SELECT w.uuid, $1::text, $2::jsonb, $4::bigint FROM webhooks w
+		WHERE w.active AND ($3::uuid IS NULL AND $1 = ANY(w.events) OR w.uuid = $3)
+		ON CONFLICT (webhook_uuid, outbox_id, event) WHERE outbox_id IS NOT NULL DO NOTHING
+	`
+	result, err := db.ExecContext(ctx, query, event, payload, webhookUUID, outboxID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return int(affected), nil
+}
+
+// ClaimWebhookDeliveries picks due deliveries of active webhooks and counts the
+// attempt. The claimed deliveries are postponed by lease, so another instance
+// does not send them meanwhile and they are retried if this one dies.
+func (db *DB) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookAttempt, error) {
+	query := `
+		WITH due AS (
+			SELECT d.uuid FROM webhook_deliveries d
+			JOIN webhooks w ON w.uuid = d.webhook_uuid
+			WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND w.active
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = now() + $2::float8 * interval '1 second'
+		FROM due, webhooks w
+		WHERE d.uuid = due.uuid AND w.uuid = d.webhook_uuid
+		RETURNING ` + webhookDeliveryColumns + `, w.url, w.secret`
+
+	rows, err := db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []models.WebhookAttempt
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		dest, finish := webhookDeliveryDest(&attempt.Delivery)
+		if err := rows.Scan(append(dest, &attempt.URL, &attempt.Secret)...); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		finish()
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// FinishWebhookAttempt records the outcome of an attempt. A pending status
+// schedules the next one retryAfter from now.
+func (db *DB) FinishWebhookAttempt(ctx context.Context, id uuid.UUID, status string, responseStatus *int, attemptErr *string, retryAfter time.Duration) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2::text, response_status = $3, last_error = $4,
+			next_attempt_at = CASE WHEN $2 = 'pending' THEN now() + $5::float8 * interval '1 second' ELSE next_attempt_at END,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN now() END
+		WHERE uuid = $1
+	`
+	if _, err := db.ExecContext(ctx, query, id, status, responseStatus, attemptErr, retryAfter.Seconds()); err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// RetryWebhookDelivery schedules a failed delivery for another round of attempts
+// and returns nil if it does not exist or is not failed
+func (db *DB) RetryWebhookDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE d.uuid = $1 AND d.status = 'failed'
+		RETURNING ` + webhookDeliveryColumns
+
+	delivery, err := scanWebhookDelivery(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to retry webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ListWebhookDeliveries returns the delivery log of a webhook, newest first
+func (db *DB) ListWebhookDeliveries(ctx context.Context, webhookUUID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := "SELECT " + webhookDeliveryColumns + ` FROM webhook_deliveries d
+		WHERE d.webhook_uuid = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.created_at DESC LIMIT $3`
+
+	rows, err := db.QueryContext(ctx, query, webhookUUID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events []string
+	err := row.Scan(&webhook.UUID, &webhook.URL, &webhook.Secret, pq.Array(&events), &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.Events = make([]models.WebhookEvent, 0, len(events))
+	for _, event := range events {
+		webhook.Events = append(webhook.Events, models.WebhookEvent(event))
+	}
+	return webhook, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	dest, finish := webhookDeliveryDest(&delivery)
+	if err := row.Scan(dest...); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	finish()
+	return delivery, nil
+}
+
+// webhookDeliveryDest returns scan destinations for webhookDeliveryColumns and
+// a function that copies the nullable values into the delivery after the scan
+func webhookDeliveryDest(delivery *models.WebhookDelivery) ([]interface{}, func()) {
+	var nextAttemptAt time.Time
+	var responseStatus sql.NullInt64
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+
+	dest := []interface{}{
+		&delivery.UUID, &delivery.WebhookUUID, &delivery.Event, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &nextAttemptAt, &responseStatus, &lastError, &delivery.CreatedAt, &deliveredAt,
+	}
+	finish := func() {
+		if delivery.Status == models.WebhookPending {
+			delivery.NextAttemptAt = &nextAttemptAt
+		}
+		if responseStatus.Valid {
+			status := int(responseStatus.Int64)
+			delivery.ResponseStatus = &status
+		}
+		if lastError.Valid {
+			delivery.LastError = &lastError.String
+		}
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+	}
+	return dest, finish
+}
@@ -0,0 +1,68 @@
+// Package export writes tables to CSV and XLSX files row by row, so that
+// large exports are streamed instead of being built in memory, and reads
+// such files back for imports.
+package export
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Format is a spreadsheet file format
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// FormatOf guesses the format of a file by its name
+func FormatOf(filename string) (Format, bool) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return CSV, true
+	case ".xlsx":
+		return XLSX, true
+	}
+	return "", false
+}
+
+// ParseFormat accepts a format name in any case; an empty one means CSV
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case "":
+		return CSV, nil
+	case CSV, XLSX:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q", name)
+}
+
+// ContentType is the MIME type of files of the format
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes a table row by row. Values may be strings, numbers,
+// time.Time, pointers to them or nil for an empty cell.
+type Writer interface {
+	WriteRow(values ...interface{}) error
+	// Close finishes the file; it does not close the underlying writer
+	Close() error
+}
+
+// NewWriter starts a file of the format; sheet names the XLSX worksheet
+func NewWriter(w io.Writer, format Format, sheet string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w)
+	case XLSX:
+		return newXLSXWriter(w, sheet)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
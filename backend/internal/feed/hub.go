@@ -0,0 +1,264 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/db"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/service"
+)
+
+const (
+	// Notifications arriving within the window are matched against the
+	// subscribers' filters with one query per distinct filter
+	batchWindow = 200 * time.Millisecond
+
+	// A subscriber that falls this far behind is disconnected
+	subscriberBuffer = 64
+
+	listenerPing    = 90 * time.Second
+	reconnectPeriod = 10 * time.Second
+)
+
+// Hub pushes changes of published orders to subscribers whose filter they
+// match. Changes are announced through Postgres notifications, so a hub sees
+// the changes made by every instance of the application.
+type Hub struct {
+	service     *service.Service
+	databaseURL string
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool // Run has returned; new subscriptions end at once
+}
+
+// Subscription receives the feed events matching a filter until it is closed
+type Subscription struct {
+	hub    *Hub
+	filter models.OrderFilter
+	key    string
+	events chan models.FeedEvent
+}
+
+func NewHub(service *service.Service, databaseURL string) *Hub {
+	return &Hub{
+		service:     service,
+		databaseURL: databaseURL,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe starts receiving the orders matching filter; pagination and sorting are ignored
+func (h *Hub) Subscribe(filter models.OrderFilter) *Subscription {
+	filter.Page, filter.Limit, filter.SortBy, filter.SortOrder = 0, 0, "", ""
+	filter.UUIDs = nil
+	key, _ := json.Marshal(filter)
+
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		key:    string(key),
+		events: make(chan models.FeedEvent, subscriberBuffer),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Events is closed when the subscription ends, including when the subscriber
+// was too slow to keep up or the hub is stopped
+func (s *Subscription) Events() <-chan models.FeedEvent {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.drop(sub)
+	}
+	log.Println("Order feed stopped")
+}
+
+// drop removes a subscriber; h.mu must be held
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Run listens for order changes until ctx is cancelled, then ends all
+// subscriptions so that open streams do not hold up the server shutdown
+func (h *Hub) Run(ctx context.Context) {
+	log.Println("Order feed started...")
+	defer h.close()
+	for {
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Feed: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectPeriod):
+		}
+	}
+}
+
+func (h *Hub) listen(ctx context.Context) error {
+	listener, err := db.Listen(ctx, h.databaseURL, service.FeedChannel)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	ping := time.NewTicker(listenerPing)
+	defer ping.Stop()
+
+	pending := make(map[uuid.UUID]change)
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ping.C:
+			go listener.Ping()
+		case notification := <-listener.Notify:
+			if notification == nil {
+				continue // reconnected, changes made meanwhile are missed
+			}
+			var event models.FeedNotification
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				log.Printf("Feed: invalid notification %q: %v", notification.Extra, err)
+				continue
+			}
+			// An order created and updated within the window is still new to
+			// subscribers, and the state before the window is what they have seen
+			previous, ok := pending[event.UUID]
+			if !ok {
+				pending[event.UUID] = change{typ: event.Type, event: event.Event}
+			} else if previous.typ != models.FeedCreated || event.Type == models.FeedRemoved {
+				pending[event.UUID] = change{typ: event.Type, event: previous.event}
+			}
+			if flush == nil {
+				flush = time.After(batchWindow)
+			}
+		case <-flush:
+			h.publish(ctx, pending)
+			pending = make(map[uuid.UUID]change)
+			flush = nil
+		}
+	}
+}
+
+// change is a pending change of an order; event is the outbox event of its
+// first change within the batch window
+type change struct {
+	typ   string
+	event int64
+}
+
+// publish sends the pending changes to the subscribers they concern. An order
+// that matches a filter now is sent as created or updated; one that matched
+// it before the change but no longer does is sent as removed.
+func (h *Hub) publish(ctx context.Context, pending map[uuid.UUID]change) {
+	h.mu.Lock()
+	filters := make(map[string]models.OrderFilter)
+	for sub := range h.subscribers {
+		filters[sub.key] = sub.filter
+	}
+	h.mu.Unlock()
+	if len(filters) == 0 {
+		return
+	}
+
+	var changed []uuid.UUID
+	var eventIDs []int64
+	for id, c := range pending {
+		if c.typ != models.FeedRemoved {
+			changed = append(changed, id)
+		}
+		eventIDs = append(eventIDs, c.event)
+	}
+	before, err := h.service.PublishedBefore(ctx, eventIDs)
+	if err != nil {
+		// Without the previous states removals go to everyone
+		log.Printf("Feed: failed to load previous states: %v", err)
+	}
+
+	// The orders are matched with the same query as GET /v1/orders
+	for key, filter := range filters {
+		current := make(map[uuid.UUID]models.Order)
+		if len(changed) > 0 {
+			filter.UUIDs = changed
+			filter.Page, filter.Limit = 1, len(changed)
+			orders, _, err := h.service.ListOrders(ctx, filter)
+			if err != nil {
+				log.Printf("Feed: failed to match orders: %v", err)
+				continue
+			}
+			for _, order := range orders {
+				current[order.UUID] = order
+			}
+		}
+
+		var events []models.FeedEvent
+		for id, c := range pending {
+			if order, ok := current[id]; ok {
+				public := order.Public()
+				events = append(events, models.FeedEvent{Type: c.typ, UUID: id, Order: &public})
+				continue
+			}
+			previous, known := before[c.event]
+			if (known && matches(filter, previous)) || (before == nil && c.typ == models.FeedRemoved) {
+				events = append(events, models.FeedEvent{Type: models.FeedRemoved, UUID: id})
+			}
+		}
+		h.broadcast(events, key)
+	}
+}
+
+// broadcast sends events to the subscribers with the given filter key
+func (h *Hub) broadcast(events []models.FeedEvent, key string) {
+	if len(events) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.key != key {
+			continue
+		}
+		for _, event := range events {
+			select {
+			case sub.events <- event:
+				continue
+			default:
+			}
+			log.Printf("Feed: dropping a subscriber that fell behind")
+			h.drop(sub)
+			break
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package feed
+
+import (
+	"strings"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// matches reports whether a published order matched a filter. It repeats
+// the conditions of the SQL filter for the state of an order before a
+// change, which is no longer in the database; current states are matched
+// with ListOrders.
+func matches(filter models.OrderFilter, order models.Order) bool {
+	if filter.CustomerUUID != nil && order.CustomerUUID != *filter.CustomerUUID {
+		return false
+	}
+	if !within(order.WeightKg, filter.MinWeight, filter.MaxWeight) ||
+		!withinOptional(order.LengthCm, filter.MinLength, filter.MaxLength) ||
+		!withinOptional(order.WidthCm, filter.MinWidth, filter.MaxWidth) ||
+		!withinOptional(order.HeightCm, filter.MinHeight, filter.MaxHeight) {
+		return false
+	}
+
+	currency := filter.Currency
+	if currency == "" && (filter.MinPrice > 0 || filter.MaxPrice > 0) {
+		currency = models.DefaultCurrency
+	}
+	if currency != "" && order.Price.Currency != currency {
+		return false
+	}
+	if (filter.MinPrice > 0 && order.Price.Amount < filter.MinPrice) ||
+		(filter.MaxPrice > 0 && order.Price.Amount > filter.MaxPrice) {
+		return false
+	}
+
+	if len(filter.Tags) > 0 && !sharesTag(order.Tags, filter.Tags) {
+		return false
+	}
+
+	switch {
+	case filter.From != "" && filter.To != "":
+		if !hasLeg(order.Stops, filter.From, filter.To) {
+			return false
+		}
+	case filter.From != "":
+		if firstStop(order.Stops, filter.From) < 0 {
+			return false
+		}
+	case filter.To != "":
+		if firstStop(order.Stops, filter.To) < 0 {
+			return false
+		}
+	}
+	if filter.Location != "" && firstStop(order.Stops, filter.Location) < 0 {
+		return false
+	}
+	return true
+}
+
+func within(value, min, max float64) bool {
+	return (min <= 0 || value >= min) && (max <= 0 || value <= max)
+}
+
+// withinOptional fails a bound on a missing value, as SQL compares with NULL
+func withinOptional(value *float64, min, max float64) bool {
+	if value == nil {
+		return min <= 0 && max <= 0
+	}
+	return within(*value, min, max)
+}
+
+func sharesTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasLeg reports whether a stop matching from comes before one matching to
+func hasLeg(stops []models.OrderStop, from, to string) bool {
+	first := firstStop(stops, from)
+	if first < 0 {
+		return false
+	}
+	for _, stop := range stops[first+1:] {
+		if stopMatches(stop, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstStop returns the index of the first stop matching query, or -1
+func firstStop(stops []models.OrderStop, query string) int {
+	for i, stop := range stops {
+		if stopMatches(stop, query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// stopMatches is ILIKE '%query%' on the address or the city
+func stopMatches(stop models.OrderStop, query string) bool {
+	query = strings.ToLower(query)
+	if strings.Contains(strings.ToLower(stop.Address), query) {
+		return true
+	}
+	return stop.City != nil && strings.Contains(strings.ToLower(*stop.City), query)
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 	"github.com/google/uuid"
 )
@@ -13,6 +15,12 @@ type Customer struct {
 	TelegramID  *int64    `json:"telegram_id,omitempty" db:"telegram_id"`
 	TelegramTag *string   `json:"telegram_tag,omitempty" db:"telegram_tag"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// Vehicle of a driver, printed on waybills
+	VehicleModel *string `json:"vehicle_model,omitempty" db:"vehicle_model"`
+	VehiclePlate *string `json:"vehicle_plate,omitempty" db:"vehicle_plate"`
+	// Reputation: average rating of reviews about the customer
+	Rating       *float64 `json:"rating,omitempty"`
+	ReviewsCount int      `json:"reviews_count"`
 }
 
 // Order represents an order in the system
@@ -28,23 +36,88 @@ type Order struct {
 	FromLocation  *string   `json:"from_location,omitempty" db:"from_location"`
 	ToLocation    *string   `json:"to_location,omitempty" db:"to_location"`
 	Tags          []string  `json:"tags" db:"tags"`
-	Price         float64   `json:"price" db:"price"`
+	Price         Money     `json:"price" db:"price"`
 	AvailableFrom *time.Time `json:"available_from,omitempty" db:"available_from"`
+	Status        OrderStatus `json:"status" db:"status"`
+	ModerationStatus ModerationStatus `json:"moderation_status" db:"moderation_status"`
+	ModerationReason *string          `json:"moderation_reason,omitempty" db:"moderation_reason"`
+	Hidden           bool             `json:"hidden,omitempty" db:"hidden"`
+	CarrierUUID      *uuid.UUID       `json:"carrier_uuid,omitempty" db:"carrier_uuid"`
+	PriceCheck       *PriceCheck      `json:"price_check,omitempty"`
+	Stops            []OrderStop      `json:"stops,omitempty"`
+	PhotosCount      int              `json:"photos_count"`
+	CoverFileID      *string          `json:"-"` // Telegram file ID of the first photo
 	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 	Customer      *Customer `json:"customer,omitempty"`
 }
 
+// OrderStop is a pickup or drop-off point of an order; stops are visited in
+// the order of Sequence, the first is the origin and the last the destination
+type OrderStop struct {
+	Sequence   int        `json:"sequence"`
+	Address    string     `json:"address"`
+	City       *string    `json:"city,omitempty"`
+	Lat        *float64   `json:"lat,omitempty"`
+	Lon        *float64   `json:"lon,omitempty"`
+	WindowFrom *time.Time `json:"window_from,omitempty"`
+	WindowTo   *time.Time `json:"window_to,omitempty"`
+	Notes      *string    `json:"notes,omitempty"` // cargo to load or unload here
+}
+
+// OrderPhoto is a picture of the cargo; the file and its thumbnail are kept
+// in the photo storage under the keys
+type OrderPhoto struct {
+	UUID           uuid.UUID `json:"uuid"`
+	OrderUUID      uuid.UUID `json:"order_uuid"`
+	Position       int       `json:"position"`
+	TelegramFileID *string   `json:"-"`
+	StorageKey     string    `json:"-"`
+	ThumbnailKey   string    `json:"-"`
+	ContentType    string    `json:"content_type"`
+	SizeBytes      int64     `json:"size_bytes"`
+	Width          int       `json:"width"`
+	Height         int       `json:"height"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ModerationStatus is the pre-publication review state of an order
+type ModerationStatus string
+
+const (
+	ModerationPending  ModerationStatus = "pending"
+	ModerationApproved ModerationStatus = "approved"
+	ModerationRejected ModerationStatus = "rejected"
+)
+
+// OrderStatus is the lifecycle state of an order
+type OrderStatus string
+
+const (
+	OrderOpen       OrderStatus = "open"
+	OrderInProgress OrderStatus = "in_progress"
+	OrderCompleted  OrderStatus = "completed"
+	OrderCancelled  OrderStatus = "cancelled"
+)
+
 // OrderFilter represents filters for listing orders
 type OrderFilter struct {
 	MinWeight, MaxWeight   float64
 	MinLength, MaxLength   float64
 	MinWidth, MaxWidth     float64
 	MinHeight, MaxHeight   float64
-	MinPrice, MaxPrice     float64
+	MinPrice, MaxPrice     int64    // minor units of Currency
+	Currency               Currency // defaults to DefaultCurrency when a price bound is set
 	Tags                   []string
-	From, To               string
+	From, To               string // a stop matching From comes before one matching To
+	Location               string // matches any stop
 	Page, Limit            int
 	SortBy, SortOrder      string
+	CustomerUUID           *uuid.UUID
+	UUIDs                  []uuid.UUID // limits the listing to these orders
+	// Public listings only show approved, visible orders of customers who are
+	// not banned; admin views set these
+	IncludeUnmoderated bool
+	IncludeHidden      bool
 }
 
 // CustomerFilter represents filters for listing customers
@@ -67,8 +140,13 @@ type CreateOrderInput struct {
 	FromLocation  *string
 	ToLocation    *string
 	Tags          []string
-	Price         float64
+	Price         Money
 	AvailableFrom *time.Time
+	// At least two stops replace FromLocation and ToLocation
+	Stops []OrderStop
+	// Set by the service: pending unless the customer is auto-approved
+	ModerationStatus ModerationStatus
+	PriceCheck       *PriceCheck
 }
 
 // UpdateOrderInput represents input for updating an order
@@ -82,8 +160,13 @@ type UpdateOrderInput struct {
 	FromLocation  *string
 	ToLocation    *string
 	Tags          *[]string
-	Price         *float64
+	Price         *Money
+	Stops         *[]OrderStop // replaces all stops and the from and to locations
 	AvailableFrom *time.Time
+	Status        *OrderStatus
+	Hidden        *bool
+	// Set by the service when the price or the cargo changes
+	PriceCheck *PriceCheck
 }
 
 // CreateCustomerInput represents input for creating a customer
@@ -100,12 +183,631 @@ type UpdateCustomerInput struct {
 	Phone       *string
 	TelegramID  *int64
 	TelegramTag *string
+	VehicleModel *string
+	VehiclePlate *string
 }
 
 // OrdersResponse represents the response for listing orders
 type OrdersResponse struct {
-	Page   int     `json:"page"`
-	Limit  int     `json:"limit"`
-	Total  int     `json:"total"`
-	Orders []Order `json:"orders"`
+	Page   int           `json:"page"`
+	Limit  int           `json:"limit"`
+	Total  int           `json:"total"`
+	Orders []PublicOrder `json:"orders"`
+}
+
+// PublicCustomer is the part of a customer shown to anyone: no contacts
+type PublicCustomer struct {
+	UUID         uuid.UUID `json:"uuid"`
+	Name         string    `json:"name"`
+	Rating       *float64  `json:"rating,omitempty"`
+	ReviewsCount int       `json:"reviews_count"`
+}
+
+// Public strips the contacts of a customer
+func (c Customer) Public() PublicCustomer {
+	return PublicCustomer{
+		UUID:         c.UUID,
+		Name:         c.Name,
+		Rating:       c.Rating,
+		ReviewsCount: c.ReviewsCount,
+	}
+}
+
+// PublicOrder is an order as listed to anonymous callers. Contacts of the
+// customer are revealed separately to authenticated drivers.
+type PublicOrder struct {
+	UUID          uuid.UUID       `json:"uuid"`
+	CustomerUUID  uuid.UUID       `json:"customer_uuid"`
+	Title         string          `json:"title"`
+	Description   *string         `json:"description,omitempty"`
+	WeightKg      float64         `json:"weight_kg"`
+	LengthCm      *float64        `json:"length_cm,omitempty"`
+	WidthCm       *float64        `json:"width_cm,omitempty"`
+	HeightCm      *float64        `json:"height_cm,omitempty"`
+	FromLocation  *string         `json:"from_location,omitempty"`
+	ToLocation    *string         `json:"to_location,omitempty"`
+	Tags          []string        `json:"tags"`
+	Price         Money           `json:"price"`
+	PricePerKg    *Money          `json:"price_per_kg,omitempty"`
+	Stops         []OrderStop     `json:"stops,omitempty"`
+	PhotosCount   int             `json:"photos_count"`
+	AvailableFrom *time.Time      `json:"available_from,omitempty"`
+	Status        OrderStatus     `json:"status"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Customer      *PublicCustomer `json:"customer,omitempty"`
+}
+
+// Public strips the moderation details and the customer's contacts of an order
+func (o Order) Public() PublicOrder {
+	public := PublicOrder{
+		UUID:          o.UUID,
+		CustomerUUID:  o.CustomerUUID,
+		Title:         o.Title,
+		Description:   o.Description,
+		WeightKg:      o.WeightKg,
+		LengthCm:      o.LengthCm,
+		WidthCm:       o.WidthCm,
+		HeightCm:      o.HeightCm,
+		FromLocation:  o.FromLocation,
+		ToLocation:    o.ToLocation,
+		Tags:          o.Tags,
+		Price:         o.Price,
+		Stops:         o.Stops,
+		PhotosCount:   o.PhotosCount,
+		AvailableFrom: o.AvailableFrom,
+		Status:        o.Status,
+		CreatedAt:     o.CreatedAt,
+	}
+	if perKg, ok := o.PricePerKg(); ok {
+		public.PricePerKg = &perKg
+	}
+	if o.Customer != nil {
+		customer := o.Customer.Public()
+		public.Customer = &customer
+	}
+	return public
+}
+
+// PricePerKg is the price of a kilogram of the cargo, if the weight is known
+func (o Order) PricePerKg() (Money, bool) {
+	return o.Price.Per(o.WeightKg)
+}
+
+// Contacts are the private details of an order's customer revealed to a driver
+type Contacts struct {
+	OrderUUID   uuid.UUID `json:"order_uuid"`
+	Name        string    `json:"name"`
+	Phone       string    `json:"phone,omitempty"`
+	TelegramID  *int64    `json:"telegram_id,omitempty"`
+	TelegramTag *string   `json:"telegram_tag,omitempty"`
+}
+
+// ReferrerStats represents a customer together with the number of customers they invited
+type ReferrerStats struct {
+	Customer  Customer `json:"customer"`
+	Referrals int      `json:"referrals"`
+}
+
+// AdminRole is the access level of an admin bot user
+type AdminRole string
+
+const (
+	RoleModerator  AdminRole = "moderator"
+	RoleSuperadmin AdminRole = "superadmin"
+)
+
+// Allows reports whether the role grants at least the required access level
+func (r AdminRole) Allows(required AdminRole) bool {
+	switch r {
+	case RoleSuperadmin:
+		return true
+	case RoleModerator:
+		return required == RoleModerator
+	default:
+		return false
+	}
+}
+
+// Admin represents an admin bot user managed from the bot
+type Admin struct {
+	TelegramID int64     `json:"telegram_id" db:"telegram_id"`
+	Role       AdminRole `json:"role" db:"role"`
+	AddedBy    *int64    `json:"added_by,omitempty" db:"added_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+
+// SavedSearch represents a search a driver subscribed to
+type SavedSearch struct {
+	UUID       uuid.UUID `json:"uuid" db:"uuid"`
+	TelegramID int64     `json:"telegram_id" db:"telegram_id"`
+	Query      string    `json:"query" db:"query"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// BroadcastAudience selects the recipients of a broadcast
+type BroadcastAudience string
+
+const (
+	AudienceAll       BroadcastAudience = "all"       // everyone who started the driver bot
+	AudienceCustomers BroadcastAudience = "customers" // users who posted at least one order
+	AudienceDrivers   BroadcastAudience = "drivers"   // users without orders
+	AudienceRegion    BroadcastAudience = "region"    // users with a saved search mentioning a region
+)
+
+// BroadcastStatus is the lifecycle state of a broadcast
+type BroadcastStatus string
+
+const (
+	BroadcastPending   BroadcastStatus = "pending"
+	BroadcastRunning   BroadcastStatus = "running"
+	BroadcastDone      BroadcastStatus = "done"
+	BroadcastCancelled BroadcastStatus = "cancelled"
+)
+
+// Delivery statuses of a single broadcast recipient
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliveryBlocked = "blocked"
+)
+
+// Broadcast represents a mass message and its delivery progress
+type Broadcast struct {
+	UUID              uuid.UUID         `json:"uuid" db:"uuid"`
+	CreatedBy         int64             `json:"created_by" db:"created_by"`
+	Audience          BroadcastAudience `json:"audience" db:"audience"`
+	Region            *string           `json:"region,omitempty" db:"region"`
+	Text              string            `json:"text" db:"text"`
+	Entities          []byte            `json:"-" db:"entities"` // JSON-encoded message entities
+	Photo             []byte            `json:"-" db:"photo"`
+	PhotoFileID       *string           `json:"-" db:"photo_file_id"`
+	Status            BroadcastStatus   `json:"status" db:"status"`
+	Total             int               `json:"total" db:"total"`
+	Sent              int               `json:"sent" db:"sent"`
+	Failed            int               `json:"failed" db:"failed"`
+	Blocked           int               `json:"blocked" db:"blocked"`
+	ProgressChatID    *int64            `json:"-" db:"progress_chat_id"`
+	ProgressMessageID *int              `json:"-" db:"progress_message_id"`
+	CreatedAt         time.Time         `json:"created_at" db:"created_at"`
+	FinishedAt        *time.Time        `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// CreateBroadcastInput represents input for creating a broadcast
+type CreateBroadcastInput struct {
+	CreatedBy int64
+	Audience  BroadcastAudience
+	Region    *string
+	Text      string
+	Entities  []byte
+	Photo     []byte
+}
+
+// StatsPeriod selects the time window of admin statistics
+type StatsPeriod string
+
+const (
+	PeriodDay   StatsPeriod = "day"
+	PeriodWeek  StatsPeriod = "week"
+	PeriodMonth StatsPeriod = "month"
+	PeriodAll   StatsPeriod = "all"
+)
+
+// Stats represents aggregate figures for the admin bot
+type Stats struct {
+	Period StatsPeriod
+	Since  *time.Time // nil for all time
+	Bucket string     // "day" or "week", granularity of the series
+
+	TotalCustomers int
+	TotalOrders    int
+	NewCustomers   int
+	NewOrders      int
+	ActiveUsers    int
+
+	OrderViews     int // unique viewers of orders, counted when they first opened an order
+	ContactReveals int // unique driver and order pairs
+
+	OrdersByStatus   map[OrderStatus]int
+	GMV              []Money // sum of prices of non-cancelled orders, per currency
+	MedianPricePerKg []Money // per currency that has orders with weight
+
+	TopRoutes      []RouteStats
+	OrdersSeries   []DailyCount
+	CustomerSeries []DailyCount
+}
+
+// RouteStats represents the number of orders on a route
+type RouteStats struct {
+	From     string
+	To       string
+	Orders   int
+	AvgPrice Money
+}
+
+// DailyCount is a point of a time series; Day is the start of the bucket
+type DailyCount struct {
+	Day   time.Time
+	Count int
+}
+
+// PriceBucket is a histogram bar of order prices in [From, To)
+type PriceBucket struct {
+	From   Money
+	To     Money
+	Orders int
+}
+
+// ModerationHistory summarises previous moderation decisions for a customer
+type ModerationHistory struct {
+	Trusted  bool
+	Approved int
+	Rejected int
+}
+
+
+// Ban blocks a user by exactly one of TelegramID, Phone or CustomerUUID
+type Ban struct {
+	UUID         uuid.UUID
+	TelegramID   *int64
+	Phone        *string
+	CustomerUUID *uuid.UUID
+	Reason       string
+	ExpiresAt    *time.Time // nil means permanent
+	CreatedBy    int64
+	CreatedAt    time.Time
+}
+
+// CreateBanInput represents input for banning a user
+type CreateBanInput struct {
+	TelegramID   *int64
+	Phone        *string
+	CustomerUUID *uuid.UUID
+	Reason       string
+	ExpiresAt    *time.Time
+	CreatedBy    int64
+}
+
+// Channel is the interface through which a change was made
+type Channel string
+
+const (
+	ChannelAPI       Channel = "api"
+	ChannelDriverBot Channel = "driver-bot"
+	ChannelAdminBot  Channel = "admin-bot"
+	ChannelSystem    Channel = "system"
+)
+
+// Actor identifies who made a change and in which request
+type Actor struct {
+	Channel    Channel
+	TelegramID *int64
+	RequestID  string
+}
+
+// Audit log entity types and actions
+const (
+	AuditOrder    = "order"
+	AuditCustomer = "customer"
+
+	AuditCreate = "create"
+	AuditUpdate = "update"
+)
+
+// FieldChange is the old and new value of a changed field
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry is a record of the append-only audit log
+type AuditEntry struct {
+	ID         int64                  `json:"id"`
+	CreatedAt  time.Time              `json:"created_at"`
+	Channel    Channel                `json:"channel"`
+	ActorID    *int64                 `json:"actor_id,omitempty"`
+	RequestID  *string                `json:"request_id,omitempty"`
+	EntityType string                 `json:"entity_type"`
+	EntityUUID uuid.UUID              `json:"entity_uuid"`
+	Action     string                 `json:"action"`
+	Changes    map[string]FieldChange `json:"changes"`
+}
+
+// AuditFilter represents filters for querying the audit log
+type AuditFilter struct {
+	EntityType  string
+	EntityUUID  *uuid.UUID
+	ActorID     *int64
+	Channel     Channel
+	Page, Limit int
+}
+
+// AuditResponse represents a page of the audit log
+type AuditResponse struct {
+	Page    int          `json:"page"`
+	Limit   int          `json:"limit"`
+	Total   int          `json:"total"`
+	Entries []AuditEntry `json:"entries"`
+}
+
+// Review is a rating left by one side of a completed order about the other
+type Review struct {
+	UUID       uuid.UUID  `json:"uuid"`
+	OrderUUID  uuid.UUID  `json:"order_uuid"`
+	AuthorUUID uuid.UUID  `json:"author_uuid"`
+	TargetUUID uuid.UUID  `json:"target_uuid"`
+	Rating     int        `json:"rating"`
+	Comment    *string    `json:"comment,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RemovedAt  *time.Time `json:"removed_at,omitempty"`
+	AuthorName string     `json:"author_name"`
+	TargetName string     `json:"target_name"`
+}
+
+// CreateReviewInput represents input for rating the other side of an order
+type CreateReviewInput struct {
+	OrderUUID  uuid.UUID
+	AuthorUUID uuid.UUID
+	Rating     int
+	Comment    *string
+}
+
+// ReviewFilter represents filters for listing reviews
+type ReviewFilter struct {
+	TargetUUID     *uuid.UUID
+	IncludeRemoved bool
+	Limit          int
+}
+
+// ChatThread is an anonymous conversation between a driver and the customer of an order
+type ChatThread struct {
+	UUID             uuid.UUID `json:"uuid"`
+	OrderUUID        uuid.UUID `json:"order_uuid"`
+	OrderTitle       string    `json:"order_title"`
+	DriverUUID       uuid.UUID `json:"driver_uuid"`
+	DriverName       string    `json:"driver_name"`
+	CustomerUUID     uuid.UUID `json:"customer_uuid"`
+	CustomerName     string    `json:"customer_name"`
+	DriverRevealed   bool      `json:"driver_revealed"`
+	CustomerRevealed bool      `json:"customer_revealed"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ChatMessage is a message relayed within a chat thread
+type ChatMessage struct {
+	ID         int64     `json:"id"`
+	ThreadUUID uuid.UUID `json:"thread_uuid"`
+	SenderUUID uuid.UUID `json:"sender_uuid"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OrderStats shows the owner of an order how many people are interested in it
+type OrderStats struct {
+	OrderUUID      uuid.UUID `json:"order_uuid"`
+	Views          int       `json:"views"`
+	ContactReveals int       `json:"contact_reveals"`
+}
+
+// PriceFlag marks an order price far from the recommended range
+type PriceFlag string
+
+const (
+	PriceTooLow  PriceFlag = "low"
+	PriceTooHigh PriceFlag = "high"
+)
+
+// PriceCheck is the recommended range an order price was compared with;
+// Flag is empty if the price is within reason
+type PriceCheck struct {
+	Flag PriceFlag `json:"flag,omitempty"`
+	Low  Money     `json:"low"`
+	High Money     `json:"high"`
+}
+
+// PriceEstimateInput describes the cargo to estimate the price for
+type PriceEstimateInput struct {
+	From     *string
+	To       *string
+	WeightKg float64
+	LengthCm *float64
+	WidthCm  *float64
+	HeightCm *float64
+	Tags     []string
+	Date     *time.Time // loading date, today if nil
+	Currency Currency   // of the past orders to compare with
+}
+
+// PriceEstimate is a recommended price range based on similar past orders
+type PriceEstimate struct {
+	Low        Money    `json:"low"`
+	Median     Money    `json:"median"`
+	High       Money    `json:"high"`
+	Samples    int      `json:"samples"`
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+}
+
+// PriceSample is a past order used for price estimates
+type PriceSample struct {
+	From      *string
+	To        *string
+	WeightKg  float64
+	LengthCm  *float64
+	WidthCm   *float64
+	HeightCm  *float64
+	Tags      []string
+	Price     Money
+	CreatedAt time.Time
+}
+
+// ImportResult reports a bulk import of orders from a spreadsheet
+type ImportResult struct {
+	DryRun  bool              `json:"dry_run"`
+	Rows    int               `json:"rows"` // data rows, without the header
+	Valid   int               `json:"valid"`
+	Columns map[string]string `json:"columns"` // order field -> header of the column it was read from
+	Created []uuid.UUID       `json:"created,omitempty"`
+	Preview []ImportPreview   `json:"preview"`
+	Errors  []ImportRowError  `json:"errors"`
+}
+
+// ImportPreview shows how a valid row of an import is created
+type ImportPreview struct {
+	Row              int              `json:"row"`
+	Title            string           `json:"title"`
+	ModerationStatus ModerationStatus `json:"moderation_status"`
+	PriceCheck       *PriceCheck      `json:"price_check,omitempty"`
+}
+
+// ImportRowError explains why a row of an import was skipped
+type ImportRowError struct {
+	Row     int    `json:"row"` // line in the file, the header is 1
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"` // "required" or "invalid"
+	Message string `json:"message"`
+}
+
+// WebhookEvent is the type of an order event sent to webhooks
+type WebhookEvent string
+
+const (
+	EventOrderCreated       WebhookEvent = "order.created"
+	EventOrderUpdated       WebhookEvent = "order.updated"
+	EventOrderStatusChanged WebhookEvent = "order.status_changed"
+	EventOrderRemoved       WebhookEvent = "order.removed" // hidden or rejected, with the order as last published
+	EventPing               WebhookEvent = "ping"          // sent on request to check an endpoint
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []WebhookEvent{EventOrderCreated, EventOrderUpdated, EventOrderStatusChanged, EventOrderRemoved}
+
+// Webhook is a partner endpoint subscribed to order events
+type Webhook struct {
+	UUID      uuid.UUID      `json:"uuid" db:"uuid"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"secret,omitempty" db:"secret"` // only returned on creation
+	Events    []WebhookEvent `json:"events" db:"events"`
+	Active    bool           `json:"active" db:"active"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+// CreateWebhookInput represents input for subscribing an endpoint;
+// a secret is generated if none is given
+type CreateWebhookInput struct {
+	URL    string         `json:"url"`
+	Secret string         `json:"secret,omitempty"`
+	Events []WebhookEvent `json:"events"`
+}
+
+// UpdateWebhookInput represents input for changing a subscription
+type UpdateWebhookInput struct {
+	URL    *string         `json:"url,omitempty"`
+	Events *[]WebhookEvent `json:"events,omitempty"`
+	Active *bool           `json:"active,omitempty"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed" // gave up after the last retry
+)
+
+// WebhookDelivery is an event sent to a webhook and the outcome of the last attempt
+type WebhookDelivery struct {
+	UUID           uuid.UUID       `json:"uuid" db:"uuid"`
+	WebhookUUID    uuid.UUID       `json:"webhook_uuid" db:"webhook_uuid"`
+	Event          WebhookEvent    `json:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"` // while pending
+	ResponseStatus *int            `json:"response_status,omitempty" db:"response_status"`
+	LastError      *string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+// WebhookAttempt is a due delivery claimed by the dispatcher with the endpoint to send it to
+type WebhookAttempt struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookPayload is the body of a webhook request
+type WebhookPayload struct {
+	Event     WebhookEvent `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Order     *PublicOrder `json:"order,omitempty"`
+	// Set on order.status_changed
+	PreviousStatus *OrderStatus `json:"previous_status,omitempty"`
+}
+
+// DomainEventType names a change recorded in the outbox
+type DomainEventType string
+
+const (
+	OrderCreated    DomainEventType = "OrderCreated"
+	OrderUpdated    DomainEventType = "OrderUpdated"
+	CustomerCreated DomainEventType = "CustomerCreated"
+)
+
+// DomainEvent is a change stored in the outbox in the transaction that made it
+type DomainEvent struct {
+	ID            int64           `json:"id" db:"id"`
+	Type          DomainEventType `json:"type" db:"event_type"`
+	AggregateUUID uuid.UUID       `json:"aggregate_uuid" db:"aggregate_uuid"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	Attempts      int             `json:"attempts" db:"attempts"` // including the current one
+}
+
+// NewDomainEvent encodes the payload of an event
+func NewDomainEvent(eventType DomainEventType, aggregateUUID uuid.UUID, payload interface{}) (DomainEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return DomainEvent{}, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	return DomainEvent{Type: eventType, AggregateUUID: aggregateUUID, Payload: data}, nil
+}
+
+// OrderCreatedEvent is the payload of OrderCreated
+type OrderCreatedEvent struct {
+	Order Order `json:"order"`
+}
+
+// OrderUpdatedEvent is the payload of OrderUpdated, sent for any change of an
+// order including its status, moderation and visibility
+type OrderUpdatedEvent struct {
+	Before Order `json:"before"`
+	After  Order `json:"after"`
+}
+
+// CustomerCreatedEvent is the payload of CustomerCreated
+type CustomerCreatedEvent struct {
+	Customer Customer `json:"customer"`
+}
+
+// Live feed event types
+const (
+	FeedCreated = "created" // an order was published
+	FeedUpdated = "updated"
+	FeedRemoved = "removed" // an order was hidden or withdrawn from publication
+)
+
+// FeedEvent is a change of a published order pushed to live feed clients
+type FeedEvent struct {
+	Type  string       `json:"type"`
+	UUID  uuid.UUID    `json:"uuid"`
+	Order *PublicOrder `json:"order,omitempty"` // not set for removed orders
+}
+
+// FeedNotification announces a change to the live feeds of all instances;
+// Event is the outbox event of the change, with the state before it
+type FeedNotification struct {
+	Type  string    `json:"type"`
+	UUID  uuid.UUID `json:"uuid"`
+	Event int64     `json:"event"`
 }
@@ -0,0 +1,197 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 code of a currency orders can be priced in
+type Currency string
+
+const (
+	RUB Currency = "RUB"
+	KZT Currency = "KZT"
+	BYN Currency = "BYN"
+)
+
+// DefaultCurrency is assumed when a price comes without a currency
+const DefaultCurrency = RUB
+
+// Currencies lists the supported currencies in display order
+var Currencies = []Currency{RUB, KZT, BYN}
+
+var currencySymbols = map[Currency]string{
+	RUB: "₽",
+	KZT: "₸",
+	BYN: "Br",
+}
+
+// ParseCurrency accepts an ISO code in any case
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := currencySymbols[currency]; !ok {
+		return "", fmt.Errorf("unsupported currency %q", code)
+	}
+	return currency, nil
+}
+
+// Symbol returns the sign shown after amounts, e.g. "₽"
+func (c Currency) Symbol() string {
+	if symbol, ok := currencySymbols[c]; ok {
+		return symbol
+	}
+	return string(c)
+}
+
+// Money is an exact amount in minor units (kopecks, tiyns) of a currency.
+// All supported currencies have two decimal places.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+const minorPerUnit = 100
+
+// NewMoney makes an amount of whole units
+func NewMoney(units int64, currency Currency) Money {
+	return Money{Amount: units * minorPerUnit, Currency: currency}
+}
+
+// ParseMoney reads a decimal amount like "5000", "5 000,50" or "1500.5"
+// without going through float64
+func ParseMoney(text string, currency Currency) (Money, error) {
+	clean := strings.NewReplacer(" ", "", " ", "", "_", "").Replace(strings.TrimSpace(text))
+	clean = strings.Replace(clean, ",", ".", 1)
+
+	whole, frac, _ := strings.Cut(clean, ".")
+	if !isDigits(whole) || len(frac) > 2 || (frac != "" && !isDigits(frac)) || len(whole) > 16 {
+		return Money{}, fmt.Errorf("invalid amount %q", text)
+	}
+	units, _ := strconv.ParseInt(whole, 10, 64)
+	minor := int64(0)
+	if frac != "" {
+		minor, _ = strconv.ParseInt(frac+strings.Repeat("0", 2-len(frac)), 10, 64)
+	}
+	return Money{Amount: units*minorPerUnit + minor, Currency: currency}, nil
+}
+
+func isDigits(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float is the amount in units, for statistics and estimates only
+func (m Money) Float() float64 {
+	return float64(m.Amount) / minorPerUnit
+}
+
+// MoneyFromFloat rounds an amount in units to minor units
+func MoneyFromFloat(units float64, currency Currency) Money {
+	return Money{Amount: int64(math.Round(units * minorPerUnit)), Currency: currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Decimal renders the amount with a dot and two decimals, e.g. "5000.50"
+func (m Money) Decimal() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorPerUnit, amount%minorPerUnit)
+}
+
+// String renders the amount for people: "5 000 ₽", "5 000,50 ₽"
+func (m Money) String() string {
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := strconv.FormatInt(amount/minorPerUnit, 10)
+	var units strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			units.WriteRune(' ')
+		}
+		units.WriteRune(digit)
+	}
+
+	text := sign + units.String()
+	if cents := amount % minorPerUnit; cents != 0 {
+		text += fmt.Sprintf(",%02d", cents)
+	}
+	return text + " " + m.Currency.Symbol()
+}
+
+// Per divides the amount by a quantity, e.g. a price by its weight, rounding
+// to the nearest minor unit
+func (m Money) Per(quantity float64) (Money, bool) {
+	if quantity <= 0 {
+		return Money{}, false
+	}
+	return Money{Amount: int64(math.Round(float64(m.Amount) / quantity)), Currency: m.Currency}, true
+}
+
+// MarshalJSON writes the amount as a decimal string to keep it exact:
+// {"amount": "5000.00", "currency": "RUB"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or a number; the
+// currency defaults to DefaultCurrency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Amount   interface{} `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	currency := DefaultCurrency
+	if raw.Currency != "" {
+		var err error
+		if currency, err = ParseCurrency(raw.Currency); err != nil {
+			return err
+		}
+	}
+
+	var amount string
+	switch value := raw.Amount.(type) {
+	case string:
+		amount = value
+	case json.Number:
+		amount = value.String()
+	default:
+		return fmt.Errorf("invalid amount %v", raw.Amount)
+	}
+
+	parsed, err := ParseMoney(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
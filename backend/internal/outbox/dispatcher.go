@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/retry"
+	"gruzy-ryadom/internal/service"
+)
+
+const (
+	dispatchBatchSize = 100
+	dispatchIdlePoll  = time.Second
+
+	// A claimed event is handled again after the lease if the instance dies meanwhile
+	claimLease = time.Minute
+
+	// Failed events are retried after retryBase, twice as long each time, up to retryMax
+	retryBase = 5 * time.Second
+	retryMax  = time.Hour
+
+	// Processed events are kept for a while to investigate problems
+	retention     = 7 * 24 * time.Hour
+	purgeInterval = time.Hour
+)
+
+// Handler reacts to a domain event. Delivery is at least once: a handler may
+// see an event again after a crash or after another handler failed, so it
+// must be idempotent.
+type Handler func(ctx context.Context, event models.DomainEvent) error
+
+type namedHandler struct {
+	name    string
+	handler Handler
+}
+
+// Dispatcher hands the events of the outbox to the registered handlers in
+// the order they were written. An event is processed once every handler has
+// succeeded; until then it is retried with exponential backoff.
+type Dispatcher struct {
+	service  *service.Service
+	handlers map[models.DomainEventType][]namedHandler
+}
+
+func NewDispatcher(service *service.Service) *Dispatcher {
+	return &Dispatcher{
+		service:  service,
+		handlers: make(map[models.DomainEventType][]namedHandler),
+	}
+}
+
+// Handle registers a handler of an event type; the name identifies it in logs.
+// Handlers must be registered before Run.
+func (d *Dispatcher) Handle(eventType models.DomainEventType, name string, handler Handler) {
+	d.handlers[eventType] = append(d.handlers[eventType], namedHandler{name: name, handler: handler})
+}
+
+// Run dispatches events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Println("Outbox dispatcher started...")
+	lastPurge := time.Time{}
+	for {
+		if time.Since(lastPurge) > purgeInterval {
+			if purged, err := d.service.PurgeEvents(ctx, retention); err != nil {
+				log.Printf("Outbox: %v", err)
+			} else if purged > 0 {
+				log.Printf("Outbox: purged %d processed events", purged)
+			}
+			lastPurge = time.Now()
+		}
+
+		events, err := d.service.ClaimEvents(ctx, dispatchBatchSize, claimLease)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Outbox: failed to claim events: %v", err)
+		}
+		for _, event := range events {
+			if ctx.Err() != nil {
+				break // the lease expires and the rest is handled later
+			}
+			d.dispatch(ctx, event)
+		}
+
+		// A full batch means more events are probably waiting
+		if len(events) == dispatchBatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox dispatcher stopped")
+			return
+		case <-time.After(dispatchIdlePoll):
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event models.DomainEvent) {
+	var failures []string
+	for _, h := range d.handlers[event.Type] {
+		if err := d.call(ctx, h, event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", h.name, err))
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Record the outcome even if we are being stopped, so the event is not handled twice
+	ctx = context.WithoutCancel(ctx)
+	if len(failures) == 0 {
+		if err := d.service.CompleteEvent(ctx, event.ID); err != nil {
+			log.Printf("Outbox: %v", err)
+		}
+		return
+	}
+
+	handlerErr := strings.Join(failures, "; ")
+	log.Printf("Outbox: %s %d failed (attempt %d): %s", event.Type, event.ID, event.Attempts, handlerErr)
+	if err := d.service.RetryEvent(ctx, event.ID, retry.Backoff(event.Attempts, retryBase, retryMax), handlerErr); err != nil {
+		log.Printf("Outbox: %v", err)
+	}
+}
+
+// call runs a handler, turning a panic into an error so one bad event does not stop the dispatcher
+func (d *Dispatcher) call(ctx context.Context, h namedHandler, event models.DomainEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.handler(ctx, event)
+}
@@ -0,0 +1,17 @@
+// Package retry computes delays between attempts of background jobs.
+package retry
+
+import "time"
+
+// Backoff returns the delay before the retry that follows the given attempt:
+// base after the first one, twice as long after each next one, up to max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
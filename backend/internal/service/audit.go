@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+type actorKey struct{}
+
+// WithActor attaches the author of the changes made with ctx for the audit log
+func WithActor(ctx context.Context, actor models.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor attached to ctx; changes without one are
+// attributed to the system
+func ActorFrom(ctx context.Context) models.Actor {
+	if actor, ok := ctx.Value(actorKey{}).(models.Actor); ok {
+		return actor
+	}
+	return models.Actor{Channel: models.ChannelSystem}
+}
+
+// Audit log methods
+func (s *Service) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, int, error) {
+	return s.db.ListAudit(ctx, filter)
+}
+
+// audit records a change of an entity. before is nil for created entities.
+// The change itself has already been committed, so a failure is only logged.
+func (s *Service) audit(ctx context.Context, entityType string, id uuid.UUID, action string, before, after interface{}) {
+	changes, err := diff(before, after)
+	if err != nil {
+		log.Printf("Audit: failed to diff %s %s: %v", entityType, id, err)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	actor := ActorFrom(ctx)
+	entry := models.AuditEntry{
+		Channel:    actor.Channel,
+		ActorID:    actor.TelegramID,
+		EntityType: entityType,
+		EntityUUID: id,
+		Action:     action,
+		Changes:    changes,
+	}
+	if actor.RequestID != "" {
+		entry.RequestID = &actor.RequestID
+	}
+
+	if err := s.db.InsertAudit(context.WithoutCancel(ctx), entry); err != nil {
+		log.Printf("Audit: %v", err)
+	}
+}
+
+// auditSkipped are fields of audited entities that belong to other entities,
+// such as the customer embedded in an order
+var auditSkipped = map[string]bool{"customer": true}
+
+// diff compares the JSON representations of two values leaf by leaf; nested
+// objects are compared by their fields, e.g. price.amount and price.currency
+func diff(before, after interface{}) (map[string]models.FieldChange, error) {
+	old, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.FieldChange{}
+	for key, value := range updated {
+		if prev, ok := old[key]; !ok || !reflect.DeepEqual(prev, value) {
+			changes[key] = models.FieldChange{Before: old[key], After: value}
+		}
+	}
+	for key, prev := range old {
+		if _, ok := updated[key]; !ok {
+			changes[key] = models.FieldChange{Before: prev}
+		}
+	}
+	return changes, nil
+}
+
+// jsonFields flattens the JSON representation of a value into dotted paths
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	for key := range auditSkipped {
+		delete(object, key)
+	}
+	flatten(fields, "", object)
+	return fields, nil
+}
+
+func flatten(fields map[string]interface{}, prefix string, object map[string]interface{}) {
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(fields, prefix+key+".", nested)
+			continue
+		}
+		fields[prefix+key] = value
+	}
+}
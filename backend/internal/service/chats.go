@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// Telegram limits a message to 4096 characters, leave room for the relay header
+const maxChatMessage = 3500
+
+// Chats methods
+
+// OpenChat starts or resumes the conversation of a driver with the customer of a published order
+func (s *Service) OpenChat(ctx context.Context, orderID string, driverUUID uuid.UUID) (models.ChatThread, error) {
+	id, err := parseUUID(orderID)
+	if err != nil {
+		return models.ChatThread{}, err
+	}
+
+	order, err := s.db.GetOrder(ctx, id)
+	if err != nil {
+		return models.ChatThread{}, err
+	}
+	if order == nil || order.ModerationStatus != models.ModerationApproved || order.Hidden {
+		return models.ChatThread{}, fmt.Errorf("order %s not found", orderID)
+	}
+	if order.CustomerUUID == driverUUID {
+		return models.ChatThread{}, fmt.Errorf("customer cannot chat about their own order")
+	}
+
+	ban, err := s.db.GetActiveBanByCustomer(ctx, driverUUID)
+	if err != nil {
+		return models.ChatThread{}, err
+	}
+	if ban != nil {
+		return models.ChatThread{}, fmt.Errorf("%w: %s", ErrBanned, ban.Reason)
+	}
+
+	return s.db.GetOrCreateChatThread(ctx, id, driverUUID)
+}
+
+func (s *Service) GetChatThread(ctx context.Context, id uuid.UUID) (*models.ChatThread, error) {
+	return s.db.GetChatThread(ctx, id)
+}
+
+func (s *Service) ListChatThreads(ctx context.Context, orderID uuid.UUID) ([]models.ChatThread, error) {
+	return s.db.ListChatThreads(ctx, orderID)
+}
+
+func (s *Service) ListChatMessages(ctx context.Context, threadID uuid.UUID, limit int) ([]models.ChatMessage, error) {
+	return s.db.ListChatMessages(ctx, threadID, limit)
+}
+
+// SendChatMessage stores a message of one side of a conversation before it is relayed
+func (s *Service) SendChatMessage(ctx context.Context, thread models.ChatThread, senderUUID uuid.UUID, text string) (models.ChatMessage, error) {
+	if senderUUID != thread.DriverUUID && senderUUID != thread.CustomerUUID {
+		return models.ChatMessage{}, fmt.Errorf("%s is not a party of chat %s", senderUUID, thread.UUID)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.ChatMessage{}, fmt.Errorf("message is empty")
+	}
+	if runes := []rune(text); len(runes) > maxChatMessage {
+		return models.ChatMessage{}, fmt.Errorf("message is longer than %d characters", maxChatMessage)
+	}
+
+	return s.db.InsertChatMessage(ctx, thread.UUID, senderUUID, text)
+}
+
+// RevealChatContacts records that a side of a conversation chose to share their contacts
+func (s *Service) RevealChatContacts(ctx context.Context, thread models.ChatThread, senderUUID uuid.UUID) error {
+	switch senderUUID {
+	case thread.DriverUUID:
+		return s.db.SetChatRevealed(ctx, thread.UUID, true)
+	case thread.CustomerUUID:
+		return s.db.SetChatRevealed(ctx, thread.UUID, false)
+	default:
+		return fmt.Errorf("%s is not a party of chat %s", senderUUID, thread.UUID)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"math"
+	"strings"
+)
+
+// Roads are longer than the great-circle distance by about this factor
+const roadFactor = 1.25
+
+type city struct {
+	name     string
+	lat, lon float64
+}
+
+// cities are the largest cities of Russia and neighbouring countries, used to
+// recognize a route in a free-form location and estimate its distance
+var cities = []city{
+	{"москва", 55.7558, 37.6173},
+	{"санкт-петербург", 59.9343, 30.3351},
+	{"новосибирск", 55.0084, 82.9357},
+	{"екатеринбург", 56.8389, 60.6057},
+	{"казань", 55.7961, 49.1064},
+	{"нижний новгород", 56.2965, 43.9361},
+	{"челябинск", 55.1644, 61.4368},
+	{"красноярск", 56.0153, 92.8932},
+	{"самара", 53.1959, 50.1002},
+	{"уфа", 54.7388, 55.9721},
+	{"ростов-на-дону", 47.2357, 39.7015},
+	{"омск", 54.9885, 73.3242},
+	{"краснодар", 45.0355, 38.9753},
+	{"воронеж", 51.6608, 39.2003},
+	{"пермь", 58.0105, 56.2502},
+	{"волгоград", 48.7080, 44.5133},
+	{"саратов", 51.5336, 46.0343},
+	{"тюмень", 57.1530, 65.5343},
+	{"тольятти", 53.5303, 49.3461},
+	{"ижевск", 56.8526, 53.2045},
+	{"барнаул", 53.3561, 83.7636},
+	{"ульяновск", 54.3142, 48.4031},
+	{"иркутск", 52.2870, 104.3050},
+	{"хабаровск", 48.4827, 135.0838},
+	{"ярославль", 57.6261, 39.8845},
+	{"владивосток", 43.1155, 131.8855},
+	{"махачкала", 42.9849, 47.5047},
+	{"томск", 56.4847, 84.9482},
+	{"оренбург", 51.7682, 55.0970},
+	{"кемерово", 55.3547, 86.0873},
+	{"новокузнецк", 53.7557, 87.1099},
+	{"рязань", 54.6269, 39.6916},
+	{"астрахань", 46.3479, 48.0336},
+	{"пенза", 53.1959, 45.0183},
+	{"липецк", 52.6031, 39.5708},
+	{"тула", 54.1931, 37.6173},
+	{"киров", 58.6035, 49.6680},
+	{"чебоксары", 56.1439, 47.2489},
+	{"калининград", 54.7104, 20.4522},
+	{"брянск", 53.2521, 34.3717},
+	{"курск", 51.7373, 36.1874},
+	{"иваново", 57.0004, 40.9739},
+	{"магнитогорск", 53.4072, 58.9791},
+	{"тверь", 56.8587, 35.9176},
+	{"ставрополь", 45.0428, 41.9734},
+	{"белгород", 50.5997, 36.5983},
+	{"сочи", 43.5855, 39.7231},
+	{"архангельск", 64.5393, 40.5187},
+	{"владимир", 56.1291, 40.4066},
+	{"смоленск", 54.7826, 32.0453},
+	{"калуга", 54.5293, 36.2754},
+	{"мурманск", 68.9585, 33.0827},
+	{"вологда", 59.2181, 39.8886},
+	{"сургут", 61.2540, 73.3962},
+	{"якутск", 62.0355, 129.6755},
+	{"новороссийск", 44.7235, 37.7686},
+	{"псков", 57.8194, 28.3318},
+	{"минск", 53.9006, 27.5590},
+	{"алматы", 43.2389, 76.8897},
+	{"астана", 51.1694, 71.4491},
+}
+
+// cityAliases maps common short names to the names above
+var cityAliases = map[string]string{
+	"спб":        "санкт-петербург",
+	"питер":      "санкт-петербург",
+	"мск":        "москва",
+	"нск":        "новосибирск",
+	"екб":        "екатеринбург",
+	"ростов":     "ростов-на-дону",
+	"н.новгород": "нижний новгород",
+	"нур-султан": "астана",
+}
+
+// findCity recognizes a known city in a free-form location like "Казань, ул. Баумана"
+func findCity(location *string) *city {
+	if location == nil {
+		return nil
+	}
+	text := strings.ReplaceAll(strings.ToLower(*location), "ё", "е")
+
+	for alias, name := range cityAliases {
+		if containsWord(text, alias) {
+			text = name
+			break
+		}
+	}
+	for i := range cities {
+		if strings.Contains(text, cities[i].name) {
+			return &cities[i]
+		}
+	}
+	return nil
+}
+
+// containsWord reports whether text contains word not as a part of a longer word
+func containsWord(text, word string) bool {
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' || r == ';' }) {
+		if field == word {
+			return true
+		}
+	}
+	return false
+}
+
+func sameCity(a, b *city) bool {
+	return a != nil && b != nil && a.name == b.name
+}
+
+// roadDistance approximates the road distance between cities in km
+func roadDistance(a, b city) float64 {
+	const earthRadius = 6371.0
+	lat1, lat2 := a.lat*math.Pi/180, b.lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.lon - a.lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h)) * roadFactor
+}
+
+// title capitalizes the city name: "ростов-на-дону" becomes "Ростов-на-Дону"
+func (c city) title() string {
+	words := strings.FieldsFunc(c.name, func(r rune) bool { return r == ' ' || r == '-' })
+	title := c.name
+	for _, word := range words {
+		if word == "на" {
+			continue
+		}
+		runes := []rune(word)
+		title = strings.Replace(title, word, strings.ToUpper(string(runes[0]))+string(runes[1:]), 1)
+	}
+	return title
+}
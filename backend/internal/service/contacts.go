@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+var (
+	// ErrRevealLimit is returned when a driver has seen too many contacts today
+	ErrRevealLimit = errors.New("contact reveal limit reached")
+
+	// ErrOrderNotFound is returned for orders that do not exist or are not published
+	ErrOrderNotFound = errors.New("order not found")
+)
+
+// ContactRevealsPerDay caps how many orders' contacts a driver can open in 24 hours
+const ContactRevealsPerDay = 20
+
+// IssueAPIToken creates a new HTTP API token of a customer; previous tokens stop working
+func (s *Service) IssueAPIToken(ctx context.Context, customerUUID uuid.UUID) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	if err := s.db.ReplaceAPIToken(ctx, customerUUID, hashToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// AuthenticateAPIToken returns the customer a token was issued to, or nil
+func (s *Service) AuthenticateAPIToken(ctx context.Context, token string) (*models.Customer, error) {
+	return s.db.GetCustomerByAPIToken(ctx, hashToken(token))
+}
+
+// RevealContacts returns the contacts of an order's customer to a driver and
+// logs the reveal. Opening a new order counts towards the daily cap; the same
+// order can be reopened for free and its carrier is never limited.
+func (s *Service) RevealContacts(ctx context.Context, orderID string, viewerUUID uuid.UUID) (models.Contacts, error) {
+	id, err := parseUUID(orderID)
+	if err != nil {
+		return models.Contacts{}, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+
+	order, err := s.db.GetOrder(ctx, id)
+	if err != nil {
+		return models.Contacts{}, err
+	}
+	if order == nil || order.Customer == nil {
+		return models.Contacts{}, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+
+	isOwner := order.CustomerUUID == viewerUUID
+	isCarrier := order.CarrierUUID != nil && *order.CarrierUUID == viewerUUID
+	if !isOwner {
+		if (order.ModerationStatus != models.ModerationApproved || order.Hidden) && !isCarrier {
+			return models.Contacts{}, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+		}
+
+		ban, err := s.db.GetActiveBanByCustomer(ctx, viewerUUID)
+		if err != nil {
+			return models.Contacts{}, err
+		}
+		if ban != nil {
+			return models.Contacts{}, fmt.Errorf("%w: %s", ErrBanned, ban.Reason)
+		}
+
+		// The carrier is never limited
+		limit := ContactRevealsPerDay
+		if isCarrier {
+			limit = 0
+		}
+		logged, err := s.db.InsertContactReveal(ctx, viewerUUID, id, ActorFrom(ctx).Channel, limit)
+		if err != nil {
+			return models.Contacts{}, err
+		}
+		if !logged {
+			return models.Contacts{}, ErrRevealLimit
+		}
+	}
+
+	return models.Contacts{
+		OrderUUID:   order.UUID,
+		Name:        order.Customer.Name,
+		Phone:       order.Customer.Phone,
+		TelegramID:  order.Customer.TelegramID,
+		TelegramTag: order.Customer.TelegramTag,
+	}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gruzy-ryadom/internal/db"
+	"gruzy-ryadom/internal/models"
+)
+
+// Domain events are written to the outbox together with the change, so
+// handlers never miss a committed change and never see a rolled back one.
+
+// orderCreated records OrderCreated for every created order
+func orderCreated(order models.Order) ([]models.DomainEvent, error) {
+	event, err := models.NewDomainEvent(models.OrderCreated, order.UUID, models.OrderCreatedEvent{Order: order})
+	if err != nil {
+		return nil, err
+	}
+	return []models.DomainEvent{event}, nil
+}
+
+// orderUpdated records OrderUpdated with the state of the order before the write
+func orderUpdated(before models.Order) db.OrderEvents {
+	before.Customer = nil
+	return func(after models.Order) ([]models.DomainEvent, error) {
+		event, err := models.NewDomainEvent(models.OrderUpdated, after.UUID, models.OrderUpdatedEvent{Before: before, After: after})
+		if err != nil {
+			return nil, err
+		}
+		return []models.DomainEvent{event}, nil
+	}
+}
+
+func customerCreated(customer models.Customer) ([]models.DomainEvent, error) {
+	event, err := models.NewDomainEvent(models.CustomerCreated, customer.UUID, models.CustomerCreatedEvent{Customer: customer})
+	if err != nil {
+		return nil, err
+	}
+	return []models.DomainEvent{event}, nil
+}
+
+// Outbox methods
+func (s *Service) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]models.DomainEvent, error) {
+	return s.db.ClaimEvents(ctx, limit, lease)
+}
+
+func (s *Service) CompleteEvent(ctx context.Context, id int64) error {
+	return s.db.CompleteEvent(ctx, id)
+}
+
+func (s *Service) RetryEvent(ctx context.Context, id int64, retryAfter time.Duration, handlerErr string) error {
+	return s.db.RetryEvent(ctx, id, retryAfter, handlerErr)
+}
+
+func (s *Service) PurgeEvents(ctx context.Context, age time.Duration) (int, error) {
+	return s.db.PurgeEvents(ctx, age)
+}
+
+// orderStates decodes the states of an order before and after an order
+// event; before is nil for a created order and both are nil for other events
+func orderStates(event models.DomainEvent) (before, after *models.Order, err error) {
+	switch event.Type {
+	case models.OrderCreated:
+		var payload models.OrderCreatedEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		return nil, &payload.Order, nil
+	case models.OrderUpdated:
+		var payload models.OrderUpdatedEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		return &payload.Before, &payload.After, nil
+	}
+	return nil, nil, nil
+}
+
+// NewlyPublished returns the order an order event makes visible to everyone:
+// created approved, or approved or unhidden later. It is nil for other events.
+func NewlyPublished(event models.DomainEvent) (*models.Order, error) {
+	before, after, err := orderStates(event)
+	if err != nil || after == nil {
+		return nil, err
+	}
+	if !published(*after) || (before != nil && published(*before)) {
+		return nil, nil
+	}
+	return after, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"gruzy-ryadom/internal/export"
+	"gruzy-ryadom/internal/models"
+)
+
+var (
+	orderExportHeader = []interface{}{
+		"uuid", "created_at", "title", "description", "from", "to", "stops", "weight_kg",
+		"length_cm", "width_cm", "height_cm", "tags", "price", "currency", "price_per_kg",
+		"available_from", "status", "photos",
+	}
+	// Admin exports add moderation and the customer's contacts
+	orderExportAdminHeader = []interface{}{
+		"moderation_status", "hidden", "customer_uuid", "customer_name", "customer_phone",
+		"customer_telegram", "carrier_uuid",
+	}
+	customerExportHeader = []interface{}{
+		"uuid", "created_at", "name", "phone", "telegram_id", "telegram_tag",
+		"vehicle_model", "vehicle_plate", "rating", "reviews",
+	}
+)
+
+// ExportOrders writes every order matching the filter, ignoring pagination.
+// Admin exports include unpublished orders and contacts of customers.
+func (s *Service) ExportOrders(ctx context.Context, filter models.OrderFilter, w export.Writer, admin bool) error {
+	header := orderExportHeader
+	if admin {
+		filter.IncludeUnmoderated, filter.IncludeHidden = true, true
+		header = append(append([]interface{}{}, header...), orderExportAdminHeader...)
+	} else {
+		filter.IncludeUnmoderated, filter.IncludeHidden, filter.CustomerUUID = false, false, nil
+	}
+	if err := w.WriteRow(header...); err != nil {
+		return err
+	}
+
+	err := s.db.EachOrder(ctx, filter, func(order models.Order) error {
+		var pricePerKg interface{}
+		if perKg, ok := order.PricePerKg(); ok {
+			pricePerKg = perKg.Float()
+		}
+		row := []interface{}{
+			order.UUID.String(), order.CreatedAt, order.Title, order.Description,
+			order.FromLocation, order.ToLocation, len(order.Stops), order.WeightKg,
+			order.LengthCm, order.WidthCm, order.HeightCm, strings.Join(order.Tags, ", "),
+			order.Price.Float(), string(order.Price.Currency), pricePerKg,
+			order.AvailableFrom, string(order.Status), order.PhotosCount,
+		}
+		if admin {
+			row = append(row, string(order.ModerationStatus), order.Hidden, order.CustomerUUID.String())
+			if customer := order.Customer; customer != nil {
+				row = append(row, customer.Name, customer.Phone, customer.TelegramTag)
+			} else {
+				row = append(row, nil, nil, nil)
+			}
+			if order.CarrierUUID != nil {
+				row = append(row, order.CarrierUUID.String())
+			} else {
+				row = append(row, nil)
+			}
+		}
+		return w.WriteRow(row...)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportCustomers writes every customer matching the filter, ignoring pagination
+func (s *Service) ExportCustomers(ctx context.Context, filter models.CustomerFilter, w export.Writer) error {
+	if err := w.WriteRow(customerExportHeader...); err != nil {
+		return err
+	}
+
+	err := s.db.EachCustomer(ctx, filter, func(customer models.Customer) error {
+		return w.WriteRow(
+			customer.UUID.String(), customer.CreatedAt, customer.Name, customer.Phone,
+			customer.TelegramID, customer.TelegramTag, customer.VehicleModel, customer.VehiclePlate,
+			customer.Rating, customer.ReviewsCount,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
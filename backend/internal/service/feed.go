@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// FeedChannel is the Postgres notification channel of the live order feed
+const FeedChannel = "order_feed"
+
+// NotifyOrderFeed is the outbox handler that announces changes of published
+// orders to the live feeds of all instances. The notification only carries
+// the order UUID and the event ID: the payload of a notification is limited
+// to 8000 bytes.
+func (s *Service) NotifyOrderFeed(ctx context.Context, event models.DomainEvent) error {
+	var change string
+	switch event.Type {
+	case models.OrderCreated:
+		var payload models.OrderCreatedEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		if published(payload.Order) {
+			change = models.FeedCreated
+		}
+	case models.OrderUpdated:
+		var payload models.OrderUpdatedEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		switch was, is := published(payload.Before), published(payload.After); {
+		case was && is:
+			change = models.FeedUpdated
+		case is:
+			change = models.FeedCreated
+		case was:
+			change = models.FeedRemoved
+		}
+	}
+	if change == "" {
+		return nil
+	}
+
+	notification, err := json.Marshal(models.FeedNotification{Type: change, UUID: event.AggregateUUID, Event: event.ID})
+	if err != nil {
+		return fmt.Errorf("failed to encode feed notification: %w", err)
+	}
+	return s.db.Notify(ctx, FeedChannel, notification)
+}
+
+// PublishedBefore returns the states in which orders were published before
+// the given events, by event ID. Orders created by an event or not published
+// before it are absent, as are events that are no longer kept.
+func (s *Service) PublishedBefore(ctx context.Context, eventIDs []int64) (map[int64]models.Order, error) {
+	events, err := s.db.GetEvents(ctx, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[int64]models.Order, len(events))
+	for _, event := range events {
+		before, _, err := orderStates(event)
+		if err != nil {
+			return nil, err
+		}
+		if before != nil && published(*before) {
+			states[event.ID] = *before
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/export"
+	"gruzy-ryadom/internal/models"
+)
+
+const (
+	// MaxImportRows is how many orders one file may have
+	MaxImportRows = 500
+	// MaxImportBytes is the largest spreadsheet accepted
+	MaxImportBytes = 5 << 20
+)
+
+var (
+	// ErrImportColumns is returned when a file lacks the columns of required fields
+	ErrImportColumns = errors.New("required columns are missing")
+
+	// ErrImportTooLarge is returned for files over MaxImportRows rows, also
+	// by the export.ReadRows they are read with
+	ErrImportTooLarge = export.ErrTooManyRows
+)
+
+// importFields are the order fields a column can be mapped to, with the
+// headers recognized for them. "via" lists stops between from and to
+// separated by ";", "dimensions" is "180x60x70".
+var importFields = map[string][]string{
+	"title":          {"title", "заголовок", "название", "наименование", "груз"},
+	"description":    {"description", "описание", "комментарий"},
+	"from":           {"from", "from_location", "откуда", "погрузка", "адрес погрузки"},
+	"to":             {"to", "to_location", "куда", "выгрузка", "адрес выгрузки"},
+	"via":            {"via", "промежуточные точки", "через"},
+	"weight_kg":      {"weight_kg", "weight", "вес", "вес кг"},
+	"length_cm":      {"length_cm", "length", "длина", "длина см"},
+	"width_cm":       {"width_cm", "width", "ширина", "ширина см"},
+	"height_cm":      {"height_cm", "height", "высота", "высота см"},
+	"dimensions":     {"dimensions", "габариты", "габариты см"},
+	"tags":           {"tags", "теги"},
+	"price":          {"price", "цена", "стоимость"},
+	"currency":       {"currency", "валюта"},
+	"available_from": {"available_from", "date", "дата", "дата погрузки", "доступен с"},
+}
+
+var requiredImportFields = []string{"title", "from", "to", "weight_kg", "price"}
+
+// ImportOrders creates orders of a customer from spreadsheet rows, the first
+// of which is the header. Columns are recognized by their headers, mapping
+// overrides them as field -> header. Invalid rows are reported and skipped;
+// the valid ones are created in one transaction unless dryRun is set. Either
+// way the result previews their moderation status and price check.
+func (s *Service) ImportOrders(ctx context.Context, customerUUID uuid.UUID, rows [][]string, mapping map[string]string, dryRun bool) (models.ImportResult, error) {
+	result := models.ImportResult{DryRun: dryRun, Preview: []models.ImportPreview{}, Errors: []models.ImportRowError{}}
+	if len(rows) == 0 {
+		return result, fmt.Errorf("%w: the file is empty", ErrImportColumns)
+	}
+	if len(rows)-1 > MaxImportRows {
+		return result, fmt.Errorf("%w: %d rows, at most %d", ErrImportTooLarge, len(rows)-1, MaxImportRows)
+	}
+
+	ban, err := s.db.GetActiveBanByCustomer(ctx, customerUUID)
+	if err != nil {
+		return result, err
+	}
+	if ban != nil {
+		return result, fmt.Errorf("%w: %s", ErrBanned, ban.Reason)
+	}
+
+	columns, err := mapImportColumns(rows[0], mapping)
+	if err != nil {
+		return result, err
+	}
+	result.Columns = make(map[string]string, len(columns))
+	for field, column := range columns {
+		result.Columns[field] = strings.TrimSpace(rows[0][column])
+	}
+
+	var parsed []models.CreateOrderInput
+	var lines []int
+	for i, row := range rows[1:] {
+		line := i + 2
+		cell := func(field string) string {
+			if column, ok := columns[field]; ok && column < len(row) {
+				return strings.TrimSpace(row[column])
+			}
+			return ""
+		}
+		if isBlankRow(row) {
+			continue
+		}
+		result.Rows++
+
+		input, rowErr := parseImportRow(cell)
+		if rowErr != nil {
+			rowErr.Row = line
+			result.Errors = append(result.Errors, *rowErr)
+			continue
+		}
+		input.CustomerUUID = customerUUID
+		parsed = append(parsed, input)
+		lines = append(lines, line)
+	}
+
+	// Orders are prepared like single ones, and rows they reject are skipped too
+	history, err := s.db.GetModerationHistory(ctx, customerUUID)
+	if err != nil {
+		return result, err
+	}
+	samples := s.newPriceSamples()
+	var inputs []models.CreateOrderInput
+	for i, input := range parsed {
+		input, err := s.prepareOrder(ctx, input, history, samples)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: lines[i], Code: "invalid", Message: err.Error()})
+			continue
+		}
+		inputs = append(inputs, input)
+		result.Preview = append(result.Preview, models.ImportPreview{
+			Row:              lines[i],
+			Title:            input.Title,
+			ModerationStatus: input.ModerationStatus,
+			PriceCheck:       input.PriceCheck,
+		})
+	}
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Row < result.Errors[j].Row })
+	result.Valid = len(inputs)
+
+	if dryRun || len(inputs) == 0 {
+		return result, nil
+	}
+
+	orders, err := s.db.CreateOrders(ctx, inputs, orderCreated)
+	if err != nil {
+		return result, err
+	}
+	for _, order := range orders {
+		order := order
+		s.audit(ctx, models.AuditOrder, order.UUID, models.AuditCreate, nil, &order)
+		result.Created = append(result.Created, order.UUID)
+	}
+	return result, nil
+}
+
+// mapImportColumns finds the column of every field in the header
+func mapImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	byHeader := make(map[string]int, len(header))
+	for i, name := range header {
+		if key := normalizeHeader(name); key != "" {
+			if _, ok := byHeader[key]; !ok {
+				byHeader[key] = i
+			}
+		}
+	}
+
+	columns := make(map[string]int)
+	for field, aliases := range importFields {
+		for _, alias := range aliases {
+			if column, ok := byHeader[normalizeHeader(alias)]; ok {
+				columns[field] = column
+				break
+			}
+		}
+	}
+	for field, name := range mapping {
+		if _, ok := importFields[field]; !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrImportColumns, field)
+		}
+		column, ok := byHeader[normalizeHeader(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: no column %q for %s", ErrImportColumns, name, field)
+		}
+		columns[field] = column
+	}
+
+	var missing []string
+	for _, field := range requiredImportFields {
+		if _, ok := columns[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%w: %s", ErrImportColumns, strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// normalizeHeader makes "Вес, кг" and "вес кг" the same header
+func normalizeHeader(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}), " ")
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportRow converts a row to an order, or explains the first problem with it
+func parseImportRow(cell func(field string) string) (models.CreateOrderInput, *models.ImportRowError) {
+	var input models.CreateOrderInput
+	required := func(field string) *models.ImportRowError {
+		return &models.ImportRowError{Field: field, Code: "required", Message: field + " is required"}
+	}
+	invalid := func(field, format string, args ...interface{}) *models.ImportRowError {
+		return &models.ImportRowError{Field: field, Code: "invalid", Message: fmt.Sprintf(format, args...)}
+	}
+
+	if input.Title = cell("title"); input.Title == "" {
+		return input, required("title")
+	}
+	if description := cell("description"); description != "" {
+		input.Description = &description
+	}
+
+	from, to := cell("from"), cell("to")
+	if from == "" {
+		return input, required("from")
+	}
+	if to == "" {
+		return input, required("to")
+	}
+	input.FromLocation, input.ToLocation = &from, &to
+	if via := cell("via"); via != "" {
+		stops := []models.OrderStop{{Address: from}}
+		for _, address := range strings.Split(via, ";") {
+			if address = strings.TrimSpace(address); address != "" {
+				stops = append(stops, models.OrderStop{Address: address})
+			}
+		}
+		input.Stops = append(stops, models.OrderStop{Address: to})
+		if _, err := normalizeStops(input.Stops); err != nil {
+			return input, invalid("via", "%v", err)
+		}
+	}
+
+	weight := cell("weight_kg")
+	if weight == "" {
+		return input, required("weight_kg")
+	}
+	value, err := parseImportNumber(weight)
+	if err != nil {
+		return input, invalid("weight_kg", "weight %q is not a positive number", weight)
+	}
+	input.WeightKg = value
+
+	sizes := []struct {
+		field string
+		dest  **float64
+	}{{"length_cm", &input.LengthCm}, {"width_cm", &input.WidthCm}, {"height_cm", &input.HeightCm}}
+	for _, size := range sizes {
+		if text := cell(size.field); text != "" {
+			value, err := parseImportNumber(text)
+			if err != nil {
+				return input, invalid(size.field, "%s %q is not a positive number", size.field, text)
+			}
+			*size.dest = &value
+		}
+	}
+	if dimensions := cell("dimensions"); dimensions != "" && input.LengthCm == nil {
+		parts := strings.FieldsFunc(strings.ToLower(dimensions), func(r rune) bool { return r == 'x' || r == 'х' || r == '*' || r == '×' })
+		if len(parts) != 3 {
+			return input, invalid("dimensions", "dimensions %q are not LxWxH", dimensions)
+		}
+		values := make([]float64, 3)
+		for i, part := range parts {
+			if values[i], err = parseImportNumber(part); err != nil {
+				return input, invalid("dimensions", "dimensions %q are not LxWxH", dimensions)
+			}
+		}
+		input.LengthCm, input.WidthCm, input.HeightCm = &values[0], &values[1], &values[2]
+	}
+
+	if tags := cell("tags"); tags != "" {
+		for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ';' }) {
+			if tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")); tag != "" {
+				input.Tags = append(input.Tags, tag)
+			}
+		}
+	}
+
+	currency := models.DefaultCurrency
+	if code := cell("currency"); code != "" {
+		if currency, err = models.ParseCurrency(code); err != nil {
+			return input, invalid("currency", "currency %q is not supported", code)
+		}
+	}
+	price := cell("price")
+	if price == "" {
+		return input, required("price")
+	}
+	if input.Price, err = parseImportPrice(price, currency); err != nil || input.Price.Amount <= 0 {
+		return input, invalid("price", "price %q is not a positive amount", price)
+	}
+
+	if date := cell("available_from"); date != "" {
+		parsed, err := parseImportDate(date)
+		if err != nil {
+			return input, invalid("available_from", "date %q is not DD.MM.YYYY", date)
+		}
+		input.AvailableFrom = &parsed
+	}
+	return input, nil
+}
+
+func parseImportNumber(text string) (float64, error) {
+	clean := strings.NewReplacer(" ", "", " ", "", ",", ".").Replace(text)
+	value, err := strconv.ParseFloat(clean, 64)
+	if err != nil || value <= 0 || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	return value, nil
+}
+
+// parseImportPrice reads an exact amount, or rounds the float a spreadsheet
+// may store it as, like 5000.499999999
+func parseImportPrice(text string, currency models.Currency) (models.Money, error) {
+	if price, err := models.ParseMoney(text, currency); err == nil {
+		return price, nil
+	}
+	value, err := parseImportNumber(text)
+	if err != nil || value > 1e13 {
+		return models.Money{}, fmt.Errorf("invalid price %q", text)
+	}
+	return models.MoneyFromFloat(value, currency), nil
+}
+
+var importDateLayouts = []string{"02.01.2006", "2.1.2006", "2006-01-02", "2006-01-02 15:04:05", "02.01.2006 15:04"}
+
+// parseImportDate accepts common date formats and spreadsheet serial dates
+func parseImportDate(text string) (time.Time, error) {
+	for _, layout := range importDateLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(text, 64); err == nil && serial > 1 && serial < 100000 {
+		epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+		return epoch.AddDate(0, 0, int(serial)), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", text)
+}
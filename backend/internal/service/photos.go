@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/storage"
+)
+
+const (
+	// MaxOrderPhotos is how many photos an order may have
+	MaxOrderPhotos = 5
+	// MaxPhotoBytes is the largest photo file accepted
+	MaxPhotoBytes = 10 << 20
+
+	// Larger images are rejected before decoding to keep memory in check
+	maxPhotoPixels = 40_000_000
+
+	// Thumbnails fit into a square of this side
+	thumbnailSize    = 320
+	thumbnailQuality = 80
+)
+
+var (
+	// ErrPhotoLimit is returned when an order already has MaxOrderPhotos photos
+	ErrPhotoLimit = errors.New("photo limit reached")
+
+	// ErrPhotoTooLarge is returned for files over MaxPhotoBytes or images over maxPhotoPixels
+	ErrPhotoTooLarge = errors.New("photo is too large")
+
+	// ErrNotAnImage is returned for files that are not JPEG or PNG images
+	ErrNotAnImage = errors.New("not a JPEG or PNG image")
+)
+
+var photoExtensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+}
+
+// AddOrderPhoto stores a photo of the cargo of an order of the owner with a
+// thumbnail. telegramFileID is set for photos sent to the driver bot.
+func (s *Service) AddOrderPhoto(ctx context.Context, orderID string, ownerUUID uuid.UUID, telegramFileID *string, content io.Reader) (models.OrderPhoto, error) {
+	if s.photos == nil {
+		return models.OrderPhoto{}, fmt.Errorf("photo storage is not configured")
+	}
+
+	order, err := s.GetOrder(ctx, orderID)
+	if err != nil {
+		return models.OrderPhoto{}, err
+	}
+	if order == nil || order.CustomerUUID != ownerUUID {
+		return models.OrderPhoto{}, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+	if order.PhotosCount >= MaxOrderPhotos {
+		return models.OrderPhoto{}, ErrPhotoLimit
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, MaxPhotoBytes+1))
+	if err != nil {
+		return models.OrderPhoto{}, fmt.Errorf("failed to read photo: %w", err)
+	}
+	if len(data) > MaxPhotoBytes {
+		return models.OrderPhoto{}, ErrPhotoTooLarge
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || photoExtensions[format] == "" {
+		return models.OrderPhoto{}, ErrNotAnImage
+	}
+	if config.Width*config.Height > maxPhotoPixels {
+		return models.OrderPhoto{}, ErrPhotoTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return models.OrderPhoto{}, ErrNotAnImage
+	}
+
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, makeThumbnail(img), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return models.OrderPhoto{}, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	photo := models.OrderPhoto{
+		UUID:           uuid.New(),
+		OrderUUID:      order.UUID,
+		TelegramFileID: telegramFileID,
+		ContentType:    "image/" + format,
+		SizeBytes:      int64(len(data)),
+		Width:          config.Width,
+		Height:         config.Height,
+	}
+	photo.StorageKey = fmt.Sprintf("orders/%s/%s%s", order.UUID, photo.UUID, photoExtensions[format])
+	photo.ThumbnailKey = fmt.Sprintf("orders/%s/%s_thumb.jpg", order.UUID, photo.UUID)
+
+	if err := s.photos.Put(ctx, photo.StorageKey, bytes.NewReader(data)); err != nil {
+		return models.OrderPhoto{}, err
+	}
+	if err := s.photos.Put(ctx, photo.ThumbnailKey, &thumbnail); err != nil {
+		s.deletePhotoFiles(ctx, photo)
+		return models.OrderPhoto{}, err
+	}
+
+	inserted, err := s.db.InsertOrderPhoto(ctx, photo, MaxOrderPhotos)
+	if err != nil || inserted == nil {
+		s.deletePhotoFiles(ctx, photo)
+		if err == nil {
+			err = ErrPhotoLimit
+		}
+		return models.OrderPhoto{}, err
+	}
+	return *inserted, nil
+}
+
+func (s *Service) ListOrderPhotos(ctx context.Context, orderID uuid.UUID) ([]models.OrderPhoto, error) {
+	return s.db.ListOrderPhotos(ctx, orderID)
+}
+
+func (s *Service) GetOrderPhoto(ctx context.Context, id string) (*models.OrderPhoto, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetOrderPhoto(ctx, uuid)
+}
+
+// OpenOrderPhoto reads the photo file or its thumbnail from the storage
+func (s *Service) OpenOrderPhoto(ctx context.Context, photo models.OrderPhoto, thumbnail bool) (io.ReadCloser, error) {
+	if s.photos == nil {
+		return nil, storage.ErrNotFound
+	}
+	if thumbnail {
+		return s.photos.Open(ctx, photo.ThumbnailKey)
+	}
+	return s.photos.Open(ctx, photo.StorageKey)
+}
+
+func (s *Service) deletePhotoFiles(ctx context.Context, photo models.OrderPhoto) {
+	for _, key := range []string{photo.StorageKey, photo.ThumbnailKey} {
+		if err := s.photos.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete photo file %s: %v", key, err)
+		}
+	}
+}
+
+// makeThumbnail scales an image down to fit thumbnailSize, keeping the aspect ratio
+func makeThumbnail(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= thumbnailSize && height <= thumbnailSize {
+		return img
+	}
+	if width >= height {
+		width, height = thumbnailSize, height*thumbnailSize/width
+	} else {
+		width, height = width*thumbnailSize/height, thumbnailSize
+	}
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, bounds, draw.Over, nil)
+	return thumbnail
+}
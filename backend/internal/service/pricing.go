@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gruzy-ryadom/internal/db"
+	"gruzy-ryadom/internal/models"
+)
+
+const (
+	// Past orders considered for an estimate
+	priceHistory     = 2 * 365 * 24 * time.Hour
+	priceSampleLimit = 5000
+
+	// The most similar orders an estimate is based on, and the least that is trusted
+	priceNeighbours = 50
+	priceMinSamples = 5
+
+	// Road freight is charged by volume when cargo is lighter than this, kg per m³
+	volumetricDensity = 250
+
+	// How price grows with chargeable weight and distance
+	weightElasticity   = 0.6
+	distanceElasticity = 0.8
+
+	// Prices outside [low/outlierFactor, high*outlierFactor] are flagged
+	outlierFactor = 2.0
+)
+
+// EstimatePrice suggests a price range from similar past orders: the same
+// route, comparable distance and chargeable weight, shared tags and season.
+// It returns nil if there is not enough history.
+func (s *Service) EstimatePrice(ctx context.Context, input models.PriceEstimateInput) (*models.PriceEstimate, error) {
+	return s.newPriceSamples().estimate(ctx, input)
+}
+
+// priceSamples loads the past orders estimates are based on once per
+// currency, so a batch of orders is checked with one query per currency
+type priceSamples struct {
+	db         *db.DB
+	byCurrency map[models.Currency][]models.PriceSample
+}
+
+func (s *Service) newPriceSamples() *priceSamples {
+	return &priceSamples{db: s.db, byCurrency: make(map[models.Currency][]models.PriceSample)}
+}
+
+func (p *priceSamples) get(ctx context.Context, currency models.Currency) ([]models.PriceSample, error) {
+	if samples, ok := p.byCurrency[currency]; ok {
+		return samples, nil
+	}
+	samples, err := p.db.ListPriceSamples(ctx, currency, time.Now().UTC().Add(-priceHistory), priceSampleLimit)
+	if err != nil {
+		return nil, err
+	}
+	p.byCurrency[currency] = samples
+	return samples, nil
+}
+
+func (p *priceSamples) estimate(ctx context.Context, input models.PriceEstimateInput) (*models.PriceEstimate, error) {
+	if input.WeightKg <= 0 {
+		return nil, fmt.Errorf("weight must be positive")
+	}
+
+	if input.Currency == "" {
+		input.Currency = models.DefaultCurrency
+	}
+	samples, err := p.get(ctx, input.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	date := time.Now().UTC()
+	if input.Date != nil {
+		date = *input.Date
+	}
+	target := priceFeatures{
+		from:     findCity(input.From),
+		to:       findCity(input.To),
+		weight:   chargeableWeight(input.WeightKg, input.LengthCm, input.WidthCm, input.HeightCm),
+		tags:     input.Tags,
+		month:    date.Month(),
+		distance: -1,
+	}
+	if target.from != nil && target.to != nil {
+		target.distance = roadDistance(*target.from, *target.to)
+	}
+
+	type candidate struct {
+		price, weight float64
+	}
+	candidates := make([]candidate, 0, len(samples))
+	for _, sample := range samples {
+		features := priceFeatures{
+			from:     findCity(sample.From),
+			to:       findCity(sample.To),
+			weight:   chargeableWeight(sample.WeightKg, sample.LengthCm, sample.WidthCm, sample.HeightCm),
+			tags:     sample.Tags,
+			month:    sample.CreatedAt.Month(),
+			distance: -1,
+		}
+		if features.from != nil && features.to != nil {
+			features.distance = roadDistance(*features.from, *features.to)
+		}
+
+		// Scale the past price to the requested cargo and distance
+		price := sample.Price.Float() * math.Pow(target.weight/features.weight, weightElasticity)
+		if target.distance > 0 && features.distance > 0 {
+			price *= math.Pow(target.distance/features.distance, distanceElasticity)
+		}
+		candidates = append(candidates, candidate{price: price, weight: similarity(target, features)})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].weight > candidates[j].weight })
+	if len(candidates) > priceNeighbours {
+		candidates = candidates[:priceNeighbours]
+	}
+	if len(candidates) < priceMinSamples {
+		return nil, nil
+	}
+
+	prices := make([]float64, len(candidates))
+	weights := make([]float64, len(candidates))
+	for i, c := range candidates {
+		prices[i], weights[i] = c.price, c.weight
+	}
+
+	estimate := &models.PriceEstimate{
+		Low:     roundPrice(weightedQuantile(prices, weights, 0.25), input.Currency),
+		Median:  roundPrice(weightedQuantile(prices, weights, 0.5), input.Currency),
+		High:    roundPrice(weightedQuantile(prices, weights, 0.75), input.Currency),
+		Samples: len(candidates),
+	}
+	if target.distance > 0 {
+		distance := math.Round(target.distance)
+		estimate.DistanceKm = &distance
+	}
+	return estimate, nil
+}
+
+// checkPrice compares an order price with the estimate for its cargo; it
+// returns nil if there is not enough history to judge
+func (p *priceSamples) checkPrice(ctx context.Context, input models.PriceEstimateInput, price models.Money) *models.PriceCheck {
+	input.Currency = price.Currency
+	estimate, err := p.estimate(ctx, input)
+	if err != nil || estimate == nil {
+		return nil
+	}
+
+	check := &models.PriceCheck{Low: estimate.Low, High: estimate.High}
+	switch {
+	case price.Float() < estimate.Low.Float()/outlierFactor:
+		check.Flag = models.PriceTooLow
+	case price.Float() > estimate.High.Float()*outlierFactor:
+		check.Flag = models.PriceTooHigh
+	}
+	return check
+}
+
+type priceFeatures struct {
+	from, to *city
+	weight   float64
+	tags     []string
+	month    time.Month
+	distance float64 // km, negative if unknown
+}
+
+// similarity weighs how comparable a past order is to the requested one
+func similarity(target, sample priceFeatures) float64 {
+	sim := 1.0
+
+	switch {
+	case sameCity(target.from, sample.from) && sameCity(target.to, sample.to):
+		sim *= 4
+	case sameCity(target.from, sample.to) && sameCity(target.to, sample.from):
+		sim *= 3
+	case sameCity(target.from, sample.from) || sameCity(target.to, sample.to):
+		sim *= 1.5
+	}
+	if target.distance > 0 && sample.distance > 0 {
+		sim *= math.Exp(-math.Abs(math.Log(target.distance / sample.distance)))
+	}
+
+	sim *= math.Exp(-math.Abs(math.Log(target.weight / sample.weight)))
+	sim *= 1 + jaccard(target.tags, sample.tags)
+
+	months := int(target.month - sample.month)
+	if months < 0 {
+		months = -months
+	}
+	if months <= 1 || months == 11 {
+		sim *= 1.3
+	}
+
+	return sim
+}
+
+// chargeableWeight is the larger of the actual and the volumetric weight
+func chargeableWeight(weightKg float64, lengthCm, widthCm, heightCm *float64) float64 {
+	if lengthCm == nil || widthCm == nil || heightCm == nil {
+		return weightKg
+	}
+	volume := *lengthCm * *widthCm * *heightCm / 1e6
+	return math.Max(weightKg, volume*volumetricDensity)
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, tag := range a {
+		set[strings.ToLower(tag)] = true
+	}
+	common, union := 0, len(set)
+	for _, tag := range b {
+		tag = strings.ToLower(tag)
+		if set[tag] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common) / float64(union)
+}
+
+func weightedQuantile(values, weights []float64, q float64) float64 {
+	idx := make([]int, len(values))
+	total := 0.0
+	for i := range idx {
+		idx[i] = i
+		total += weights[i]
+	}
+	sort.Slice(idx, func(i, j int) bool { return values[idx[i]] < values[idx[j]] })
+
+	acc := 0.0
+	for _, i := range idx {
+		acc += weights[i]
+		if acc >= q*total {
+			return values[i]
+		}
+	}
+	return values[idx[len(idx)-1]]
+}
+
+// roundPrice rounds to 100 units of the currency, or to 10 for small amounts
+func roundPrice(price float64, currency models.Currency) models.Money {
+	if price < 1000 {
+		return models.MoneyFromFloat(math.Round(price/10)*10, currency)
+	}
+	return models.MoneyFromFloat(math.Round(price/100)*100, currency)
+}
+
+// recheckPrice compares the price of an order with the estimate after an update
+func (s *Service) recheckPrice(ctx context.Context, order models.Order, input models.UpdateOrderInput) *models.PriceCheck {
+	estimate := models.PriceEstimateInput{
+		From:     order.FromLocation,
+		To:       order.ToLocation,
+		WeightKg: order.WeightKg,
+		LengthCm: order.LengthCm,
+		WidthCm:  order.WidthCm,
+		HeightCm: order.HeightCm,
+		Tags:     order.Tags,
+		Date:     order.AvailableFrom,
+	}
+	price := order.Price
+
+	if input.FromLocation != nil {
+		estimate.From = input.FromLocation
+	}
+	if input.ToLocation != nil {
+		estimate.To = input.ToLocation
+	}
+	if input.WeightKg != nil {
+		estimate.WeightKg = *input.WeightKg
+	}
+	if input.Tags != nil {
+		estimate.Tags = *input.Tags
+	}
+	if input.Price != nil {
+		price = *input.Price
+	}
+
+	return s.newPriceSamples().checkPrice(ctx, estimate, price)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+var (
+	// ErrOrderTaken is returned when a driver takes an order that is no longer open
+	ErrOrderTaken = errors.New("order is already taken")
+
+	// ErrAlreadyReviewed is returned on a second review of the same order by one author
+	ErrAlreadyReviewed = errors.New("order is already reviewed")
+)
+
+// Longer comments are cut to keep order cards readable
+const maxReviewComment = 500
+
+// TakeOrder assigns an open published order to a driver
+func (s *Service) TakeOrder(ctx context.Context, id string, carrierUUID uuid.UUID) (models.Order, error) {
+	orderID, err := parseUUID(id)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	before, err := s.db.GetOrder(ctx, orderID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if before == nil || before.ModerationStatus != models.ModerationApproved || before.Hidden {
+		return models.Order{}, fmt.Errorf("order %s not found", id)
+	}
+	if before.CustomerUUID == carrierUUID {
+		return models.Order{}, fmt.Errorf("customer cannot take their own order")
+	}
+
+	ban, err := s.db.GetActiveBanByCustomer(ctx, carrierUUID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if ban != nil {
+		return models.Order{}, fmt.Errorf("%w: %s", ErrBanned, ban.Reason)
+	}
+
+	taken, err := s.db.AssignCarrier(ctx, orderID, carrierUUID, orderUpdated(*before))
+	if err != nil {
+		return models.Order{}, err
+	}
+	if !taken {
+		return models.Order{}, ErrOrderTaken
+	}
+
+	order, err := s.db.GetOrder(ctx, orderID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if order == nil {
+		return models.Order{}, fmt.Errorf("order %s not found", id)
+	}
+	before.Customer = nil
+	s.audit(ctx, models.AuditOrder, orderID, models.AuditUpdate, before, order)
+	return *order, nil
+}
+
+// Reviews methods
+
+// CreateReview rates the other side of a completed order: the customer rates
+// the driver and the driver rates the customer
+func (s *Service) CreateReview(ctx context.Context, input models.CreateReviewInput) (models.Review, error) {
+	if input.Rating < 1 || input.Rating > 5 {
+		return models.Review{}, fmt.Errorf("rating must be between 1 and 5")
+	}
+	if input.Comment != nil {
+		comment := truncateComment(*input.Comment)
+		input.Comment = &comment
+		if comment == "" {
+			input.Comment = nil
+		}
+	}
+
+	order, err := s.db.GetOrder(ctx, input.OrderUUID)
+	if err != nil {
+		return models.Review{}, err
+	}
+	if order == nil {
+		return models.Review{}, fmt.Errorf("order %s not found", input.OrderUUID)
+	}
+	if order.Status != models.OrderCompleted || order.CarrierUUID == nil {
+		return models.Review{}, fmt.Errorf("order %s is not completed", input.OrderUUID)
+	}
+
+	var target uuid.UUID
+	switch input.AuthorUUID {
+	case order.CustomerUUID:
+		target = *order.CarrierUUID
+	case *order.CarrierUUID:
+		target = order.CustomerUUID
+	default:
+		return models.Review{}, fmt.Errorf("only the customer and the driver can review order %s", input.OrderUUID)
+	}
+
+	existing, err := s.db.GetReview(ctx, input.OrderUUID, input.AuthorUUID)
+	if err != nil {
+		return models.Review{}, err
+	}
+	if existing != nil {
+		return models.Review{}, ErrAlreadyReviewed
+	}
+
+	return s.db.CreateReview(ctx, input, target)
+}
+
+// SetReviewComment adds a comment to a review left without one
+func (s *Service) SetReviewComment(ctx context.Context, id uuid.UUID, comment string) (bool, error) {
+	comment = truncateComment(comment)
+	if comment == "" {
+		return false, fmt.Errorf("comment is empty")
+	}
+	return s.db.SetReviewComment(ctx, id, comment)
+}
+
+func (s *Service) ListReviews(ctx context.Context, filter models.ReviewFilter) ([]models.Review, error) {
+	return s.db.ListReviews(ctx, filter)
+}
+
+// RemoveReview hides an abusive review and excludes it from the reputation
+func (s *Service) RemoveReview(ctx context.Context, id string, removedBy int64) (bool, error) {
+	reviewID, err := parseUUID(id)
+	if err != nil {
+		return false, err
+	}
+	return s.db.RemoveReview(ctx, reviewID, removedBy)
+}
+
+func truncateComment(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if runes := []rune(comment); len(runes) > maxReviewComment {
+		comment = string(runes[:maxReviewComment])
+	}
+	return comment
+}
@@ -2,20 +2,33 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gruzy-ryadom/internal/db"
 	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/storage"
 )
 
+// ErrBanned is returned when a banned user tries to publish an order
+var ErrBanned = errors.New("user is banned")
+
+// Customers with this many approved orders and no rejections skip moderation
+const autoApproveAfter = 3
+
 type Service struct {
-	db *db.DB
+	db     *db.DB
+	photos storage.Storage // nil disables photo uploads
+	// Lets webhooks reach loopback and private addresses, for local testing
+	allowPrivateWebhooks bool
 }
 
-func New(db *db.DB) *Service {
-	return &Service{db: db}
+func New(db *db.DB, photos storage.Storage, allowPrivateWebhooks bool) *Service {
+	return &Service{db: db, photos: photos, allowPrivateWebhooks: allowPrivateWebhooks}
 }
 
 // Orders methods
@@ -23,8 +36,70 @@ func (s *Service) ListOrders(ctx context.Context, filter models.OrderFilter) ([]
 	return s.db.ListOrders(ctx, filter)
 }
 
+// CreateOrder stores a new order. It is published right away for trusted
+// customers and goes to the moderation queue otherwise.
 func (s *Service) CreateOrder(ctx context.Context, input models.CreateOrderInput) (models.Order, error) {
-	return s.db.CreateOrder(ctx, input)
+	ban, err := s.db.GetActiveBanByCustomer(ctx, input.CustomerUUID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if ban != nil {
+		return models.Order{}, fmt.Errorf("%w: %s", ErrBanned, ban.Reason)
+	}
+
+	history, err := s.db.GetModerationHistory(ctx, input.CustomerUUID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if input, err = s.prepareOrder(ctx, input, history, s.newPriceSamples()); err != nil {
+		return models.Order{}, err
+	}
+
+	order, err := s.db.CreateOrder(ctx, input, orderCreated)
+	if err != nil {
+		return models.Order{}, err
+	}
+	s.audit(ctx, models.AuditOrder, order.UUID, models.AuditCreate, nil, &order)
+	return order, nil
+}
+
+// prepareOrder validates a new order and decides whether it needs moderation;
+// orders prepared together share the price samples
+func (s *Service) prepareOrder(ctx context.Context, input models.CreateOrderInput, history models.ModerationHistory, samples *priceSamples) (models.CreateOrderInput, error) {
+	var err error
+	if input.Price.Currency == "" {
+		input.Price.Currency = models.DefaultCurrency
+	}
+	if _, err := models.ParseCurrency(string(input.Price.Currency)); err != nil {
+		return input, err
+	}
+	if len(input.Stops) > 0 {
+		if input.Stops, err = normalizeStops(input.Stops); err != nil {
+			return input, err
+		}
+		input.FromLocation, input.ToLocation = routeEnds(input.Stops)
+	} else {
+		input.Stops = stopsFromLocations(input.FromLocation, input.ToLocation)
+	}
+
+	// Prices far from the market go to moderators even from trusted customers
+	input.PriceCheck = samples.checkPrice(ctx, models.PriceEstimateInput{
+		From:     input.FromLocation,
+		To:       input.ToLocation,
+		WeightKg: input.WeightKg,
+		LengthCm: input.LengthCm,
+		WidthCm:  input.WidthCm,
+		HeightCm: input.HeightCm,
+		Tags:     input.Tags,
+		Date:     input.AvailableFrom,
+	}, input.Price)
+	outlier := input.PriceCheck != nil && input.PriceCheck.Flag != ""
+
+	input.ModerationStatus = models.ModerationPending
+	if !outlier && (history.Trusted || (history.Approved >= autoApproveAfter && history.Rejected == 0)) {
+		input.ModerationStatus = models.ModerationApproved
+	}
+	return input, nil
 }
 
 func (s *Service) UpdateOrder(ctx context.Context, id string, input models.UpdateOrderInput) (models.Order, error) {
@@ -32,7 +107,111 @@ func (s *Service) UpdateOrder(ctx context.Context, id string, input models.Updat
 	if err != nil {
 		return models.Order{}, err
 	}
-	return s.db.UpdateOrder(ctx, uuid, input)
+
+	before, err := s.db.GetOrder(ctx, uuid)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if before == nil {
+		return models.Order{}, fmt.Errorf("order %s not found", id)
+	}
+	before.Customer = nil
+
+	// A price without a currency keeps the currency of the order
+	if input.Price != nil && input.Price.Currency == "" {
+		price := models.Money{Amount: input.Price.Amount, Currency: before.Price.Currency}
+		input.Price = &price
+	}
+	if input.Price != nil {
+		if _, err := models.ParseCurrency(string(input.Price.Currency)); err != nil {
+			return models.Order{}, err
+		}
+	}
+
+	if input.Stops != nil {
+		stops, err := normalizeStops(*input.Stops)
+		if err != nil {
+			return models.Order{}, err
+		}
+		input.Stops = &stops
+		input.FromLocation, input.ToLocation = routeEnds(stops)
+	} else if input.FromLocation != nil || input.ToLocation != nil {
+		stops := restopOrder(*before, input)
+		input.Stops = &stops
+	}
+
+	if input.Price != nil || input.WeightKg != nil || input.FromLocation != nil || input.ToLocation != nil {
+		input.PriceCheck = s.recheckPrice(ctx, *before, input)
+	}
+
+	order, err := s.db.UpdateOrder(ctx, uuid, input, orderUpdated(*before))
+	if err != nil {
+		return models.Order{}, err
+	}
+	s.audit(ctx, models.AuditOrder, order.UUID, models.AuditUpdate, before, &order)
+	return order, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetOrder(ctx, uuid)
+}
+
+func (s *Service) DeleteOrder(ctx context.Context, id string) (bool, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return false, err
+	}
+	return s.db.DeleteOrder(ctx, uuid)
+}
+
+// Moderation methods
+func (s *Service) ListUnnotifiedModeration(ctx context.Context, limit int) ([]models.Order, error) {
+	return s.db.ListUnnotifiedModeration(ctx, limit)
+}
+
+func (s *Service) ListPendingModeration(ctx context.Context, limit int) ([]models.Order, int, error) {
+	return s.db.ListPendingModeration(ctx, limit)
+}
+
+func (s *Service) MarkModerationNotified(ctx context.Context, ids []uuid.UUID) error {
+	return s.db.MarkModerationNotified(ctx, ids)
+}
+
+// ModerateOrder approves or rejects a pending order. A rejection requires a
+// reason that is shown to the author. It returns nil if the order was already
+// moderated.
+func (s *Service) ModerateOrder(ctx context.Context, id string, status models.ModerationStatus, reason string, moderatorID int64) (*models.Order, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasonArg *string
+	switch status {
+	case models.ModerationApproved:
+	case models.ModerationRejected:
+		reason = strings.TrimSpace(reason)
+		if reason == "" {
+			return nil, fmt.Errorf("rejection reason is required")
+		}
+		reasonArg = &reason
+	default:
+		return nil, fmt.Errorf("invalid moderation status %q", status)
+	}
+
+	before, err := s.db.GetOrder(ctx, uuid)
+	if err != nil || before == nil {
+		return nil, err
+	}
+	return s.db.ModerateOrder(ctx, uuid, status, reasonArg, moderatorID, orderUpdated(*before))
+}
+
+func (s *Service) SetCustomerTrusted(ctx context.Context, id uuid.UUID, trusted bool) error {
+	return s.db.SetCustomerTrusted(ctx, id, trusted)
 }
 
 // Customers methods
@@ -41,7 +220,12 @@ func (s *Service) ListCustomers(ctx context.Context, filter models.CustomerFilte
 }
 
 func (s *Service) CreateCustomer(ctx context.Context, input models.CreateCustomerInput) (models.Customer, error) {
-	return s.db.CreateCustomer(ctx, input)
+	customer, err := s.db.CreateCustomer(ctx, input, customerCreated)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	s.audit(ctx, models.AuditCustomer, customer.UUID, models.AuditCreate, nil, &customer)
+	return customer, nil
 }
 
 func (s *Service) UpdateCustomer(ctx context.Context, id string, input models.UpdateCustomerInput) (models.Customer, error) {
@@ -49,13 +233,277 @@ func (s *Service) UpdateCustomer(ctx context.Context, id string, input models.Up
 	if err != nil {
 		return models.Customer{}, err
 	}
-	return s.db.UpdateCustomer(ctx, uuid, input)
+
+	before, err := s.db.GetCustomer(ctx, uuid)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if before == nil {
+		return models.Customer{}, fmt.Errorf("customer %s not found", id)
+	}
+
+	customer, err := s.db.UpdateCustomer(ctx, uuid, input)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	s.audit(ctx, models.AuditCustomer, customer.UUID, models.AuditUpdate, before, &customer)
+	return customer, nil
 }
 
 func (s *Service) GetCustomerByTelegramID(ctx context.Context, telegramID int64) (*models.Customer, error) {
 	return s.db.GetCustomerByTelegramID(ctx, telegramID)
 }
 
+func (s *Service) GetCustomer(ctx context.Context, id string) (*models.Customer, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetCustomer(ctx, uuid)
+}
+
+func (s *Service) DeleteCustomer(ctx context.Context, id string) (bool, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return false, err
+	}
+	return s.db.DeleteCustomer(ctx, uuid)
+}
+
+// Bans methods
+
+// CreateBan bans a user by exactly one identifier. Phones are stored as
+// digits only so that any formatting of the customer's number matches.
+func (s *Service) CreateBan(ctx context.Context, input models.CreateBanInput) (models.Ban, error) {
+	targets := 0
+	if input.TelegramID != nil {
+		targets++
+	}
+	if input.CustomerUUID != nil {
+		targets++
+	}
+	if input.Phone != nil {
+		targets++
+		phone := normalizePhone(*input.Phone)
+		if len(phone) < 5 {
+			return models.Ban{}, fmt.Errorf("invalid phone %q", *input.Phone)
+		}
+		input.Phone = &phone
+	}
+	if targets != 1 {
+		return models.Ban{}, fmt.Errorf("ban needs exactly one of telegram ID, phone or customer")
+	}
+
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return models.Ban{}, fmt.Errorf("ban reason is required")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now().UTC()) {
+		return models.Ban{}, fmt.Errorf("ban expiry is in the past")
+	}
+
+	return s.db.CreateBan(ctx, input)
+}
+
+func (s *Service) ListActiveBans(ctx context.Context, limit int) ([]models.Ban, error) {
+	return s.db.ListActiveBans(ctx, limit)
+}
+
+func (s *Service) LiftBan(ctx context.Context, id string, liftedBy int64) (bool, error) {
+	uuid, err := parseUUID(id)
+	if err != nil {
+		return false, err
+	}
+	return s.db.LiftBan(ctx, uuid, liftedBy)
+}
+
+// GetActiveBanByTelegramID returns the ban in force for a Telegram user, or nil
+func (s *Service) GetActiveBanByTelegramID(ctx context.Context, telegramID int64) (*models.Ban, error) {
+	return s.db.GetActiveBanByTelegramID(ctx, telegramID)
+}
+
+func (s *Service) GetActiveBanByCustomer(ctx context.Context, customerUUID uuid.UUID) (*models.Ban, error) {
+	return s.db.GetActiveBanByCustomer(ctx, customerUUID)
+}
+
+// Referrals methods
+func (s *Service) GetReferralCode(ctx context.Context, customerUUID uuid.UUID) (string, error) {
+	return s.db.GetReferralCode(ctx, customerUUID)
+}
+
+// RegisterReferral attributes a newly created customer to the owner of the
+// referral code. Unknown codes and self-referrals are ignored.
+func (s *Service) RegisterReferral(ctx context.Context, code string, referred uuid.UUID) error {
+	referrer, err := s.db.GetCustomerByReferralCode(ctx, code)
+	if err != nil {
+		return err
+	}
+	if referrer == nil || referrer.UUID == referred {
+		return nil
+	}
+	return s.db.CreateReferral(ctx, referrer.UUID, referred)
+}
+
+func (s *Service) CountReferrals(ctx context.Context, referrerUUID uuid.UUID) (int, error) {
+	return s.db.CountReferrals(ctx, referrerUUID)
+}
+
+func (s *Service) ListTopReferrers(ctx context.Context, limit int) ([]models.ReferrerStats, int, error) {
+	return s.db.ListTopReferrers(ctx, limit)
+}
+
+// Admins methods
+func (s *Service) ListAdmins(ctx context.Context) ([]models.Admin, error) {
+	return s.db.ListAdmins(ctx)
+}
+
+func (s *Service) GetAdmin(ctx context.Context, telegramID int64) (*models.Admin, error) {
+	return s.db.GetAdmin(ctx, telegramID)
+}
+
+func (s *Service) SaveAdmin(ctx context.Context, telegramID int64, role models.AdminRole, addedBy int64) (models.Admin, error) {
+	if role != models.RoleModerator && role != models.RoleSuperadmin {
+		return models.Admin{}, fmt.Errorf("unknown role %q", role)
+	}
+	return s.db.SaveAdmin(ctx, telegramID, role, addedBy)
+}
+
+func (s *Service) DeleteAdmin(ctx context.Context, telegramID int64) (bool, error) {
+	return s.db.DeleteAdmin(ctx, telegramID)
+}
+
+// Broadcasts methods
+func (s *Service) CountBroadcastAudience(ctx context.Context, audience models.BroadcastAudience, region *string) (int, error) {
+	return s.db.CountBroadcastAudience(ctx, audience, region)
+}
+
+func (s *Service) CreateBroadcast(ctx context.Context, input models.CreateBroadcastInput) (models.Broadcast, error) {
+	if input.Text == "" && len(input.Photo) == 0 {
+		return models.Broadcast{}, fmt.Errorf("broadcast message is empty")
+	}
+	return s.db.CreateBroadcast(ctx, input)
+}
+
+func (s *Service) GetBroadcast(ctx context.Context, id uuid.UUID) (*models.Broadcast, error) {
+	return s.db.GetBroadcast(ctx, id)
+}
+
+func (s *Service) ListActiveBroadcasts(ctx context.Context) ([]models.Broadcast, error) {
+	return s.db.ListActiveBroadcasts(ctx)
+}
+
+func (s *Service) SetBroadcastStatus(ctx context.Context, id uuid.UUID, status models.BroadcastStatus) (bool, error) {
+	return s.db.SetBroadcastStatus(ctx, id, status)
+}
+
+func (s *Service) SetBroadcastProgressMessage(ctx context.Context, id uuid.UUID, chatID int64, messageID int) error {
+	return s.db.SetBroadcastProgressMessage(ctx, id, chatID, messageID)
+}
+
+func (s *Service) SetBroadcastPhotoFileID(ctx context.Context, id uuid.UUID, fileID string) error {
+	return s.db.SetBroadcastPhotoFileID(ctx, id, fileID)
+}
+
+func (s *Service) NextBroadcastRecipients(ctx context.Context, id uuid.UUID, limit int) ([]int64, error) {
+	return s.db.NextBroadcastRecipients(ctx, id, limit)
+}
+
+func (s *Service) MarkBroadcastDelivery(ctx context.Context, id uuid.UUID, telegramID int64, status string, deliveryErr *string) error {
+	return s.db.MarkBroadcastDelivery(ctx, id, telegramID, status, deliveryErr)
+}
+
+// MarkBotUnblocked makes a user who came back to the driver bot reachable by broadcasts again
+func (s *Service) MarkBotUnblocked(ctx context.Context, telegramID int64) error {
+	return s.db.SetCustomerBotBlocked(ctx, telegramID, false)
+}
+
+// Saved searches methods
+func (s *Service) SaveSearch(ctx context.Context, telegramID int64, query string) (models.SavedSearch, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return models.SavedSearch{}, fmt.Errorf("search query is empty")
+	}
+	return s.db.SaveSearch(ctx, telegramID, query)
+}
+
+func (s *Service) ListSavedSearches(ctx context.Context, telegramID int64) ([]models.SavedSearch, error) {
+	return s.db.ListSavedSearches(ctx, telegramID)
+}
+
+func (s *Service) DeleteSavedSearches(ctx context.Context, telegramID int64) (int, error) {
+	return s.db.DeleteSavedSearches(ctx, telegramID)
+}
+
+func (s *Service) ListSubscribedSearches(ctx context.Context) ([]models.SavedSearch, error) {
+	return s.db.ListSubscribedSearches(ctx)
+}
+
+func (s *Service) ClaimSearchNotification(ctx context.Context, telegramID int64, orderUUID uuid.UUID) (bool, error) {
+	return s.db.ClaimSearchNotification(ctx, telegramID, orderUUID)
+}
+
+func (s *Service) ReleaseSearchNotification(ctx context.Context, telegramID int64, orderUUID uuid.UUID) error {
+	return s.db.ReleaseSearchNotification(ctx, telegramID, orderUUID)
+}
+
+func (s *Service) SetCustomerBotBlocked(ctx context.Context, telegramID int64, blocked bool) error {
+	return s.db.SetCustomerBotBlocked(ctx, telegramID, blocked)
+}
+
+// Statistics methods
+
+// GetStats returns aggregate statistics for the period. Short periods are
+// broken down by day, a month and all time by week.
+func (s *Service) GetStats(ctx context.Context, period models.StatsPeriod) (models.Stats, error) {
+	since, bucket, err := periodSince(period)
+	if err != nil {
+		return models.Stats{}, err
+	}
+
+	stats, err := s.db.GetStats(ctx, since, bucket)
+	if err != nil {
+		return models.Stats{}, err
+	}
+	stats.Period = period
+	return stats, nil
+}
+
+func (s *Service) GetPriceDistribution(ctx context.Context, period models.StatsPeriod, currency models.Currency, buckets int) ([]models.PriceBucket, error) {
+	since, _, err := periodSince(period)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetPriceDistribution(ctx, since, currency, buckets)
+}
+
+// periodSince returns the start of the period (nil for all time) and the
+// granularity of its time series
+func periodSince(period models.StatsPeriod) (*time.Time, string, error) {
+	// Timestamps are stored in UTC without a time zone
+	now := time.Now().UTC()
+
+	switch period {
+	case models.PeriodDay:
+		t := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return &t, "day", nil
+	case models.PeriodWeek:
+		t := now.AddDate(0, 0, -7)
+		return &t, "day", nil
+	case models.PeriodMonth:
+		t := now.AddDate(0, -1, 0)
+		return &t, "week", nil
+	case models.PeriodAll:
+		return nil, "week", nil
+	default:
+		return nil, "", fmt.Errorf("unknown period %q", period)
+	}
+}
+
+// TouchCustomer records that a user interacted with the driver bot
+func (s *Service) TouchCustomer(ctx context.Context, telegramID int64) error {
+	return s.db.TouchCustomer(ctx, telegramID)
+}
+
 // Helper functions
 func parseUUID(id string) (uuid.UUID, error) {
 	// Parse UUID string to UUID type
@@ -70,6 +518,16 @@ func parseInt(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
+// normalizePhone keeps only the digits of a phone number
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
 func parseTags(s string) []string {
 	if s == "" {
 		return []string{}
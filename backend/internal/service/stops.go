@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// MaxOrderStops is how many pickups and drop-offs an order may have, including the origin and the destination
+const MaxOrderStops = 10
+
+// normalizeStops checks the stops of an order and fills in the cities it recognizes
+func normalizeStops(stops []models.OrderStop) ([]models.OrderStop, error) {
+	if len(stops) < 2 {
+		return nil, fmt.Errorf("an order needs at least 2 stops, got %d", len(stops))
+	}
+	if len(stops) > MaxOrderStops {
+		return nil, fmt.Errorf("an order can have at most %d stops, got %d", MaxOrderStops, len(stops))
+	}
+
+	normalized := make([]models.OrderStop, len(stops))
+	for i, stop := range stops {
+		stop.Sequence = i
+		stop.Address = strings.TrimSpace(stop.Address)
+		if stop.Address == "" {
+			return nil, fmt.Errorf("stop %d has no address", i+1)
+		}
+		if (stop.Lat == nil) != (stop.Lon == nil) {
+			return nil, fmt.Errorf("stop %d needs both latitude and longitude", i+1)
+		}
+		if stop.WindowFrom != nil && stop.WindowTo != nil && stop.WindowTo.Before(*stop.WindowFrom) {
+			return nil, fmt.Errorf("stop %d time window ends before it starts", i+1)
+		}
+		if stop.City == nil {
+			stop.City = newStop(stop.Address).City
+		}
+		normalized[i] = stop
+	}
+	return normalized, nil
+}
+
+// stopsFromLocations turns the from and to of an order without explicit stops into stops
+func stopsFromLocations(from, to *string) []models.OrderStop {
+	var stops []models.OrderStop
+	for _, location := range []*string{from, to} {
+		if location != nil && strings.TrimSpace(*location) != "" {
+			stop := newStop(*location)
+			stop.Sequence = len(stops)
+			stops = append(stops, stop)
+		}
+	}
+	return stops
+}
+
+func newStop(address string) models.OrderStop {
+	stop := models.OrderStop{Address: strings.TrimSpace(address)}
+	if city := findCity(&stop.Address); city != nil {
+		name := city.title()
+		stop.City = &name
+	}
+	return stop
+}
+
+// routeEnds returns the addresses of the first and the last stop
+func routeEnds(stops []models.OrderStop) (*string, *string) {
+	from, to := stops[0].Address, stops[len(stops)-1].Address
+	return &from, &to
+}
+
+// restopOrder keeps the stops of an order in line with an update of its from
+// or to: the first or the last stop gets the new address, keeping its time
+// window and notes
+func restopOrder(order models.Order, input models.UpdateOrderInput) []models.OrderStop {
+	if len(order.Stops) < 2 {
+		from, to := order.FromLocation, order.ToLocation
+		if input.FromLocation != nil {
+			from = input.FromLocation
+		}
+		if input.ToLocation != nil {
+			to = input.ToLocation
+		}
+		return stopsFromLocations(from, to)
+	}
+
+	stops := make([]models.OrderStop, len(order.Stops))
+	copy(stops, order.Stops)
+	replace := func(i int, address string) {
+		stop := newStop(address)
+		stop.Sequence, stop.WindowFrom, stop.WindowTo, stop.Notes = i, stops[i].WindowFrom, stops[i].WindowTo, stops[i].Notes
+		stops[i] = stop
+	}
+	if input.FromLocation != nil {
+		replace(0, *input.FromLocation)
+	}
+	if input.ToLocation != nil {
+		replace(len(stops)-1, *input.ToLocation)
+	}
+	return stops
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// CustomerViewer identifies a known viewer across the API and the bots
+func CustomerViewer(id uuid.UUID) string {
+	return "customer:" + id.String()
+}
+
+// AnonymousViewer identifies an anonymous API caller, e.g. by IP and user agent,
+// without storing them
+func AnonymousViewer(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return "anon:" + hex.EncodeToString(sum[:16])
+}
+
+// GetPublicOrder returns an order if it is visible in public listings, or nil
+func (s *Service) GetPublicOrder(ctx context.Context, id string) (*models.Order, error) {
+	orderID, err := parseUUID(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	order, err := s.db.GetOrder(ctx, orderID)
+	if err != nil || order == nil {
+		return nil, err
+	}
+	if order.ModerationStatus != models.ModerationApproved || order.Hidden {
+		return nil, nil
+	}
+
+	ban, err := s.db.GetActiveBanByCustomer(ctx, order.CustomerUUID)
+	if err != nil {
+		return nil, err
+	}
+	if ban != nil {
+		return nil, nil
+	}
+	return order, nil
+}
+
+// RecordOrderView counts a viewer of an order once; the owner's own views are not counted
+func (s *Service) RecordOrderView(ctx context.Context, order models.Order, viewer string) error {
+	if viewer == CustomerViewer(order.CustomerUUID) {
+		return nil
+	}
+	return s.db.InsertOrderView(ctx, order.UUID, viewer, ActorFrom(ctx).Channel)
+}
+
+func (s *Service) GetOrderStats(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.OrderStats, error) {
+	return s.db.GetOrderStats(ctx, ids)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/waybill"
+)
+
+// ErrNoCarrier is returned for waybills of orders that no driver has taken
+var ErrNoCarrier = errors.New("order has no carrier")
+
+// Waybill renders the transport document of a taken order as PDF. Only the
+// customer and the carrier of the order may get it; a nil requester is an admin.
+func (s *Service) Waybill(ctx context.Context, orderID string, requester *uuid.UUID, form waybill.Form) ([]byte, error) {
+	id, err := parseUUID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+
+	order, err := s.db.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.Customer == nil {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+	isCarrier := requester != nil && order.CarrierUUID != nil && *order.CarrierUUID == *requester
+	if requester != nil && *requester != order.CustomerUUID && !isCarrier {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, orderID)
+	}
+	if order.CarrierUUID == nil || order.Status == models.OrderCancelled {
+		return nil, ErrNoCarrier
+	}
+
+	carrier, err := s.db.GetCustomer(ctx, *order.CarrierUUID)
+	if err != nil {
+		return nil, err
+	}
+	if carrier == nil {
+		return nil, ErrNoCarrier
+	}
+
+	pdf, err := waybill.Render(form, waybill.Data{
+		Number:  WaybillNumber(*order),
+		Date:    time.Now(),
+		Order:   *order,
+		Shipper: *order.Customer,
+		Carrier: *carrier,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render waybill: %w", err)
+	}
+	return pdf, nil
+}
+
+// WaybillNumber is the document number printed on the waybill of an order
+func WaybillNumber(order models.Order) string {
+	return strings.ToUpper(order.UUID.String()[:8])
+}
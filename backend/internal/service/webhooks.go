@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gruzy-ryadom/internal/models"
+)
+
+// ErrInvalidWebhook is returned for a webhook with a bad URL or unknown events
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Webhooks methods
+
+// CreateWebhook subscribes an endpoint to order events. The returned webhook
+// holds the signing secret, which is not shown again.
+func (s *Service) CreateWebhook(ctx context.Context, input models.CreateWebhookInput) (models.Webhook, error) {
+	if err := s.validateWebhookURL(ctx, input.URL); err != nil {
+		return models.Webhook{}, err
+	}
+	events, err := normalizeWebhookEvents(input.Events)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	input.Events = events
+
+	if input.Secret == "" {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return models.Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		input.Secret = hex.EncodeToString(raw)
+	}
+
+	return s.db.CreateWebhook(ctx, input)
+}
+
+// ListWebhooks returns all webhooks without their secrets
+func (s *Service) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := s.db.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *Service) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	webhookID, err := parseUUID(id)
+	if err != nil {
+		return nil, nil
+	}
+	webhook, err := s.db.GetWebhook(ctx, webhookID)
+	if webhook != nil {
+		webhook.Secret = ""
+	}
+	return webhook, err
+}
+
+// UpdateWebhook changes a subscription and returns nil if it does not exist
+func (s *Service) UpdateWebhook(ctx context.Context, id string, input models.UpdateWebhookInput) (*models.Webhook, error) {
+	webhookID, err := parseUUID(id)
+	if err != nil {
+		return nil, nil
+	}
+	if input.URL != nil {
+		if err := s.validateWebhookURL(ctx, *input.URL); err != nil {
+			return nil, err
+		}
+	}
+	if input.Events != nil {
+		events, err := normalizeWebhookEvents(*input.Events)
+		if err != nil {
+			return nil, err
+		}
+		input.Events = &events
+	}
+
+	webhook, err := s.db.UpdateWebhook(ctx, webhookID, input)
+	if webhook != nil {
+		webhook.Secret = ""
+	}
+	return webhook, err
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	webhookID, err := parseUUID(id)
+	if err != nil {
+		return false, nil
+	}
+	return s.db.DeleteWebhook(ctx, webhookID)
+}
+
+// PingWebhook queues a ping event to a webhook to check the endpoint. It
+// returns false if the webhook does not exist or is paused.
+func (s *Service) PingWebhook(ctx context.Context, id string) (bool, error) {
+	webhookID, err := parseUUID(id)
+	if err != nil {
+		return false, nil
+	}
+	payload, err := json.Marshal(models.WebhookPayload{Event: models.EventPing, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return false, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	queued, err := s.db.EnqueueWebhookDeliveries(ctx, models.EventPing, payload, &webhookID, nil)
+	return queued > 0, err
+}
+
+// Webhook deliveries methods
+func (s *Service) ListWebhookDeliveries(ctx context.Context, webhookUUID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	return s.db.ListWebhookDeliveries(ctx, webhookUUID, status, limit)
+}
+
+func (s *Service) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookAttempt, error) {
+	return s.db.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (s *Service) FinishWebhookAttempt(ctx context.Context, id uuid.UUID, status string, responseStatus *int, attemptErr *string, retryAfter time.Duration) error {
+	return s.db.FinishWebhookAttempt(ctx, id, status, responseStatus, attemptErr, retryAfter)
+}
+
+// RetryWebhookDelivery sends a failed delivery again and returns nil if it is not failed
+func (s *Service) RetryWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	deliveryID, err := parseUUID(id)
+	if err != nil {
+		return nil, nil
+	}
+	return s.db.RetryWebhookDelivery(ctx, deliveryID)
+}
+
+// QueueOrderWebhooks is the outbox handler that queues webhook deliveries for
+// OrderCreated and OrderUpdated. It can run more than once for an event:
+// deliveries already queued for it are not queued again.
+func (s *Service) QueueOrderWebhooks(ctx context.Context, event models.DomainEvent) error {
+	switch event.Type {
+	case models.OrderCreated:
+		var payload models.OrderCreatedEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		return s.publishOrderEvent(ctx, event.ID, models.EventOrderCreated, payload.Order, nil)
+	case models.OrderUpdated:
+		var payload models.OrderUpdatedEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("failed to decode %s event: %w", event.Type, err)
+		}
+		return s.publishOrderChange(ctx, event.ID, payload.Before, payload.After)
+	}
+	return nil
+}
+
+// publishOrderEvent queues an order event for the subscribed webhooks. Only
+// published orders are sent, without moderation details and contacts.
+func (s *Service) publishOrderEvent(ctx context.Context, outboxID int64, event models.WebhookEvent, order models.Order, previous *models.OrderStatus) error {
+	if !published(order) {
+		return nil
+	}
+	return s.queueOrderEvent(ctx, outboxID, event, order, previous)
+}
+
+func (s *Service) queueOrderEvent(ctx context.Context, outboxID int64, event models.WebhookEvent, order models.Order, previous *models.OrderStatus) error {
+	public := order.Public()
+	payload, err := json.Marshal(models.WebhookPayload{
+		Event:          event,
+		CreatedAt:      time.Now().UTC(),
+		Order:          &public,
+		PreviousStatus: previous,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	_, err = s.db.EnqueueWebhookDeliveries(ctx, event, payload, nil, &outboxID)
+	return err
+}
+
+// publishOrderChange queues the events of an order update: order.status_changed
+// if the status changed and order.updated if anything else did. An order that
+// has just been approved or unhidden appears to partners as order.created, one
+// that has been hidden or rejected disappears with order.removed.
+func (s *Service) publishOrderChange(ctx context.Context, outboxID int64, before, after models.Order) error {
+	if !published(before) {
+		return s.publishOrderEvent(ctx, outboxID, models.EventOrderCreated, after, nil)
+	}
+	if !published(after) {
+		return s.queueOrderEvent(ctx, outboxID, models.EventOrderRemoved, before, nil)
+	}
+
+	if before.Status != after.Status {
+		previous := before.Status
+		if err := s.publishOrderEvent(ctx, outboxID, models.EventOrderStatusChanged, after, &previous); err != nil {
+			return err
+		}
+	}
+
+	before.Status = after.Status
+	before.Customer, after.Customer = nil, nil
+	changes, err := diff(before.Public(), after.Public())
+	if err != nil {
+		return fmt.Errorf("failed to diff order %s: %w", after.UUID, err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return s.publishOrderEvent(ctx, outboxID, models.EventOrderUpdated, after, nil)
+}
+
+// published reports whether an order is visible to everyone
+func published(order models.Order) bool {
+	return order.ModerationStatus == models.ModerationApproved && !order.Hidden
+}
+
+// validateWebhookURL checks that a webhook URL is absolute http(s) and that
+// its host resolves to public addresses only. The dispatcher checks the
+// address again when it connects, as DNS may change in between.
+func (s *Service) validateWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: failed to resolve %s", ErrInvalidWebhook, u.Hostname())
+	}
+	for _, addr := range addrs {
+		if err := s.CheckWebhookAddress(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shared address space of carrier-grade NAT, not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// CheckWebhookAddress rejects the loopback, private, link-local and other
+// non-public addresses that webhooks must not reach, so that a partner URL
+// cannot be used to call internal services
+func (s *Service) CheckWebhookAddress(ip net.IP) error {
+	if s.allowPrivateWebhooks {
+		return nil
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%w: %s is not a public address", ErrInvalidWebhook, ip)
+	}
+	return nil
+}
+
+// normalizeWebhookEvents checks the events of a subscription and drops duplicates
+func normalizeWebhookEvents(events []models.WebhookEvent) ([]models.WebhookEvent, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+
+	var normalized []models.WebhookEvent
+	seen := make(map[models.WebhookEvent]bool)
+	for _, event := range events {
+		event = models.WebhookEvent(strings.TrimSpace(string(event)))
+		known := false
+		for _, supported := range models.WebhookEvents {
+			known = known || event == supported
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
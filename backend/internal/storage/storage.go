@@ -0,0 +1,20 @@
+// Package storage keeps uploaded files such as order photos. Keys are
+// slash-separated paths like "orders/<uuid>/<photo>.jpg", so an S3-compatible
+// bucket can stand in for the local directory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by Open for a missing key
+var ErrNotFound = errors.New("file not found")
+
+// Storage is a flat key-value store of files
+type Storage interface {
+	Put(ctx context.Context, key string, content io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
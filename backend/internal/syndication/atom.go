@@ -0,0 +1,79 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang     string      `xml:"xml:lang,attr"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Author   atomAuthor  `xml:"author"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Link       atomLink       `xml:"link"`
+	Summary    atomText       `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func atomDocument(feed Feed) atomFeed {
+	doc := atomFeed{
+		Lang:     "ru",
+		ID:       feed.SelfURL,
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		Updated:  feed.Updated().Format(time.RFC3339),
+		Author:   atomAuthor{Name: "Грузы рядом"},
+		Links: []atomLink{
+			{Href: feed.SelfURL, Rel: "self", Type: Atom.MediaType()},
+			{Href: feed.Link, Rel: "alternate", Type: "application/json"},
+		},
+		Entries: make([]atomEntry, 0, len(feed.Orders)),
+	}
+
+	for _, order := range feed.Orders {
+		entry := atomEntry{
+			ID:        "urn:uuid:" + order.UUID.String(),
+			Title:     entryTitle(order),
+			Updated:   order.CreatedAt.Format(time.RFC3339),
+			Published: order.CreatedAt.Format(time.RFC3339),
+			Link:      atomLink{Href: feed.OrderURL(order), Rel: "alternate"},
+			Summary:   atomText{Type: "html", Value: entrySummary(order)},
+		}
+		for _, tag := range order.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
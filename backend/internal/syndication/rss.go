@@ -0,0 +1,71 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          rssSelf   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssSelf is the atom:link recommended for RSS feeds to point at themselves
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssDocument(feed Feed) rss {
+	channel := rssChannel{
+		Title:       feed.Title,
+		Link:        feed.Link,
+		Description: feed.Description,
+		Language:    "ru",
+		Self:        rssSelf{Href: feed.SelfURL, Rel: "self", Type: RSS.MediaType()},
+		Items:       make([]rssItem, 0, len(feed.Orders)),
+	}
+	if len(feed.Orders) > 0 {
+		channel.LastBuildDate = feed.Updated().Format(time.RFC1123Z)
+	}
+
+	for _, order := range feed.Orders {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       entryTitle(order),
+			Link:        feed.OrderURL(order),
+			Description: entrySummary(order),
+			Categories:  order.Tags,
+			GUID:        rssGUID{Value: "urn:uuid:" + order.UUID.String()},
+			PubDate:     order.CreatedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	return rss{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel}
+}
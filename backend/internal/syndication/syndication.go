@@ -0,0 +1,131 @@
+// Package syndication renders order listings as RSS 2.0 and Atom feeds for
+// feed readers and aggregators.
+package syndication
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"gruzy-ryadom/internal/models"
+)
+
+// Format is a feed format
+type Format string
+
+const (
+	RSS  Format = "rss"
+	Atom Format = "atom"
+)
+
+// MediaType is the MIME type of feeds of the format
+func (f Format) MediaType() string {
+	if f == Atom {
+		return "application/atom+xml"
+	}
+	return "application/rss+xml"
+}
+
+// ContentType is the Content-Type header of feeds of the format
+func (f Format) ContentType() string {
+	return f.MediaType() + "; charset=utf-8"
+}
+
+// Feed describes a feed of orders
+type Feed struct {
+	Title       string
+	Description string
+	SelfURL     string // address of the feed itself
+	Link        string // address of the same listing as JSON
+	Orders      []models.Order
+	OrderURL    func(order models.Order) string
+}
+
+// Updated is the creation time of the newest order, or the Unix epoch for an
+// empty feed. Orders have no modification time; an edited order changes the
+// feed content but not this time.
+func (f Feed) Updated() time.Time {
+	updated := time.Unix(0, 0).UTC()
+	for _, order := range f.Orders {
+		if order.CreatedAt.After(updated) {
+			updated = order.CreatedAt
+		}
+	}
+	return updated
+}
+
+// Render writes the feed in the format. The output depends only on the feed,
+// so it can be hashed for an ETag.
+func Render(feed Feed, format Format) ([]byte, error) {
+	var doc interface{}
+	if format == Atom {
+		doc = atomDocument(feed)
+	} else {
+		doc = rssDocument(feed)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to render %s feed: %w", format, err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// entryTitle is the headline of an order: "Москва → Казань · 1500 кг · 5 000 ₽"
+func entryTitle(order models.Order) string {
+	return strings.Join([]string{
+		route(order),
+		strconv.FormatFloat(order.WeightKg, 'f', -1, 64) + " кг",
+		order.Price.String(),
+	}, " · ")
+}
+
+// entrySummary lists the details of an order as HTML lines; feed readers
+// render RSS descriptions as HTML anyway
+func entrySummary(order models.Order) string {
+	lines := []string{order.Title}
+	if len(order.Stops) > 2 {
+		for i, stop := range order.Stops {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, stop.Address))
+		}
+	}
+	lines = append(lines, "Вес: "+strconv.FormatFloat(order.WeightKg, 'f', -1, 64)+" кг")
+	if order.LengthCm != nil && order.WidthCm != nil && order.HeightCm != nil {
+		lines = append(lines, fmt.Sprintf("Габариты: %g×%g×%g см", *order.LengthCm, *order.WidthCm, *order.HeightCm))
+	}
+	price := "Цена: " + order.Price.String()
+	if perKg, ok := order.PricePerKg(); ok && !order.Price.IsZero() {
+		price += fmt.Sprintf(" (%s/кг)", perKg)
+	}
+	lines = append(lines, price)
+	if order.AvailableFrom != nil {
+		lines = append(lines, "С "+order.AvailableFrom.Format("02.01.2006"))
+	}
+	if order.Description != nil && *order.Description != "" {
+		lines = append(lines, *order.Description)
+	}
+	for i, line := range lines {
+		lines[i] = html.EscapeString(line)
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// route returns a short "from → to" line
+func route(order models.Order) string {
+	from, to := "?", "?"
+	if order.FromLocation != nil {
+		from = *order.FromLocation
+	}
+	if order.ToLocation != nil {
+		to = *order.ToLocation
+	}
+	return from + " → " + to
+}
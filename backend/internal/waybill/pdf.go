@@ -0,0 +1,311 @@
+package waybill
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// A minimal PDF 1.4 writer: A4 pages with text, lines and rectangles. Text is
+// encoded by glyph index (Identity-H) in embedded TrueType fonts, so every
+// character of the font, Cyrillic included, can be printed.
+
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+)
+
+// pdfFont is a TrueType font embedded into one document
+type pdfFont struct {
+	name string // resource name, e.g. "F1"
+	base string // PostScript name
+	ttf  []byte
+	face *sfnt.Font
+	buf  sfnt.Buffer
+	upem fixed.Int26_6
+
+	runes  map[sfnt.GlyphIndex]rune    // for text extraction
+	widths map[sfnt.GlyphIndex]float64 // in 1/1000 of the font size
+	glyphs map[rune]sfnt.GlyphIndex
+}
+
+func newFont(name, base string, ttf []byte, face *sfnt.Font) *pdfFont {
+	return &pdfFont{
+		name:   name,
+		base:   base,
+		ttf:    ttf,
+		face:   face,
+		upem:   fixed.Int26_6(face.UnitsPerEm()),
+		runes:  make(map[sfnt.GlyphIndex]rune),
+		widths: make(map[sfnt.GlyphIndex]float64),
+		glyphs: make(map[rune]sfnt.GlyphIndex),
+	}
+}
+
+// glyph returns the glyph of a character, falling back to "?" for missing ones
+func (f *pdfFont) glyph(r rune) sfnt.GlyphIndex {
+	if g, ok := f.glyphs[r]; ok {
+		return g
+	}
+	g, err := f.face.GlyphIndex(&f.buf, r)
+	if (err != nil || g == 0) && r != '?' {
+		g = f.glyph('?')
+		f.glyphs[r] = g
+		return g
+	}
+
+	advance, err := f.face.GlyphAdvance(&f.buf, g, f.upem, font.HintingNone)
+	if err != nil {
+		advance = f.upem / 2
+	}
+	f.glyphs[r] = g
+	f.runes[g] = r
+	f.widths[g] = float64(advance) * 1000 / float64(f.upem)
+	return g
+}
+
+func (f *pdfFont) textWidth(text string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += f.widths[f.glyph(r)]
+	}
+	return width * size / 1000
+}
+
+// wrap splits text into lines no wider than width, breaking words only when
+// a single word does not fit
+func (f *pdfFont) wrap(text string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if f.textWidth(candidate, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for _, r := range word {
+				if line != "" && f.textWidth(line+string(r), size) > width {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (f *pdfFont) encode(text string) string {
+	var hex strings.Builder
+	for _, r := range text {
+		fmt.Fprintf(&hex, "%04X", uint16(f.glyph(r)))
+	}
+	return hex.String()
+}
+
+// document collects pages; coordinates of the drawing methods are in points
+// from the top left corner of the page
+type document struct {
+	title string
+	fonts []*pdfFont
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+}
+
+func (d *document) addPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+}
+
+func (d *document) text(f *pdfFont, size, x, y float64, gray float64, text string) {
+	fmt.Fprintf(d.page, "BT %s g /%s %s Tf 1 0 0 1 %s %s Tm <%s> Tj ET\n",
+		num(gray), f.name, num(size), num(x), num(pageHeight-y), f.encode(text))
+}
+
+func (d *document) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(pageHeight-y1), num(x2), num(pageHeight-y2))
+}
+
+func (d *document) rect(x, y, w, h, width float64) {
+	fmt.Fprintf(d.page, "%s w %s %s %s %s re S\n", num(width), num(x), num(pageHeight-y-h), num(w), num(h))
+}
+
+func (d *document) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.page, "%s g %s %s %s %s re f\n", num(gray), num(x), num(pageHeight-y-h), num(w), num(h))
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// pdfWriter numbers objects and remembers their offsets for the xref table
+type pdfWriter struct {
+	out     bytes.Buffer
+	offsets []int
+}
+
+// reserve allocates an object number to be written later
+func (w *pdfWriter) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *pdfWriter) object(n int, body string) {
+	w.offsets[n-1] = w.out.Len()
+	fmt.Fprintf(&w.out, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+func (w *pdfWriter) stream(n int, dict string, data []byte) error {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(data); err != nil {
+		return fmt.Errorf("failed to compress stream: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress stream: %w", err)
+	}
+
+	w.offsets[n-1] = w.out.Len()
+	fmt.Fprintf(&w.out, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", n, dict, compressed.Len())
+	w.out.Write(compressed.Bytes())
+	w.out.WriteString("\nendstream\nendobj\n")
+	return nil
+}
+
+// bytes serializes the document
+func (d *document) bytes() ([]byte, error) {
+	w := &pdfWriter{}
+	w.out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	catalog, pages, info := w.reserve(), w.reserve(), w.reserve()
+
+	var fontRefs strings.Builder
+	for _, f := range d.fonts {
+		ref, err := d.writeFont(w, f)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&fontRefs, "/%s %d 0 R ", f.name, ref)
+	}
+
+	var kids strings.Builder
+	for _, content := range d.pages {
+		page, stream := w.reserve(), w.reserve()
+		if err := w.stream(stream, "", content.Bytes()); err != nil {
+			return nil, err
+		}
+		w.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			pages, num(pageWidth), num(pageHeight), fontRefs.String(), stream))
+		fmt.Fprintf(&kids, "%d 0 R ", page)
+	}
+
+	w.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(d.pages)))
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.object(info, fmt.Sprintf("<< /Title <%s> /Producer (gruzy-ryadom) >>", utf16Hex(d.title)))
+
+	xref := w.out.Len()
+	fmt.Fprintf(&w.out, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, catalog, info, xref)
+
+	return w.out.Bytes(), nil
+}
+
+// writeFont embeds the whole font file as a CID-keyed font and returns the
+// number of the font object
+func (d *document) writeFont(w *pdfWriter, f *pdfFont) (int, error) {
+	fontObj, cidFont, descriptor, file, toUnicode := w.reserve(), w.reserve(), w.reserve(), w.reserve(), w.reserve()
+
+	glyphs := make([]sfnt.GlyphIndex, 0, len(f.widths))
+	for g := range f.widths {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+
+	var widths strings.Builder
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, "%d [%s] ", g, num(float64(int(f.widths[g]))))
+	}
+
+	metrics, err := f.face.Metrics(&f.buf, f.upem, font.HintingNone)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read font metrics: %w", err)
+	}
+	bounds, err := f.face.Bounds(&f.buf, f.upem, font.HintingNone)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read font bounds: %w", err)
+	}
+	scale := func(v fixed.Int26_6) int { return int(float64(v) * 1000 / float64(f.upem)) }
+
+	w.object(fontObj, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.base, cidFont, toUnicode))
+	w.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>",
+		f.base, descriptor, widths.String()))
+	// sfnt bounds grow downwards, PDF ones upwards
+	w.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.base, scale(bounds.Min.X), -scale(bounds.Max.Y), scale(bounds.Max.X), -scale(bounds.Min.Y),
+		scale(metrics.Ascent), -scale(metrics.Descent), scale(metrics.CapHeight), file))
+	if err := w.stream(file, fmt.Sprintf("/Length1 %d", len(f.ttf)), f.ttf); err != nil {
+		return 0, err
+	}
+	if err := w.stream(toUnicode, "", toUnicodeCMap(f.runes, glyphs)); err != nil {
+		return 0, err
+	}
+	return fontObj, nil
+}
+
+// toUnicodeCMap maps glyphs back to characters so that text can be copied and searched
+func toUnicodeCMap(runes map[sfnt.GlyphIndex]rune, glyphs []sfnt.GlyphIndex) []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString(`/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def
+/CMapName /Adobe-Identity-UCS def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+`)
+	// A bfchar block may hold at most 100 entries
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, g := range glyphs[start:end] {
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", uint16(g), utf16Hex(string(runes[g]))[4:])
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return cmap.Bytes()
+}
+
+// utf16Hex encodes a PDF text string as big-endian UTF-16 with a byte order mark
+func utf16Hex(text string) string {
+	var hex strings.Builder
+	hex.WriteString("FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&hex, "%04X", unit)
+	}
+	return hex.String()
+}
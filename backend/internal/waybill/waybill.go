@@ -0,0 +1,329 @@
+// Package waybill renders transport documents for taken orders as PDF
+// without any external tools: the Russian consignment note (ТТН) and the
+// international CMR note. The Go fonts are embedded for Cyrillic text.
+package waybill
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"gruzy-ryadom/internal/models"
+)
+
+// Form is the kind of transport document
+type Form string
+
+const (
+	// TTN is the Russian transport consignment note
+	TTN Form = "ttn"
+	// CMR is the international consignment note, printed in English and Russian
+	CMR Form = "cmr"
+)
+
+// ParseForm accepts a form name in any case; an empty one means TTN
+func ParseForm(name string) (Form, error) {
+	switch form := Form(strings.ToLower(strings.TrimSpace(name))); form {
+	case "":
+		return TTN, nil
+	case TTN, CMR:
+		return form, nil
+	}
+	return "", fmt.Errorf("unknown waybill form %q", name)
+}
+
+// Data is everything printed on a waybill
+type Data struct {
+	Number  string
+	Date    time.Time
+	Order   models.Order
+	Shipper models.Customer
+	Carrier models.Customer
+}
+
+var (
+	loadOnce     sync.Once
+	regularFace  *sfnt.Font
+	boldFace     *sfnt.Font
+	fontsLoadErr error
+)
+
+func loadFonts() error {
+	loadOnce.Do(func() {
+		if regularFace, fontsLoadErr = sfnt.Parse(goregular.TTF); fontsLoadErr != nil {
+			return
+		}
+		boldFace, fontsLoadErr = sfnt.Parse(gobold.TTF)
+	})
+	return fontsLoadErr
+}
+
+// section is a numbered box of the form
+type section struct {
+	label string
+	lines []string
+}
+
+// Render lays out a waybill and returns the PDF file
+func Render(form Form, data Data) ([]byte, error) {
+	if err := loadFonts(); err != nil {
+		return nil, fmt.Errorf("failed to load fonts: %w", err)
+	}
+
+	title, sections, signatures := ttn(data)
+	if form == CMR {
+		title, sections, signatures = cmr(data)
+	}
+
+	r := &renderer{
+		doc:     &document{title: fmt.Sprintf("%s № %s", title, data.Number)},
+		regular: newFont("F1", "GoRegular", goregular.TTF, regularFace),
+		bold:    newFont("F2", "GoBold", gobold.TTF, boldFace),
+	}
+	r.doc.fonts = []*pdfFont{r.regular, r.bold}
+	r.newPage()
+
+	// Bilingual titles are printed one language per line
+	for _, line := range strings.Split(title, " / ") {
+		r.centered(r.bold, 14, line)
+		r.y += 4
+	}
+	r.centered(r.regular, 10, fmt.Sprintf("№ %s от %s", data.Number, data.Date.Format("02.01.2006")))
+	r.y += 10
+
+	for i, s := range sections {
+		r.section(i+1, s)
+	}
+	r.signatures(signatures)
+
+	r.y += 14
+	r.doc.text(r.regular, 7, margin, r.y, 0.5,
+		fmt.Sprintf("Сформировано %s по заказу %s", data.Date.Format("02.01.2006 15:04"), data.Order.UUID))
+
+	return r.doc.bytes()
+}
+
+const (
+	margin       = 40
+	contentWidth = pageWidth - 2*margin
+	bodySize     = 9.5
+	labelSize    = 8
+	lineHeight   = 12.5
+	padding      = 4
+)
+
+type renderer struct {
+	doc           *document
+	regular, bold *pdfFont
+	y             float64 // top of the free space on the page
+}
+
+func (r *renderer) newPage() {
+	r.doc.addPage()
+	r.y = margin
+}
+
+// ensure starts a new page unless height fits on the current one
+func (r *renderer) ensure(height float64) {
+	if r.y+height > pageHeight-margin {
+		r.newPage()
+	}
+}
+
+func (r *renderer) centered(f *pdfFont, size float64, text string) {
+	r.y += size
+	r.doc.text(f, size, (pageWidth-f.textWidth(text, size))/2, r.y, 0, text)
+}
+
+func (r *renderer) section(number int, s section) {
+	var lines []string
+	for _, line := range s.lines {
+		lines = append(lines, r.regular.wrap(line, bodySize, contentWidth-2*padding)...)
+	}
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+
+	header := float64(labelSize + 2*padding)
+	height := header + float64(len(lines))*lineHeight + padding
+	r.ensure(height)
+
+	r.doc.fillRect(margin, r.y, contentWidth, header, 0.92)
+	r.doc.text(r.bold, labelSize, margin+padding, r.y+padding+labelSize-1, 0.25, fmt.Sprintf("%d. %s", number, s.label))
+	for i, line := range lines {
+		r.doc.text(r.regular, bodySize, margin+padding, r.y+header+float64(i+1)*lineHeight-3, 0, line)
+	}
+	r.doc.rect(margin, r.y, contentWidth, height, 0.6)
+	r.y += height - 0.6 // boxes share borders
+}
+
+func (r *renderer) signatures(parties []string) {
+	const height = 58
+	r.y += 16
+	r.ensure(height)
+
+	column := contentWidth / float64(len(parties))
+	for i, party := range parties {
+		x := margin + float64(i)*column
+		r.doc.text(r.bold, labelSize, x, r.y+labelSize, 0.25, party)
+		r.doc.line(x, r.y+38, x+column-16, r.y+38, 0.5)
+		r.doc.text(r.regular, 6.5, x, r.y+46, 0.5, "подпись / расшифровка / дата")
+	}
+	r.y += height
+}
+
+func ttn(data Data) (string, []section, []string) {
+	order := data.Order
+	pickup, delivery, via := route(order)
+	return "ТРАНСПОРТНАЯ НАКЛАДНАЯ", []section{
+		{"Грузоотправитель", party(data.Shipper)},
+		{"Грузополучатель", []string{"Адрес доставки: " + delivery.Address}},
+		{"Наименование груза", cargo(order)},
+		{"Прием груза", append([]string{"Адрес погрузки: " + pickup.Address}, schedule(pickup, order.AvailableFrom)...)},
+		{"Промежуточные пункты", via},
+		{"Сдача груза", append([]string{"Адрес выгрузки: " + delivery.Address}, schedule(delivery, nil)...)},
+		{"Перевозчик", party(data.Carrier)},
+		{"Транспортное средство и водитель", vehicle(data.Carrier)},
+		{"Стоимость перевозки", []string{order.Price.String()}},
+		{"Оговорки и замечания перевозчика", nil},
+	}, []string{
+		"Грузоотправитель",
+		"Перевозчик (водитель)",
+		"Грузополучатель",
+	}
+}
+
+func cmr(data Data) (string, []section, []string) {
+	order := data.Order
+	pickup, delivery, via := route(order)
+	taking := []string{pickup.Address}
+	if order.AvailableFrom != nil {
+		taking = append(taking, order.AvailableFrom.Format("02.01.2006"))
+	}
+	return "INTERNATIONAL CONSIGNMENT NOTE CMR / МЕЖДУНАРОДНАЯ ТОВАРНО-ТРАНСПОРТНАЯ НАКЛАДНАЯ", []section{
+		{"Sender / Отправитель", party(data.Shipper)},
+		{"Consignee / Получатель", nil},
+		{"Place of delivery of the goods / Место разгрузки груза", append([]string{delivery.Address}, schedule(delivery, nil)...)},
+		{"Place and date of taking over the goods / Место и дата погрузки груза", taking},
+		{"Nature of the goods / Наименование груза", cargo(order)},
+		{"Carrier / Перевозчик", party(data.Carrier)},
+		{"Vehicle / Транспортное средство", vehicle(data.Carrier)},
+		{"Special agreements / Особые согласованные условия", via},
+		{"To be paid / Стоимость перевозки", []string{order.Price.String()}},
+		{"Carrier's reservations / Оговорки и замечания перевозчика", nil},
+	}, []string{
+		"Sender / Отправитель",
+		"Carrier / Перевозчик",
+		"Consignee / Получатель",
+	}
+}
+
+// route returns the pickup and delivery stops and describes the ones in between
+func route(order models.Order) (models.OrderStop, models.OrderStop, []string) {
+	stops := order.Stops
+	if len(stops) < 2 {
+		stops = []models.OrderStop{{Address: deref(order.FromLocation)}, {Address: deref(order.ToLocation)}}
+	}
+
+	var via []string
+	for i, stop := range stops[1 : len(stops)-1] {
+		line := fmt.Sprintf("%d) %s", i+1, stop.Address)
+		if when := window(stop); when != "" {
+			line += ", " + when
+		}
+		if stop.Notes != nil {
+			line += " — " + *stop.Notes
+		}
+		via = append(via, line)
+	}
+	if len(via) == 0 {
+		via = []string{"—"}
+	}
+	return stops[0], stops[len(stops)-1], via
+}
+
+// schedule describes the time window of a stop, or the date from which the
+// cargo is available
+func schedule(stop models.OrderStop, date *time.Time) []string {
+	if when := window(stop); when != "" {
+		return []string{"Время: " + when}
+	}
+	if date != nil {
+		return []string{"Дата: " + date.Format("02.01.2006")}
+	}
+	return nil
+}
+
+func window(stop models.OrderStop) string {
+	if stop.WindowFrom == nil || stop.WindowTo == nil {
+		return ""
+	}
+	to := stop.WindowTo.Format("15:04")
+	if !sameDay(*stop.WindowFrom, *stop.WindowTo) {
+		to = stop.WindowTo.Format("02.01.2006 15:04")
+	}
+	return stop.WindowFrom.Format("02.01.2006 15:04") + " – " + to
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+func party(customer models.Customer) []string {
+	lines := []string{customer.Name}
+	if customer.Phone != "" {
+		lines = append(lines, "Телефон: "+customer.Phone)
+	}
+	if customer.TelegramTag != nil {
+		lines = append(lines, "Telegram: @"+*customer.TelegramTag)
+	}
+	return lines
+}
+
+func vehicle(carrier models.Customer) []string {
+	lines := []string{"Водитель: " + carrier.Name}
+	if carrier.VehicleModel != nil {
+		lines = append(lines, "Марка: "+*carrier.VehicleModel)
+	}
+	if carrier.VehiclePlate != nil {
+		lines = append(lines, "Госномер: "+*carrier.VehiclePlate)
+	}
+	if carrier.VehicleModel == nil && carrier.VehiclePlate == nil {
+		lines = append(lines, "Марка и госномер: ____________________")
+	}
+	return lines
+}
+
+func cargo(order models.Order) []string {
+	lines := []string{order.Title}
+	if order.Description != nil {
+		lines = append(lines, *order.Description)
+	}
+	lines = append(lines, fmt.Sprintf("Вес брутто: %s кг", decimal(order.WeightKg)))
+	if order.LengthCm != nil && order.WidthCm != nil && order.HeightCm != nil {
+		volume := *order.LengthCm * *order.WidthCm * *order.HeightCm / 1e6
+		lines = append(lines, fmt.Sprintf("Габариты: %s×%s×%s см, объем %s м³",
+			decimal(*order.LengthCm), decimal(*order.WidthCm), decimal(*order.HeightCm), decimal(volume)))
+	}
+	if len(order.Tags) > 0 {
+		lines = append(lines, "Особенности: "+strings.Join(order.Tags, ", "))
+	}
+	return lines
+}
+
+// decimal prints up to two decimals with a comma, dropping trailing zeros
+func decimal(v float64) string {
+	text := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+	return strings.Replace(text, ".", ",", 1)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "—"
+	}
+	return *s
+}
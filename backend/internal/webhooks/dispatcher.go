@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"gruzy-ryadom/internal/models"
+	"gruzy-ryadom/internal/retry"
+	"gruzy-ryadom/internal/service"
+)
+
+const (
+	dispatchBatchSize = 50
+	dispatchIdlePoll  = 5 * time.Second
+	dispatchWorkers   = 4
+	requestTimeout    = 10 * time.Second
+
+	// A claimed delivery is retried after the lease if the instance dies mid-request
+	claimLease = time.Minute
+
+	// Retries wait retryBase, then twice as long each time, up to retryMax;
+	// a delivery is given up on about 15 hours after the first attempt
+	retryBase   = 30 * time.Second
+	retryMax    = 6 * time.Hour
+	maxAttempts = 12
+)
+
+// Dispatcher sends queued webhook deliveries. The queue lives in the database,
+// so retries survive restarts and several instances can share it.
+type Dispatcher struct {
+	service *service.Service
+	client  *http.Client
+}
+
+// NewDispatcher creates a dispatcher; a nil client uses one with a request
+// timeout that only connects to the addresses service.CheckWebhookAddress allows
+func NewDispatcher(service *service.Service, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = newClient(service)
+	}
+	return &Dispatcher{service: service, client: client}
+}
+
+// newClient checks the address of every connection, redirects included, so a
+// host that resolves to a private address after registration is not reached
+func newClient(service *service.Service) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: requestTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("webhook dial to %s: not an IP address", address)
+			}
+			return service.CheckWebhookAddress(ip)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // a proxy would be the only address checked
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: requestTimeout, Transport: transport}
+}
+
+// Run sends due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Println("Webhook dispatcher started...")
+	for {
+		attempts, err := d.service.ClaimWebhookDeliveries(ctx, dispatchBatchSize, claimLease)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Webhooks: failed to claim deliveries: %v", err)
+		}
+		d.sendAll(ctx, attempts)
+
+		// A full batch means more deliveries are probably due
+		if len(attempts) == dispatchBatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook dispatcher stopped")
+			return
+		case <-time.After(dispatchIdlePoll):
+		}
+	}
+}
+
+// sendAll sends a batch with a few requests in flight, so one slow endpoint
+// does not hold up the others
+func (d *Dispatcher) sendAll(ctx context.Context, attempts []models.WebhookAttempt) {
+	queue := make(chan models.WebhookAttempt)
+	var wg sync.WaitGroup
+	for i := 0; i < dispatchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attempt := range queue {
+				d.deliver(ctx, attempt)
+			}
+		}()
+	}
+	for _, attempt := range attempts {
+		queue <- attempt
+	}
+	close(queue)
+	wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, attempt models.WebhookAttempt) {
+	delivery := attempt.Delivery
+	responseStatus, err := d.send(ctx, attempt)
+	if ctx.Err() != nil {
+		return // shutting down, the lease expires and the attempt is repeated
+	}
+
+	status := models.WebhookDelivered
+	var errText *string
+	var retryAfter time.Duration
+	if err != nil {
+		text := err.Error()
+		errText = &text
+		status = models.WebhookFailed
+		if delivery.Attempts < maxAttempts {
+			status = models.WebhookPending
+			retryAfter = retry.Backoff(delivery.Attempts, retryBase, retryMax)
+		}
+		log.Printf("Webhooks: delivery %s to %s failed (attempt %d): %v", delivery.UUID, attempt.URL, delivery.Attempts, err)
+	}
+
+	if err := d.service.FinishWebhookAttempt(context.WithoutCancel(ctx), delivery.UUID, status, responseStatus, errText, retryAfter); err != nil {
+		log.Printf("Webhooks: %v", err)
+	}
+}
+
+// send makes one signed request; any response other than 2xx is an error
+func (d *Dispatcher) send(ctx context.Context, attempt models.WebhookAttempt) (*int, error) {
+	body := []byte(attempt.Delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, attempt.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gruzy-ryadom-webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(attempt.Delivery.Event))
+	req.Header.Set(HeaderDelivery, attempt.Delivery.UUID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(attempt.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return &resp.StatusCode, nil
+}
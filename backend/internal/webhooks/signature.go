@@ -0,0 +1,48 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of a webhook request
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"  // UUID of the delivery, the same on every retry
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix seconds when the attempt was signed
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" and the hex HMAC of "<timestamp>.<body>"
+)
+
+// ErrBadSignature is returned by Verify for a request that was not signed with the secret
+var ErrBadSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header of a body sent at timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a received webhook.
+// Requests signed more than tolerance ago are rejected to prevent replays.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if age := time.Since(time.Unix(ts, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return ErrBadSignature
+	}
+	expected := Sign(secret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+-- Referral codes used in t.me/<bot>?start=ref_<code> links. 16 hex characters
+-- (64 random bits) make a collision, which would fail the customer's INSERT,
+-- practically impossible.
+ALTER TABLE customers ADD COLUMN referral_code TEXT;
+UPDATE customers SET referral_code = substr(md5(uuid::text || random()::text), 1, 16);
+ALTER TABLE customers ALTER COLUMN referral_code SET NOT NULL;
+ALTER TABLE customers ALTER COLUMN referral_code
+  SET DEFAULT substr(md5(uuid_generate_v4()::text || clock_timestamp()::text), 1, 16);
+CREATE UNIQUE INDEX idx_customers_referral_code ON customers(referral_code);
+
+-- Who invited whom; a customer can be attributed to a single referrer
+CREATE TABLE referrals (
+  referred_uuid  UUID      PRIMARY KEY REFERENCES customers(uuid) ON DELETE CASCADE,
+  referrer_uuid  UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  CHECK (referred_uuid <> referrer_uuid)
+);
+
+CREATE INDEX idx_referrals_referrer ON referrals(referrer_uuid);
@@ -0,0 +1,8 @@
+-- Admin bot users granted from the bot by a superadmin.
+-- IDs from the config roster are not stored here and always keep their role.
+CREATE TABLE admins (
+  telegram_id    BIGINT    PRIMARY KEY,
+  role           TEXT      NOT NULL CHECK(role IN ('moderator', 'superadmin')),
+  added_by       BIGINT,
+  created_at     TIMESTAMP NOT NULL DEFAULT now()
+);
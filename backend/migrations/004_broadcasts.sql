@@ -0,0 +1,45 @@
+-- Users who blocked the driver bot are skipped by broadcasts until they /start again
+ALTER TABLE customers ADD COLUMN bot_blocked_at TIMESTAMP;
+
+-- Searches saved by drivers with /subscribe, used to target broadcasts by region
+CREATE TABLE saved_searches (
+  uuid           UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  telegram_id    BIGINT    NOT NULL,
+  query          TEXT      NOT NULL,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (telegram_id, query)
+);
+
+-- Broadcast messages and their delivery queue
+CREATE TABLE broadcasts (
+  uuid                UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  created_by          BIGINT    NOT NULL,
+  audience            TEXT      NOT NULL CHECK(audience IN ('all', 'customers', 'drivers', 'region')),
+  region              TEXT,
+  text                TEXT      NOT NULL DEFAULT '',
+  entities            JSONB,
+  photo               BYTEA,                  -- original photo, re-uploaded by the driver bot
+  photo_file_id       TEXT,                   -- driver bot file_id after the first upload
+  status              TEXT      NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'running', 'done', 'cancelled')),
+  total               INT       NOT NULL DEFAULT 0,
+  sent                INT       NOT NULL DEFAULT 0,
+  failed              INT       NOT NULL DEFAULT 0,
+  blocked             INT       NOT NULL DEFAULT 0,
+  progress_chat_id    BIGINT,
+  progress_message_id INT,
+  created_at          TIMESTAMP NOT NULL DEFAULT now(),
+  finished_at         TIMESTAMP
+);
+
+CREATE TABLE broadcast_deliveries (
+  broadcast_uuid UUID      NOT NULL REFERENCES broadcasts(uuid) ON DELETE CASCADE,
+  telegram_id    BIGINT    NOT NULL,
+  status         TEXT      NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'sent', 'failed', 'blocked')),
+  error          TEXT,
+  updated_at     TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (broadcast_uuid, telegram_id)
+);
+
+CREATE INDEX idx_saved_searches_telegram_id ON saved_searches(telegram_id);
+CREATE INDEX idx_broadcasts_status ON broadcasts(status);
+CREATE INDEX idx_broadcast_deliveries_pending ON broadcast_deliveries(broadcast_uuid) WHERE status = 'pending';
@@ -0,0 +1,10 @@
+-- Order lifecycle
+ALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'open'
+  CHECK(status IN ('open', 'in_progress', 'completed', 'cancelled'));
+CREATE INDEX idx_orders_status ON orders(status);
+CREATE INDEX idx_orders_created_at ON orders(created_at);
+
+-- Last interaction with the driver bot, used for "active users" statistics
+ALTER TABLE customers ADD COLUMN last_active_at TIMESTAMP;
+CREATE INDEX idx_customers_last_active_at ON customers(last_active_at);
+CREATE INDEX idx_customers_created_at ON customers(created_at);
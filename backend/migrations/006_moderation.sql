@@ -0,0 +1,15 @@
+-- Pre-publication moderation of orders
+ALTER TABLE orders ADD COLUMN moderation_status TEXT NOT NULL DEFAULT 'pending'
+  CHECK(moderation_status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE orders ADD COLUMN moderation_reason TEXT;
+ALTER TABLE orders ADD COLUMN moderated_by BIGINT;
+ALTER TABLE orders ADD COLUMN moderated_at TIMESTAMP;
+ALTER TABLE orders ADD COLUMN moderation_notified_at TIMESTAMP; -- sent to the moderators' queue
+
+-- Orders published before moderation existed stay visible
+UPDATE orders SET moderation_status = 'approved', moderated_at = now(), moderation_notified_at = now();
+
+CREATE INDEX idx_orders_moderation_status ON orders(moderation_status);
+
+-- Orders of trusted customers are approved automatically
+ALTER TABLE customers ADD COLUMN trusted BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Orders hidden by an admin stay in the database but leave public listings
+ALTER TABLE orders ADD COLUMN hidden_at TIMESTAMP;
@@ -0,0 +1,18 @@
+-- Blocklist of users by Telegram ID, phone or customer
+CREATE TABLE bans (
+  uuid           UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  telegram_id    BIGINT,
+  phone          TEXT,                   -- digits only
+  customer_uuid  UUID      REFERENCES customers(uuid) ON DELETE CASCADE,
+  reason         TEXT      NOT NULL,
+  expires_at     TIMESTAMP,              -- NULL means permanent
+  created_by     BIGINT    NOT NULL,     -- admin Telegram ID
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  lifted_by      BIGINT,
+  lifted_at      TIMESTAMP,
+  CHECK (num_nonnulls(telegram_id, phone, customer_uuid) = 1)
+);
+
+CREATE INDEX idx_bans_telegram_id ON bans(telegram_id) WHERE lifted_at IS NULL;
+CREATE INDEX idx_bans_phone ON bans(phone) WHERE lifted_at IS NULL;
+CREATE INDEX idx_bans_customer ON bans(customer_uuid) WHERE lifted_at IS NULL;
@@ -0,0 +1,25 @@
+-- Append-only history of changes to orders and customers
+CREATE TABLE audit_log (
+  id             BIGSERIAL PRIMARY KEY,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  channel        TEXT      NOT NULL,     -- api, driver-bot, admin-bot, system
+  actor_id       BIGINT,                 -- Telegram ID of the user or admin
+  request_id     TEXT,
+  entity_type    TEXT      NOT NULL CHECK(entity_type IN ('order', 'customer')),
+  entity_uuid    UUID      NOT NULL,
+  action         TEXT      NOT NULL CHECK(action IN ('create', 'update')),
+  changes        JSONB     NOT NULL      -- {"field": {"before": ..., "after": ...}}
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_uuid, created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id, created_at);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,19 @@
+-- Driver who took the order
+ALTER TABLE orders ADD COLUMN carrier_uuid UUID REFERENCES customers(uuid) ON DELETE SET NULL;
+CREATE INDEX idx_orders_carrier ON orders(carrier_uuid);
+
+-- Ratings left by the customer and the driver of a completed order
+CREATE TABLE reviews (
+  uuid           UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  order_uuid     UUID      NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  author_uuid    UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  target_uuid    UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  rating         SMALLINT  NOT NULL CHECK(rating BETWEEN 1 AND 5),
+  comment        TEXT,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  removed_by     BIGINT,                 -- admin Telegram ID
+  removed_at     TIMESTAMP,
+  UNIQUE (order_uuid, author_uuid)
+);
+
+CREATE INDEX idx_reviews_target ON reviews(target_uuid) WHERE removed_at IS NULL;
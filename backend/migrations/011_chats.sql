@@ -0,0 +1,21 @@
+-- Anonymous conversations between a driver and the customer of an order
+CREATE TABLE chat_threads (
+  uuid              UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  order_uuid        UUID      NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  driver_uuid       UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  driver_revealed   BOOLEAN   NOT NULL DEFAULT FALSE,  -- driver shared their contacts
+  customer_revealed BOOLEAN   NOT NULL DEFAULT FALSE,  -- customer shared their contacts
+  created_at        TIMESTAMP NOT NULL DEFAULT now(),
+  UNIQUE (order_uuid, driver_uuid)
+);
+
+-- Every relayed message is kept for dispute resolution
+CREATE TABLE chat_messages (
+  id             BIGSERIAL PRIMARY KEY,
+  thread_uuid    UUID      NOT NULL REFERENCES chat_threads(uuid) ON DELETE CASCADE,
+  sender_uuid    UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  text           TEXT      NOT NULL,
+  created_at     TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_chat_messages_thread ON chat_messages(thread_uuid, id);
@@ -0,0 +1,22 @@
+-- Tokens drivers use to authenticate in the HTTP API, issued by the driver bot
+CREATE TABLE api_tokens (
+  token_hash     TEXT      PRIMARY KEY,  -- SHA-256 of the token, the token itself is not stored
+  customer_uuid  UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  last_used_at   TIMESTAMP,
+  revoked_at     TIMESTAMP
+);
+
+CREATE INDEX idx_api_tokens_customer ON api_tokens(customer_uuid) WHERE revoked_at IS NULL;
+
+-- Every time a driver sees the contacts of an order's customer
+CREATE TABLE contact_reveals (
+  id             BIGSERIAL PRIMARY KEY,
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  viewer_uuid    UUID      NOT NULL REFERENCES customers(uuid) ON DELETE CASCADE,
+  order_uuid     UUID      NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  channel        TEXT      NOT NULL      -- api, driver-bot
+);
+
+CREATE INDEX idx_contact_reveals_viewer ON contact_reveals(viewer_uuid, created_at);
+CREATE INDEX idx_contact_reveals_order ON contact_reveals(order_uuid);
@@ -0,0 +1,10 @@
+-- Unique viewers of an order: a viewer is counted once however often they open it
+CREATE TABLE order_views (
+  order_uuid     UUID      NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  viewer_key     TEXT      NOT NULL,     -- customer:<uuid>, telegram:<id> or anon:<hash of IP and user agent>
+  channel        TEXT      NOT NULL,     -- api, driver-bot
+  created_at     TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (order_uuid, viewer_key)
+);
+
+CREATE INDEX idx_order_views_created_at ON order_views(created_at);
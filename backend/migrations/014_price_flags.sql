@@ -0,0 +1,4 @@
+-- Price check against similar orders, made when the price is set
+ALTER TABLE orders ADD COLUMN price_flag TEXT CHECK(price_flag IN ('low', 'high'));
+ALTER TABLE orders ADD COLUMN price_estimate_low  NUMERIC;
+ALTER TABLE orders ADD COLUMN price_estimate_high NUMERIC;
@@ -0,0 +1,12 @@
+-- Prices are stored exactly in minor units (kopecks, tiyns) together with the currency
+ALTER TABLE orders ADD COLUMN currency TEXT NOT NULL DEFAULT 'RUB' CHECK(currency IN ('RUB', 'KZT', 'BYN'));
+
+ALTER TABLE orders ALTER COLUMN price TYPE BIGINT USING round(price * 100);
+ALTER TABLE orders RENAME COLUMN price TO price_minor;
+
+ALTER TABLE orders ALTER COLUMN price_estimate_low  TYPE BIGINT USING round(price_estimate_low * 100);
+ALTER TABLE orders ALTER COLUMN price_estimate_high TYPE BIGINT USING round(price_estimate_high * 100);
+
+-- Price filters and sorting always go within one currency
+DROP INDEX idx_orders_price;
+CREATE INDEX idx_orders_price ON orders(currency, price_minor);
@@ -0,0 +1,24 @@
+-- Ordered pickup and drop-off points of an order. from_location and to_location
+-- of the order are kept equal to the addresses of the first and the last stop.
+CREATE TABLE order_stops (
+  order_uuid     UUID             NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  sequence       INT              NOT NULL CHECK(sequence >= 0),
+  address        TEXT             NOT NULL,
+  city           TEXT,
+  lat            DOUBLE PRECISION CHECK(lat BETWEEN -90 AND 90),
+  lon            DOUBLE PRECISION CHECK(lon BETWEEN -180 AND 180),
+  window_from    TIMESTAMP,
+  window_to      TIMESTAMP,
+  notes          TEXT,
+  PRIMARY KEY (order_uuid, sequence),
+  CHECK(window_to IS NULL OR window_from IS NULL OR window_to >= window_from)
+);
+
+CREATE INDEX idx_order_stops_city ON order_stops(lower(city));
+
+-- Existing orders get their from and to as two stops
+INSERT INTO order_stops (order_uuid, sequence, address)
+SELECT uuid, 0, from_location FROM orders WHERE from_location IS NOT NULL;
+
+INSERT INTO order_stops (order_uuid, sequence, address)
+SELECT uuid, CASE WHEN from_location IS NULL THEN 0 ELSE 1 END, to_location FROM orders WHERE to_location IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Photos of the cargo attached by the customer. Files live in the photo
+-- storage; Telegram file IDs let the driver bot resend them without uploading.
+CREATE TABLE order_photos (
+  uuid             UUID        PRIMARY KEY DEFAULT uuid_generate_v4(),
+  order_uuid       UUID        NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  position         INT         NOT NULL,
+  telegram_file_id TEXT,
+  storage_key      TEXT        NOT NULL,
+  thumbnail_key    TEXT        NOT NULL,
+  content_type     TEXT        NOT NULL,
+  size_bytes       BIGINT      NOT NULL,
+  width            INT         NOT NULL,
+  height           INT         NOT NULL,
+  created_at       TIMESTAMP   NOT NULL DEFAULT now(),
+  UNIQUE (order_uuid, position)
+);
@@ -0,0 +1,3 @@
+-- Vehicle of a driver, printed on waybills
+ALTER TABLE customers ADD COLUMN vehicle_model TEXT;
+ALTER TABLE customers ADD COLUMN vehicle_plate TEXT;
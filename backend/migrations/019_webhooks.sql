@@ -0,0 +1,27 @@
+-- Partner subscriptions to order events
+CREATE TABLE webhooks (
+  uuid           UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  url            TEXT      NOT NULL,
+  secret         TEXT      NOT NULL,              -- HMAC-SHA256 key of the signature header
+  events         TEXT[]    NOT NULL,              -- order.created, order.updated, order.status_changed
+  active         BOOLEAN   NOT NULL DEFAULT true,
+  created_at     TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Every event sent to a webhook, retried with exponential backoff until it succeeds or gives up
+CREATE TABLE webhook_deliveries (
+  uuid            UUID      PRIMARY KEY DEFAULT uuid_generate_v4(),
+  webhook_uuid    UUID      NOT NULL REFERENCES webhooks(uuid) ON DELETE CASCADE,
+  event           TEXT      NOT NULL,
+  payload         JSONB     NOT NULL,
+  status          TEXT      NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'delivered', 'failed')),
+  attempts        INT       NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+  response_status INT,                            -- HTTP status of the last attempt
+  last_error      TEXT,
+  created_at      TIMESTAMP NOT NULL DEFAULT now(),
+  delivered_at    TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_uuid, created_at);
@@ -0,0 +1,19 @@
+-- Domain events written in the same transaction as the change they describe
+-- and handed to in-process handlers by the outbox dispatcher, at least once
+CREATE TABLE outbox (
+  id              BIGSERIAL PRIMARY KEY,
+  event_type      TEXT      NOT NULL,   -- OrderCreated, OrderUpdated, CustomerCreated
+  aggregate_uuid  UUID      NOT NULL,   -- order or customer the event is about
+  payload         JSONB     NOT NULL,
+  created_at      TIMESTAMP NOT NULL DEFAULT now(),
+  attempts        INT       NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+  last_error      TEXT,
+  processed_at    TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_due ON outbox(next_attempt_at, id) WHERE processed_at IS NULL;
+
+-- A redelivered event does not queue the same webhook twice
+ALTER TABLE webhook_deliveries ADD COLUMN outbox_id BIGINT;
+CREATE UNIQUE INDEX idx_webhook_deliveries_outbox ON webhook_deliveries(webhook_uuid, outbox_id, event) WHERE outbox_id IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Orders already sent to a driver by their saved searches: an order is sent
+-- once however many searches match and however often its event is retried
+CREATE TABLE search_notifications (
+  telegram_id  BIGINT    NOT NULL,
+  order_uuid   UUID      NOT NULL REFERENCES orders(uuid) ON DELETE CASCADE,
+  created_at   TIMESTAMP NOT NULL DEFAULT now(),
+  PRIMARY KEY (telegram_id, order_uuid)
+);
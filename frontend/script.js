@@ -157,7 +157,7 @@ function createOrderCard(order) {
                 </div>
                 <div class="order-detail">
                     <span>💰</span>
-                    <strong>${formatPrice(order.price)}</strong> ₽
+                    <strong>${formatPrice(order.price)}</strong>
                 </div>
                 ${order.from_location ? `
                     <div class="order-detail">
@@ -198,7 +198,7 @@ function createOrderCard(order) {
             ` : ""}
             
             <div class="order-price">
-                ${formatPrice(order.price)} ₽
+                ${formatPrice(order.price)}
             </div>
             
             ${customerInfo}
@@ -257,8 +257,16 @@ function showError(message) {
     `;
 }
 
+// price is {amount: "5000.00", currency: "RUB"}; the amount is a decimal string
 function formatPrice(price) {
-    return new Intl.NumberFormat("ru-RU").format(price);
+    const amount = Number(price.amount);
+    const digits = Number.isInteger(amount) ? 0 : 2;
+    return new Intl.NumberFormat("ru-RU", {
+        style: "currency",
+        currency: price.currency || "RUB",
+        minimumFractionDigits: digits,
+        maximumFractionDigits: digits,
+    }).format(amount);
 }
 
 function formatDate(dateString) {